@@ -0,0 +1,193 @@
+package audit
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testKey = []byte("0123456789abcdef")
+
+// memorySink keeps written lines
+type memorySink struct {
+	lines [][]byte
+}
+
+func (s *memorySink) Write(r Record, line []byte) error {
+	s.lines = append(s.lines, line)
+	return nil
+}
+
+func (s *memorySink) Close() error { return nil }
+
+func (s *memorySink) content() []byte {
+	return append(bytes.Join(s.lines, []byte("\n")), '\n')
+}
+
+func logRecords(t *testing.T, l *Logger, n int) {
+	for i := 0; i < n; i++ {
+		if err := l.Log(Record{Method: "/password.Password/Encode", Outcome: OutcomeSuccess}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLoggerKey(t *testing.T) {
+	for _, key := range [][]byte{nil, []byte("short")} {
+		if _, err := NewLogger(&memorySink{}, key); err != ErrWeakKey {
+			t.Errorf("NewLogger(%q) should fail with ErrWeakKey, got %v", key, err)
+		}
+	}
+	if _, err := VerifyReader(strings.NewReader(""), nil); err != ErrWeakKey {
+		t.Errorf("VerifyReader without key should fail with ErrWeakKey, got %v", err)
+	}
+}
+
+func TestChain(t *testing.T) {
+	sink := &memorySink{}
+	l, err := NewLogger(sink, testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logRecords(t, l, 3)
+
+	last, err := VerifyReader(bytes.NewReader(sink.content()), testKey)
+	if err != nil {
+		t.Fatalf("chain should be valid, got %v", err)
+	}
+	if last.Sequence != 3 {
+		t.Errorf("last record should be 3, got %d", last.Sequence)
+	}
+
+	tests := []struct {
+		name    string
+		content func() []byte
+		key     []byte
+	}{
+		{"wrong key", sink.content, []byte("fedcba9876543210")},
+		{"altered record", func() []byte {
+			return bytes.Replace(sink.content(), []byte(OutcomeSuccess), []byte(OutcomeDenied), 1)
+		}, testKey},
+		{"removed record", func() []byte {
+			return append(bytes.Join([][]byte{sink.lines[0], sink.lines[2]}, []byte("\n")), '\n')
+		}, testKey},
+		{"reordered records", func() []byte {
+			return append(bytes.Join([][]byte{sink.lines[1], sink.lines[0]}, []byte("\n")), '\n')
+		}, testKey},
+		{"malformed record", func() []byte {
+			return append(sink.content(), []byte("{broken\n")...)
+		}, testKey},
+	}
+	for _, tt := range tests {
+		if _, err := VerifyReader(bytes.NewReader(tt.content()), tt.key); err == nil {
+			t.Errorf("%s: chain should be invalid", tt.name)
+		}
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.log")
+
+	if _, err := FileSink(path, 0, nil); err == nil {
+		t.Error("FileSink should reject a zero sync interval")
+	}
+
+	// Anchor is updated on close
+	sink, err := FileSink(path, time.Hour, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := NewLogger(sink, testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logRecords(t, l, 3)
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if last, err := VerifyFile(path, testKey); err != nil || last.Sequence != 3 {
+		t.Fatalf("VerifyFile() = %v %v, expected 3 valid records", last, err)
+	}
+
+	// Chain is resumed, records written after the last anchor are accepted
+	sink, err = FileSink(path, time.Hour, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err = NewLogger(sink, testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logRecords(t, l, 2)
+	if err := sink.(*fileSink).sync(); err != nil {
+		t.Fatal(err)
+	}
+	logRecords(t, l, 1)
+	if last, err := VerifyFile(path, testKey); err != nil || last.Sequence != 6 {
+		t.Fatalf("VerifyFile() = %v %v, expected 6 valid records", last, err)
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Truncation is detected
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := bytes.SplitAfter(content, []byte("\n"))
+	if err := ioutil.WriteFile(path, bytes.Join(lines[:4], nil), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := VerifyFile(path, testKey); err == nil || !strings.Contains(err.Error(), "truncated") {
+		t.Errorf("truncated log should be detected, got %v", err)
+	}
+
+	// Anchor is mandatory
+	os.Remove(HeadPath(path))
+	if _, err := VerifyFile(path, testKey); err == nil {
+		t.Error("missing anchor should be reported")
+	}
+}
+
+func TestFileSinkInterval(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.log")
+
+	sink, err := FileSink(path, 10*time.Millisecond, func(err error) { t.Error(err) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+	l, err := NewLogger(sink, testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logRecords(t, l, 2)
+
+	// The anchor follows written records without closing the sink
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		last, err := VerifyFile(path, testKey)
+		if err == nil && last.Sequence == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("anchor should be updated by the sync loop, got %v %v", last, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
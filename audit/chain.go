@@ -0,0 +1,51 @@
+package audit
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+const (
+	// genesis is the chain link of the first record
+	genesis = "0000000000000000000000000000000000000000000000000000000000000000"
+	// MinKeyLength is the minimal HMAC key size in bytes
+	MinKeyLength = 16
+)
+
+// ErrWeakKey is raised when the chain HMAC key is missing or too short
+var ErrWeakKey = fmt.Errorf("audit: hmac key of at least %d bytes is required", MinKeyLength)
+
+// checkKey rejects keys that would let anyone able to write the log
+// recompute the chain
+func checkKey(key []byte) error {
+	if len(key) < MinKeyLength {
+		return ErrWeakKey
+	}
+	return nil
+}
+
+// chainHash computes the record link from its content (without hash) and
+// its predecessor link, as an HMAC so that the chain can not be recomputed
+// without the key.
+func chainHash(key []byte, r Record) (string, error) {
+	if len(key) == 0 {
+		return "", errors.New("audit: hmac key is missing")
+	}
+
+	r.Hash = ""
+	payload, err := json.Marshal(r)
+	if err != nil {
+		return "", err
+	}
+
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(r.Previous))
+	h.Write([]byte("\n"))
+	h.Write(payload)
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
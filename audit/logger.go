@@ -0,0 +1,89 @@
+package audit
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// Logger writes hash chained audit records to a sink
+type Logger struct {
+	mu       sync.Mutex
+	sink     Sink
+	key      []byte
+	sequence uint64
+	previous string
+	now      func() time.Time
+}
+
+// NewLogger returns an audit logger writing to the given sink, records are
+// chained with the given HMAC key. The chain is resumed from the last record
+// known by the sink, if any.
+func NewLogger(sink Sink, key []byte) (*Logger, error) {
+	if err := checkKey(key); err != nil {
+		return nil, err
+	}
+
+	l := &Logger{
+		sink:     sink,
+		key:      key,
+		previous: genesis,
+		now:      time.Now,
+	}
+
+	if r, ok := sink.(resumable); ok {
+		last, err := r.Last()
+		if err != nil {
+			return nil, err
+		}
+		if last != nil {
+			l.sequence = last.Sequence
+			l.previous = last.Hash
+		}
+	}
+
+	return l, nil
+}
+
+// Log appends the given record to the audit trail, sequence, timestamp and
+// chain links are assigned by the logger.
+func (l *Logger) Log(r Record) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	r.Sequence = l.sequence + 1
+	r.Timestamp = l.now().UTC()
+	r.Previous = l.previous
+
+	var err error
+	r.Hash, err = chainHash(l.key, r)
+	if err != nil {
+		return err
+	}
+
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	if err := l.sink.Write(r, line); err != nil {
+		return err
+	}
+
+	l.sequence = r.Sequence
+	l.previous = r.Hash
+
+	return nil
+}
+
+// Close the underlying sink
+func (l *Logger) Close() error {
+	if l == nil {
+		return nil
+	}
+	return l.sink.Close()
+}
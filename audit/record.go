@@ -0,0 +1,33 @@
+package audit
+
+import "time"
+
+const (
+	// OutcomeSuccess is used when an operation completes successfully
+	OutcomeSuccess = "success"
+	// OutcomeValid is used when a password validation matches
+	OutcomeValid = "valid"
+	// OutcomeInvalid is used when a password validation does not match
+	OutcomeInvalid = "invalid"
+	// OutcomeError is used when an operation fails
+	OutcomeError = "error"
+	// OutcomeDenied is used when the caller is not allowed to invoke the operation
+	OutcomeDenied = "denied"
+)
+
+// Record defines an audit trail entry.
+//
+// Records must never hold a password nor a password hash.
+type Record struct {
+	Sequence  uint64    `json:"seq"`
+	Timestamp time.Time `json:"ts"`
+	Identity  string    `json:"identity,omitempty"`
+	ClientIP  string    `json:"client_ip,omitempty"`
+	Method    string    `json:"method"`
+	Subject   string    `json:"subject,omitempty"`
+	Outcome   string    `json:"outcome"`
+	Algorithm string    `json:"algorithm,omitempty"`
+	Reason    string    `json:"reason,omitempty"`
+	Previous  string    `json:"prev"`
+	Hash      string    `json:"hash,omitempty"`
+}
@@ -0,0 +1,311 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/syslog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrQueueFull is raised when an asynchronous sink could not accept more records
+	ErrQueueFull = errors.New("audit: sink queue is full")
+)
+
+// Sink defines audit record destination contract
+type Sink interface {
+	Write(r Record, line []byte) error
+	Close() error
+}
+
+// resumable is implemented by sinks able to return the last written record
+type resumable interface {
+	Last() (*Record, error)
+}
+
+// -----------------------------------------------------------------------------
+
+// head is the anchor of a file chain, used to detect truncation
+type head struct {
+	Sequence uint64 `json:"seq"`
+	Hash     string `json:"hash"`
+}
+
+// HeadPath returns the anchor file path associated to the given audit log file
+func HeadPath(path string) string {
+	return fmt.Sprintf("%s.head", path)
+}
+
+type fileSink struct {
+	path  string
+	f     *os.File
+	onErr func(error)
+
+	// mu guards the last written record link, written by the logger and
+	// read by the anchoring loop
+	mu      sync.Mutex
+	written head
+	synced  head
+
+	// anchor serializes fsync and anchor updates
+	anchor sync.Mutex
+	done   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// FileSink returns a sink appending JSON lines to the given file. Every sync
+// interval, the file is flushed to stable storage and the last record link is
+// kept in an anchor file next to it. Records written since the last anchor
+// update are not covered by truncation detection.
+func FileSink(path string, interval time.Duration, onErr func(error)) (Sink, error) {
+	if interval <= 0 {
+		return nil, errors.New("audit: file sink sync interval must be positive")
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("audit: unable to open log file, %v", err)
+	}
+
+	s := &fileSink{
+		path:  path,
+		f:     f,
+		onErr: onErr,
+		done:  make(chan struct{}),
+	}
+
+	s.wg.Add(1)
+	go s.run(interval)
+
+	return s, nil
+}
+
+func (s *fileSink) run(interval time.Duration) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.sync(); err != nil && s.onErr != nil {
+				s.onErr(err)
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// Write appends the record line, the anchor is updated asynchronously
+func (s *fileSink) Write(r Record, line []byte) error {
+	if _, err := s.f.Write(append(line, '\n')); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.written = head{Sequence: r.Sequence, Hash: r.Hash}
+	s.mu.Unlock()
+
+	return nil
+}
+
+// sync flushes written records to stable storage, then replaces the anchor
+// so that it never references a record which could be lost.
+func (s *fileSink) sync() error {
+	s.anchor.Lock()
+	defer s.anchor.Unlock()
+
+	s.mu.Lock()
+	written, synced := s.written, s.synced
+	s.mu.Unlock()
+	if written == synced {
+		return nil
+	}
+
+	if err := s.f.Sync(); err != nil {
+		return fmt.Errorf("audit: unable to sync log file, %v", err)
+	}
+	if err := writeHead(HeadPath(s.path), written); err != nil {
+		return fmt.Errorf("audit: unable to update anchor file, %v", err)
+	}
+
+	s.mu.Lock()
+	s.synced = written
+	s.mu.Unlock()
+
+	return nil
+}
+
+// writeHead atomically replaces the anchor file
+func writeHead(path string, h head) error {
+	content, err := json.Marshal(h)
+	if err != nil {
+		return err
+	}
+
+	tmp := fmt.Sprintf("%s.tmp", path)
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(content); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+
+	// Persist the rename
+	dir, err := os.Open(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
+
+func (s *fileSink) Last() (*Record, error) {
+	f, err := os.Open(s.path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var last *Record
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var r Record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			return nil, fmt.Errorf("audit: unable to decode existing record, %v", err)
+		}
+		last = &r
+	}
+
+	return last, scanner.Err()
+}
+
+// Close flushes written records and the anchor, then closes the file
+func (s *fileSink) Close() error {
+	close(s.done)
+	s.wg.Wait()
+
+	err := s.sync()
+	if cerr := s.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// -----------------------------------------------------------------------------
+
+type syslogSink struct {
+	w *syslog.Writer
+}
+
+// SyslogSink returns a sink sending JSON records to syslog. An empty network
+// uses the local syslog daemon.
+func SyslogSink(network, address, tag string) (Sink, error) {
+	w, err := syslog.Dial(network, address, syslog.LOG_INFO|syslog.LOG_AUTH, tag)
+	if err != nil {
+		return nil, fmt.Errorf("audit: unable to connect to syslog, %v", err)
+	}
+
+	return &syslogSink{
+		w: w,
+	}, nil
+}
+
+func (s *syslogSink) Write(r Record, line []byte) error {
+	return s.w.Info(string(line))
+}
+
+func (s *syslogSink) Close() error {
+	return s.w.Close()
+}
+
+// -----------------------------------------------------------------------------
+
+type httpSink struct {
+	url    string
+	client *http.Client
+	queue  chan []byte
+	wg     sync.WaitGroup
+	onErr  func(error)
+}
+
+// HTTPSink returns a sink posting JSON records to the given URL. Records are
+// sent asynchronously, in order, from a bounded queue.
+func HTTPSink(url string, timeout time.Duration, onErr func(error)) Sink {
+	s := &httpSink{
+		url: url,
+		client: &http.Client{
+			Timeout: timeout,
+		},
+		queue: make(chan []byte, 1024),
+		onErr: onErr,
+	}
+
+	s.wg.Add(1)
+	go s.run()
+
+	return s
+}
+
+func (s *httpSink) run() {
+	defer s.wg.Done()
+
+	for line := range s.queue {
+		if err := s.post(line); err != nil && s.onErr != nil {
+			s.onErr(err)
+		}
+	}
+}
+
+func (s *httpSink) post(line []byte) error {
+	res, err := s.client.Post(s.url, "application/json", bytes.NewReader(line))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("audit: http sink returned status %d", res.StatusCode)
+	}
+	return nil
+}
+
+func (s *httpSink) Write(r Record, line []byte) error {
+	select {
+	case s.queue <- line:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+func (s *httpSink) Close() error {
+	close(s.queue)
+	s.wg.Wait()
+	return nil
+}
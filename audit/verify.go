@@ -0,0 +1,114 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// VerifyReader checks the hash chain of the given JSON lines stream and
+// returns the last verified record.
+func VerifyReader(r io.Reader, key []byte) (*Record, error) {
+	return verify(r, key, nil)
+}
+
+// verify checks the hash chain, visit is called with each verified record
+func verify(r io.Reader, key []byte, visit func(rec *Record)) (*Record, error) {
+	if err := checkKey(key); err != nil {
+		return nil, err
+	}
+
+	var (
+		last     *Record
+		previous = genesis
+		line     int
+	)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line++
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		var rec Record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return last, fmt.Errorf("audit: line %d is not a valid record, %v", line, err)
+		}
+
+		expectedSequence := uint64(1)
+		if last != nil {
+			expectedSequence = last.Sequence + 1
+		}
+		if rec.Sequence != expectedSequence {
+			return last, fmt.Errorf("audit: line %d, sequence gap (expected %d, got %d)", line, expectedSequence, rec.Sequence)
+		}
+		if rec.Previous != previous {
+			return last, fmt.Errorf("audit: line %d, broken link to previous record", line)
+		}
+
+		expected, err := chainHash(key, rec)
+		if err != nil {
+			return last, err
+		}
+		if expected != rec.Hash {
+			return last, fmt.Errorf("audit: line %d, record content does not match its hash", line)
+		}
+
+		previous = rec.Hash
+		last = &rec
+		if visit != nil {
+			visit(last)
+		}
+	}
+
+	return last, scanner.Err()
+}
+
+// VerifyFile checks the hash chain of an audit log file and checks the
+// record referenced by the anchor file is still present, to detect
+// truncation. Records appended after the last anchor update are accepted.
+func VerifyFile(path string, key []byte) (*Record, error) {
+	content, err := ioutil.ReadFile(HeadPath(path))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("audit: anchor file is missing, truncation can not be checked")
+		}
+		return nil, err
+	}
+
+	var h head
+	if err := json.Unmarshal(content, &h); err != nil {
+		return nil, fmt.Errorf("audit: unable to decode anchor file, %v", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var anchored *Record
+	last, err := verify(f, key, func(rec *Record) {
+		if rec.Sequence == h.Sequence {
+			anchored = rec
+		}
+	})
+	if err != nil {
+		return last, err
+	}
+
+	switch {
+	case anchored == nil:
+		return last, fmt.Errorf("audit: log is truncated, anchor references record %d", h.Sequence)
+	case anchored.Hash != h.Hash:
+		return last, fmt.Errorf("audit: record %d does not match the anchor", h.Sequence)
+	}
+
+	return last, nil
+}
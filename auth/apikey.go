@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// APIKey defines a static API key entry, only the SHA-256 digest of the key
+// is kept.
+type APIKey struct {
+	Name string `mapstructure:"name"`
+	Hash string `mapstructure:"hash"`
+}
+
+type apiKeyAuthenticator struct {
+	keys []apiKey
+}
+
+type apiKey struct {
+	name   string
+	digest []byte
+}
+
+// HashAPIKey returns the stored representation of the given API key
+func HashAPIKey(key string) string {
+	digest := sha256.Sum256([]byte(key))
+	return fmt.Sprintf("sha256:%s", hex.EncodeToString(digest[:]))
+}
+
+// APIKeys returns an authenticator that matches bearer tokens against the
+// given hashed API keys.
+func APIKeys(keys []APIKey) (Authenticator, error) {
+	a := &apiKeyAuthenticator{}
+
+	for _, k := range keys {
+		if len(strings.TrimSpace(k.Name)) == 0 {
+			return nil, fmt.Errorf("auth: api key name is mandatory")
+		}
+
+		encoded := strings.TrimPrefix(k.Hash, "sha256:")
+		digest, err := hex.DecodeString(encoded)
+		if err != nil || len(digest) != sha256.Size {
+			return nil, fmt.Errorf("auth: invalid sha256 digest for api key '%s'", k.Name)
+		}
+
+		a.keys = append(a.keys, apiKey{
+			name:   k.Name,
+			digest: digest,
+		})
+	}
+
+	return a, nil
+}
+
+func (a *apiKeyAuthenticator) Authenticate(ctx context.Context, token string) (*Identity, error) {
+	digest := sha256.Sum256([]byte(token))
+
+	// Compare with all keys using time constant operation
+	var found *apiKey
+	for i := range a.keys {
+		if subtle.ConstantTimeCompare(digest[:], a.keys[i].digest) == 1 {
+			found = &a.keys[i]
+		}
+	}
+	if found == nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	return &Identity{
+		Subject: found.name,
+		Method:  MethodAPIKey,
+	}, nil
+}
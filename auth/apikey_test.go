@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"context"
+	"testing"
+)
+
+func TestAPIKeys(t *testing.T) {
+	hash := HashAPIKey("s3cr3t-k3y")
+	if hash != "sha256:4cac9eaf4f5835a64d77390e1f675c9b18df2159892ba0ecef3b820042000356" {
+		t.Fatalf("HashAPIKey() = %s, expected sha256 prefixed hex digest", hash)
+	}
+
+	a, err := APIKeys([]APIKey{
+		{Name: "login-service", Hash: hash},
+		{Name: "ops-team", Hash: HashAPIKey("ops-k3y")[len("sha256:"):]},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		token   string
+		subject string
+	}{
+		{"s3cr3t-k3y", "login-service"},
+		{"ops-k3y", "ops-team"},
+		{hash, ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		id, err := a.Authenticate(context.Background(), tt.token)
+		switch {
+		case len(tt.subject) == 0 && err != ErrInvalidCredentials:
+			t.Errorf("Authenticate(%q) should fail, got %v %v", tt.token, id, err)
+		case len(tt.subject) > 0 && (err != nil || id.Subject != tt.subject || id.Method != MethodAPIKey):
+			t.Errorf("Authenticate(%q) = %v %v, expected %s", tt.token, id, err, tt.subject)
+		}
+	}
+
+	invalid := [][]APIKey{
+		{{Name: "", Hash: hash}},
+		{{Name: "short", Hash: "sha256:abcd"}},
+		{{Name: "cleartext", Hash: "s3cr3t-k3y"}},
+	}
+	for _, keys := range invalid {
+		if _, err := APIKeys(keys); err == nil {
+			t.Errorf("APIKeys(%+v) should fail", keys)
+		}
+	}
+}
+
+func TestBearerToken(t *testing.T) {
+	tests := []struct {
+		value string
+		token string
+		err   error
+	}{
+		{"Bearer abc", "abc", nil},
+		{"bearer  abc ", "abc", nil},
+		{"Basic abc", "", ErrMissingCredentials},
+		{"Bearer ", "", ErrMissingCredentials},
+		{"", "", ErrMissingCredentials},
+	}
+	for _, tt := range tests {
+		token, err := BearerToken(tt.value)
+		if token != tt.token || err != tt.err {
+			t.Errorf("BearerToken(%q) = %q %v, expected %q %v", tt.value, token, err, tt.token, tt.err)
+		}
+	}
+}
+
+func TestChain(t *testing.T) {
+	keys, err := APIKeys([]APIKey{{Name: "login-service", Hash: HashAPIKey("s3cr3t-k3y")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwt, err := JWT(JWTSettings{HS256Secret: testSecret})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := Chain(jwt, keys)
+
+	if id, err := a.Authenticate(context.Background(), "s3cr3t-k3y"); err != nil || id.Method != MethodAPIKey {
+		t.Errorf("Chain should fall back to API keys, got %v %v", id, err)
+	}
+	if _, err := a.Authenticate(context.Background(), "unknown"); err != ErrInvalidCredentials {
+		t.Errorf("Chain should fail when no authenticator succeeds, got %v", err)
+	}
+}
@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+var (
+	// ErrMissingCredentials is raised when no bearer token is provided
+	ErrMissingCredentials = errors.New("auth: missing credentials")
+	// ErrInvalidCredentials is raised when given token could not be authenticated
+	ErrInvalidCredentials = errors.New("auth: invalid credentials")
+)
+
+var (
+	// DefaultPublicMethods defines gRPC methods reachable without authentication
+	DefaultPublicMethods = []string{
+		"/grpc.health.v1.Health/Check",
+	}
+	// DefaultPublicPaths defines HTTP paths reachable without authentication
+	DefaultPublicPaths = []string{
+		"/healthz",
+		"/livez",
+		"/readyz",
+		"/metrics",
+		"/.well-known/finger",
+		"/swagger.json",
+	}
+)
+
+const (
+	// MethodAPIKey identifies callers authenticated by a static API key
+	MethodAPIKey = "apikey"
+	// MethodJWT identifies callers authenticated by a signed JWT
+	MethodJWT = "jwt"
+)
+
+// Identity describes an authenticated caller
+type Identity struct {
+	// Subject is the caller name (API key name or JWT subject)
+	Subject string
+	// Method is the authentication method used (MethodAPIKey or MethodJWT)
+	Method string
+}
+
+// Principal returns the method qualified caller name, subjects of different
+// authentication methods never collide.
+func (id *Identity) Principal() string {
+	return id.Method + ":" + id.Subject
+}
+
+// Authenticator defines bearer token authentication contract
+type Authenticator interface {
+	Authenticate(ctx context.Context, token string) (*Identity, error)
+}
+
+// -----------------------------------------------------------------------------
+
+type identityKey struct{}
+
+// NewContext returns a context holding the given identity
+func NewContext(ctx context.Context, id *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+// FromContext extracts the caller identity from the given context
+func FromContext(ctx context.Context) (*Identity, bool) {
+	id, ok := ctx.Value(identityKey{}).(*Identity)
+	return id, ok
+}
+
+// -----------------------------------------------------------------------------
+
+// BearerToken extracts the token part of an "Authorization: Bearer <token>" value
+func BearerToken(value string) (string, error) {
+	parts := strings.SplitN(strings.TrimSpace(value), " ", 2)
+	if len(parts) != 2 || !strings.EqualFold(parts[0], "bearer") {
+		return "", ErrMissingCredentials
+	}
+
+	token := strings.TrimSpace(parts[1])
+	if len(token) == 0 {
+		return "", ErrMissingCredentials
+	}
+
+	return token, nil
+}
+
+// -----------------------------------------------------------------------------
+
+type chain []Authenticator
+
+// Chain returns an authenticator that tries each given authenticator in turn
+// and returns the first successful identity.
+func Chain(authenticators ...Authenticator) Authenticator {
+	return chain(authenticators)
+}
+
+func (c chain) Authenticate(ctx context.Context, token string) (*Identity, error) {
+	for _, a := range c {
+		id, err := a.Authenticate(ctx, token)
+		if err == nil {
+			return id, nil
+		}
+	}
+	return nil, ErrInvalidCredentials
+}
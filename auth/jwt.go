@@ -0,0 +1,388 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"strings"
+	"time"
+)
+
+const (
+	// clockSkew defines the tolerated clock difference for time based claims
+	clockSkew = 30 * time.Second
+	// minRSABits defines the minimal accepted RSA modulus size
+	minRSABits = 2048
+)
+
+var (
+	// ErrUnsupportedAlgorithm is raised when the JWT signature algorithm is not supported
+	ErrUnsupportedAlgorithm = errors.New("auth: unsupported jwt algorithm")
+	// ErrUnknownKey is raised when no key matches the JWT header
+	ErrUnknownKey = errors.New("auth: no matching verification key")
+	// ErrKeyMismatch is raised when the JWT algorithm does not match the
+	// selected key
+	ErrKeyMismatch = errors.New("auth: jwt algorithm does not match verification key")
+)
+
+// ecdsaCurves binds ES* algorithms to their curve
+var ecdsaCurves = map[string]elliptic.Curve{
+	"ES256": elliptic.P256(),
+	"ES384": elliptic.P384(),
+	"ES512": elliptic.P521(),
+}
+
+// JWTSettings defines JWT verification settings
+type JWTSettings struct {
+	// HS256Secret is the shared secret used for HS256 tokens
+	HS256Secret string `mapstructure:"hs256_secret"`
+	// JWKSFile is the local JSON Web Key Set used for RS*/ES* tokens
+	JWKSFile string `mapstructure:"jwks_file"`
+	// Issuer is the expected "iss" claim, ignored when empty
+	Issuer string `mapstructure:"issuer"`
+	// Audience is the expected "aud" claim, ignored when empty
+	Audience string `mapstructure:"audience"`
+}
+
+type jwtAuthenticator struct {
+	settings JWTSettings
+	secret   []byte
+	keys     map[string]verificationKey
+	now      func() time.Time
+}
+
+// verificationKey is a JWKS public key and the algorithm it is restricted to,
+// when declared
+type verificationKey struct {
+	key       crypto.PublicKey
+	algorithm string
+}
+
+// JWT returns an authenticator that verifies bearer tokens as signed JWT
+func JWT(settings JWTSettings) (Authenticator, error) {
+	a := &jwtAuthenticator{
+		settings: settings,
+		secret:   []byte(settings.HS256Secret),
+		keys:     map[string]verificationKey{},
+		now:      time.Now,
+	}
+
+	if len(settings.JWKSFile) > 0 {
+		keys, err := loadJWKS(settings.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		a.keys = keys
+	}
+
+	if len(a.secret) == 0 && len(a.keys) == 0 {
+		return nil, fmt.Errorf("auth: jwt requires a shared secret or a jwks file")
+	}
+
+	return a, nil
+}
+
+type jwtHeader struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+}
+
+type jwtClaims struct {
+	Subject   string          `json:"sub"`
+	Issuer    string          `json:"iss"`
+	Audience  json.RawMessage `json:"aud"`
+	ExpiresAt *int64          `json:"exp"`
+	NotBefore *int64          `json:"nbf"`
+}
+
+func (a *jwtAuthenticator) Authenticate(ctx context.Context, token string) (*Identity, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidCredentials
+	}
+
+	// Decode header
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	// Check signature
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	if err := a.verify(header, []byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return nil, err
+	}
+
+	// Decode claims
+	var claims jwtClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	if err := a.validate(&claims); err != nil {
+		return nil, err
+	}
+
+	return &Identity{
+		Subject: claims.Subject,
+		Method:  MethodJWT,
+	}, nil
+}
+
+func (a *jwtAuthenticator) verify(header jwtHeader, signed, signature []byte) error {
+	switch header.Algorithm {
+	case "HS256":
+		if len(a.secret) == 0 {
+			return ErrUnknownKey
+		}
+		// A key id designates a JWKS public key, never the shared secret
+		if _, ok := a.keys[header.KeyID]; ok && len(header.KeyID) > 0 {
+			return ErrKeyMismatch
+		}
+		mac := hmac.New(sha256.New, a.secret)
+		mac.Write(signed)
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return ErrInvalidCredentials
+		}
+		return nil
+	case "RS256", "RS384", "RS512":
+		vk, err := a.lookup(header)
+		if err != nil {
+			return err
+		}
+		key, ok := vk.key.(*rsa.PublicKey)
+		if !ok || key.N.BitLen() < minRSABits {
+			return ErrKeyMismatch
+		}
+		h, digest := digestFor(header.Algorithm, signed)
+		if err := rsa.VerifyPKCS1v15(key, h, digest, signature); err != nil {
+			return ErrInvalidCredentials
+		}
+		return nil
+	case "ES256", "ES384", "ES512":
+		vk, err := a.lookup(header)
+		if err != nil {
+			return err
+		}
+		key, ok := vk.key.(*ecdsa.PublicKey)
+		if !ok || key.Curve != ecdsaCurves[header.Algorithm] {
+			return ErrKeyMismatch
+		}
+		size := (key.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return ErrInvalidCredentials
+		}
+		_, digest := digestFor(header.Algorithm, signed)
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(key, digest, r, s) {
+			return ErrInvalidCredentials
+		}
+		return nil
+	}
+
+	return ErrUnsupportedAlgorithm
+}
+
+// lookup returns the JWKS key designated by the header, its declared
+// algorithm must match the header one.
+func (a *jwtAuthenticator) lookup(header jwtHeader) (verificationKey, error) {
+	vk, ok := a.keys[header.KeyID]
+	// Without key id, only a single key set is unambiguous
+	if !ok && len(header.KeyID) == 0 && len(a.keys) == 1 {
+		for _, key := range a.keys {
+			vk, ok = key, true
+		}
+	}
+	if !ok {
+		return vk, ErrUnknownKey
+	}
+	if len(vk.algorithm) > 0 && vk.algorithm != header.Algorithm {
+		return vk, ErrKeyMismatch
+	}
+	return vk, nil
+}
+
+func (a *jwtAuthenticator) validate(claims *jwtClaims) error {
+	now := a.now()
+
+	if claims.ExpiresAt == nil || now.After(time.Unix(*claims.ExpiresAt, 0).Add(clockSkew)) {
+		return ErrInvalidCredentials
+	}
+	if claims.NotBefore != nil && now.Add(clockSkew).Before(time.Unix(*claims.NotBefore, 0)) {
+		return ErrInvalidCredentials
+	}
+	if len(strings.TrimSpace(claims.Subject)) == 0 {
+		return ErrInvalidCredentials
+	}
+	if len(a.settings.Issuer) > 0 && claims.Issuer != a.settings.Issuer {
+		return ErrInvalidCredentials
+	}
+	if len(a.settings.Audience) > 0 && !hasAudience(claims.Audience, a.settings.Audience) {
+		return ErrInvalidCredentials
+	}
+
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+
+func decodeSegment(segment string, v interface{}) error {
+	raw, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
+
+func digestFor(alg string, signed []byte) (crypto.Hash, []byte) {
+	switch alg[2:] {
+	case "384":
+		d := sha512.Sum384(signed)
+		return crypto.SHA384, d[:]
+	case "512":
+		d := sha512.Sum512(signed)
+		return crypto.SHA512, d[:]
+	default:
+		d := sha256.Sum256(signed)
+		return crypto.SHA256, d[:]
+	}
+}
+
+func hasAudience(raw json.RawMessage, expected string) bool {
+	var single string
+	if err := json.Unmarshal(raw, &single); err == nil {
+		return single == expected
+	}
+
+	var multiple []string
+	if err := json.Unmarshal(raw, &multiple); err == nil {
+		for _, aud := range multiple {
+			if aud == expected {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// -----------------------------------------------------------------------------
+
+type jsonWebKey struct {
+	KeyType string `json:"kty"`
+	KeyID   string `json:"kid"`
+	Use     string `json:"use"`
+	Alg     string `json:"alg"`
+	N       string `json:"n"`
+	E       string `json:"e"`
+	Curve   string `json:"crv"`
+	X       string `json:"x"`
+	Y       string `json:"y"`
+}
+
+func loadJWKS(path string) (map[string]verificationKey, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("auth: unable to read jwks file, %v", err)
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(content, &set); err != nil {
+		return nil, fmt.Errorf("auth: unable to decode jwks file, %v", err)
+	}
+
+	keys := map[string]verificationKey{}
+	for _, jwk := range set.Keys {
+		if len(jwk.Use) > 0 && jwk.Use != "sig" {
+			continue
+		}
+
+		key, err := jwk.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("auth: invalid key '%s' in jwks file, %v", jwk.KeyID, err)
+		}
+		if _, ok := keys[jwk.KeyID]; ok {
+			return nil, fmt.Errorf("auth: duplicate key '%s' in jwks file", jwk.KeyID)
+		}
+		keys[jwk.KeyID] = verificationKey{key: key, algorithm: jwk.Alg}
+	}
+
+	return keys, nil
+}
+
+func (k *jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.KeyType {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if n.BitLen() < minRSABits {
+			return nil, fmt.Errorf("rsa modulus must be at least %d bits", minRSABits)
+		}
+		if !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 || e.Bit(0) == 0 {
+			return nil, errors.New("invalid rsa exponent")
+		}
+		if len(k.Alg) > 0 && k.Alg != "RS256" && k.Alg != "RS384" && k.Alg != "RS512" {
+			return nil, fmt.Errorf("algorithm '%s' does not match key type", k.Alg)
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Curve {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve '%s'", k.Curve)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("point is not on curve")
+		}
+		if len(k.Alg) > 0 && ecdsaCurves[k.Alg] != curve {
+			return nil, fmt.Errorf("algorithm '%s' does not match curve '%s'", k.Alg, k.Curve)
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported key type '%s'", k.KeyType)
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(raw), nil
+}
@@ -0,0 +1,249 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+var (
+	testNow = time.Unix(1500000000, 0)
+
+	p256Key, _ = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	p384Key, _ = ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	rsaKey, _  = rsa.GenerateKey(rand.Reader, 2048)
+)
+
+func b64(raw []byte) string {
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func segment(v interface{}) string {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return b64(raw)
+}
+
+// signToken returns a compact JWT signed with the given key, the header
+// algorithm is used as is to forge mismatching tokens.
+func signToken(header, claims map[string]interface{}, key interface{}) string {
+	signed := segment(header) + "." + segment(claims)
+
+	var signature []byte
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(signed))
+		signature = mac.Sum(nil)
+	case *ecdsa.PrivateKey:
+		_, digest := digestFor(header["alg"].(string), []byte(signed))
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest)
+		if err != nil {
+			panic(err)
+		}
+		size := (k.Curve.Params().BitSize + 7) / 8
+		signature = make([]byte, 2*size)
+		r.FillBytes(signature[:size])
+		s.FillBytes(signature[size:])
+	case *rsa.PrivateKey:
+		h, digest := digestFor(header["alg"].(string), []byte(signed))
+		var err error
+		signature, err = rsa.SignPKCS1v15(rand.Reader, k, h, digest)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	return signed + "." + b64(signature)
+}
+
+// tamper replaces the claims of the given token, keeping its signature
+func tamper(token string, claims map[string]interface{}) string {
+	parts := strings.Split(token, ".")
+	return parts[0] + "." + segment(claims) + "." + parts[2]
+}
+
+func ecJWK(kid, alg string, key *ecdsa.PublicKey) map[string]interface{} {
+	size := (key.Curve.Params().BitSize + 7) / 8
+	x, y := make([]byte, size), make([]byte, size)
+	key.X.FillBytes(x)
+	key.Y.FillBytes(y)
+	return map[string]interface{}{"kty": "EC", "kid": kid, "alg": alg, "crv": key.Curve.Params().Name, "x": b64(x), "y": b64(y)}
+}
+
+func rsaJWK(kid, alg string, key *rsa.PublicKey) map[string]interface{} {
+	return map[string]interface{}{"kty": "RSA", "kid": kid, "alg": alg, "n": b64(key.N.Bytes()), "e": b64(big.NewInt(int64(key.E)).Bytes())}
+}
+
+func writeJWKS(t *testing.T, keys ...map[string]interface{}) string {
+	dir, err := ioutil.TempDir("", "jwks")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "jwks.json")
+	raw, _ := json.Marshal(map[string]interface{}{"keys": keys})
+	if err := ioutil.WriteFile(path, raw, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestJWT(t *testing.T) {
+	path := writeJWKS(t,
+		ecJWK("p256", "", &p256Key.PublicKey),
+		ecJWK("p384", "ES384", &p384Key.PublicKey),
+		rsaJWK("rsa", "RS256", &rsaKey.PublicKey),
+	)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	a, err := JWT(JWTSettings{HS256Secret: testSecret, JWKSFile: path, Issuer: "issuer", Audience: "password"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.(*jwtAuthenticator).now = func() time.Time { return testNow }
+
+	claims := func(overrides map[string]interface{}) map[string]interface{} {
+		c := map[string]interface{}{
+			"sub": "login-service",
+			"iss": "issuer",
+			"aud": "password",
+			"exp": testNow.Add(time.Minute).Unix(),
+		}
+		for k, v := range overrides {
+			if v == nil {
+				delete(c, k)
+				continue
+			}
+			c[k] = v
+		}
+		return c
+	}
+	hs := map[string]interface{}{"alg": "HS256"}
+
+	tests := []struct {
+		name  string
+		token string
+		err   error
+	}{
+		// Algorithms
+		{"hs256", signToken(hs, claims(nil), []byte(testSecret)), nil},
+		{"es256", signToken(map[string]interface{}{"alg": "ES256", "kid": "p256"}, claims(nil), p256Key), nil},
+		{"es384", signToken(map[string]interface{}{"alg": "ES384", "kid": "p384"}, claims(nil), p384Key), nil},
+		{"rs256", signToken(map[string]interface{}{"alg": "RS256", "kid": "rsa"}, claims(nil), rsaKey), nil},
+		{"none", segment(map[string]interface{}{"alg": "none"}) + "." + segment(claims(nil)) + ".", ErrUnsupportedAlgorithm},
+		{"hs384", signToken(map[string]interface{}{"alg": "HS384"}, claims(nil), []byte(testSecret)), ErrUnsupportedAlgorithm},
+		{"hs256 wrong secret", signToken(hs, claims(nil), []byte("wrong")), ErrInvalidCredentials},
+		{"tampered claims", tamper(signToken(hs, claims(nil), []byte(testSecret)), claims(map[string]interface{}{"sub": "admin"})), ErrInvalidCredentials},
+
+		// Algorithm and key confusion
+		{"es512 with p256 key", signToken(map[string]interface{}{"alg": "ES512", "kid": "p256"}, claims(nil), p256Key), ErrKeyMismatch},
+		{"es256 with p384 key", signToken(map[string]interface{}{"alg": "ES256", "kid": "p384"}, claims(nil), p384Key), ErrKeyMismatch},
+		{"es256 with rsa key", signToken(map[string]interface{}{"alg": "ES256", "kid": "rsa"}, claims(nil), p256Key), ErrKeyMismatch},
+		{"rs384 with rs256 key", signToken(map[string]interface{}{"alg": "RS384", "kid": "rsa"}, claims(nil), rsaKey), ErrKeyMismatch},
+		{"rs256 with ec key", signToken(map[string]interface{}{"alg": "RS256", "kid": "p256"}, claims(nil), rsaKey), ErrKeyMismatch},
+		{"hs256 with jwks kid", signToken(map[string]interface{}{"alg": "HS256", "kid": "rsa"}, claims(nil), []byte(testSecret)), ErrKeyMismatch},
+		{"unknown kid", signToken(map[string]interface{}{"alg": "ES256", "kid": "other"}, claims(nil), p256Key), ErrUnknownKey},
+		{"ambiguous kid", signToken(map[string]interface{}{"alg": "ES256"}, claims(nil), p256Key), ErrUnknownKey},
+
+		// Claims
+		{"missing exp", signToken(hs, claims(map[string]interface{}{"exp": nil}), []byte(testSecret)), ErrInvalidCredentials},
+		{"expired", signToken(hs, claims(map[string]interface{}{"exp": testNow.Add(-time.Minute).Unix()}), []byte(testSecret)), ErrInvalidCredentials},
+		{"expired within skew", signToken(hs, claims(map[string]interface{}{"exp": testNow.Add(-clockSkew / 2).Unix()}), []byte(testSecret)), nil},
+		{"not yet valid", signToken(hs, claims(map[string]interface{}{"nbf": testNow.Add(time.Minute).Unix()}), []byte(testSecret)), ErrInvalidCredentials},
+		{"valid nbf", signToken(hs, claims(map[string]interface{}{"nbf": testNow.Add(-time.Minute).Unix()}), []byte(testSecret)), nil},
+		{"wrong issuer", signToken(hs, claims(map[string]interface{}{"iss": "other"}), []byte(testSecret)), ErrInvalidCredentials},
+		{"missing issuer", signToken(hs, claims(map[string]interface{}{"iss": nil}), []byte(testSecret)), ErrInvalidCredentials},
+		{"wrong audience", signToken(hs, claims(map[string]interface{}{"aud": "other"}), []byte(testSecret)), ErrInvalidCredentials},
+		{"audience list", signToken(hs, claims(map[string]interface{}{"aud": []string{"other", "password"}}), []byte(testSecret)), nil},
+		{"missing subject", signToken(hs, claims(map[string]interface{}{"sub": nil}), []byte(testSecret)), ErrInvalidCredentials},
+
+		// Encoding
+		{"malformed", "a.b", ErrInvalidCredentials},
+		{"malformed header", "!!.e30.e30", ErrInvalidCredentials},
+	}
+	for _, tt := range tests {
+		id, err := a.Authenticate(context.Background(), tt.token)
+		if err != tt.err {
+			t.Errorf("%s: Authenticate() error = %v, expected %v", tt.name, err, tt.err)
+			continue
+		}
+		if err == nil && (id.Subject != "login-service" || id.Method != MethodJWT) {
+			t.Errorf("%s: Authenticate() = %+v", tt.name, id)
+		}
+	}
+}
+
+func TestJWTSingleKey(t *testing.T) {
+	path := writeJWKS(t, ecJWK("p256", "ES256", &p256Key.PublicKey))
+	defer os.RemoveAll(filepath.Dir(path))
+
+	a, err := JWT(JWTSettings{JWKSFile: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.(*jwtAuthenticator).now = func() time.Time { return testNow }
+
+	claims := map[string]interface{}{"sub": "login-service", "exp": testNow.Add(time.Minute).Unix()}
+
+	// A single key is selected without key id
+	if _, err := a.Authenticate(context.Background(), signToken(map[string]interface{}{"alg": "ES256"}, claims, p256Key)); err != nil {
+		t.Errorf("token without kid should be verified by the single key, got %v", err)
+	}
+	// Without shared secret, HS256 tokens are rejected
+	if _, err := a.Authenticate(context.Background(), signToken(map[string]interface{}{"alg": "HS256"}, claims, []byte(testSecret))); err != ErrUnknownKey {
+		t.Errorf("HS256 token should be rejected without secret, got %v", err)
+	}
+}
+
+func TestLoadJWKS(t *testing.T) {
+	weak, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		key   map[string]interface{}
+		valid bool
+	}{
+		{"ec", ecJWK("k", "ES256", &p256Key.PublicKey), true},
+		{"ec curve mismatch", ecJWK("k", "ES512", &p256Key.PublicKey), false},
+		{"ec rsa algorithm", ecJWK("k", "RS256", &p256Key.PublicKey), false},
+		{"rsa", rsaJWK("k", "", &rsaKey.PublicKey), true},
+		{"rsa ec algorithm", rsaJWK("k", "ES256", &rsaKey.PublicKey), false},
+		{"rsa weak modulus", rsaJWK("k", "RS256", &weak.PublicKey), false},
+		{"unsupported type", map[string]interface{}{"kty": "oct", "kid": "k", "k": b64([]byte(testSecret))}, false},
+	}
+	for _, tt := range tests {
+		path := writeJWKS(t, tt.key)
+		_, err := loadJWKS(path)
+		os.RemoveAll(filepath.Dir(path))
+		if (err == nil) != tt.valid {
+			t.Errorf("%s: loadJWKS() = %v, expected valid %v", tt.name, err, tt.valid)
+		}
+	}
+
+	path := writeJWKS(t, ecJWK("k", "", &p256Key.PublicKey), ecJWK("k", "", &p384Key.PublicKey))
+	defer os.RemoveAll(filepath.Dir(path))
+	if _, err := loadJWKS(path); err == nil {
+		t.Error("loadJWKS should reject duplicate key ids")
+	}
+}
@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Policy maps identities to roles and roles to gRPC full method names.
+// Bindings are keyed by authentication method and subject, an API key and a
+// JWT subject sharing a name are distinct identities.
+//
+//	roles:
+//	  validator:
+//	    - /password.Password/Validate
+//	  ops:
+//	    - /password.Password/*
+//	bindings:
+//	  apikey:login-service: [validator]
+//	  jwt:ops-team: [ops]
+type Policy struct {
+	Roles    map[string][]string `yaml:"roles"`
+	Bindings map[string][]string `yaml:"bindings"`
+}
+
+// LoadPolicy reads an authorization policy from the given YAML file
+func LoadPolicy(path string) (*Policy, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("auth: unable to read policy file, %v", err)
+	}
+
+	var p Policy
+	if err := yaml.Unmarshal(content, &p); err != nil {
+		return nil, fmt.Errorf("auth: unable to decode policy file, %v", err)
+	}
+
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+// Validate checks policy consistency
+func (p *Policy) Validate() error {
+	for principal, roles := range p.Bindings {
+		parts := strings.SplitN(principal, ":", 2)
+		if len(parts) != 2 || (parts[0] != MethodAPIKey && parts[0] != MethodJWT) || len(parts[1]) == 0 {
+			return fmt.Errorf("auth: binding '%s' must be qualified by authentication method, 'apikey:<name>' or 'jwt:<sub>' are expected", principal)
+		}
+		for _, role := range roles {
+			if _, ok := p.Roles[role]; !ok {
+				return fmt.Errorf("auth: identity '%s' is bound to undefined role '%s'", principal, role)
+			}
+		}
+	}
+	for role, methods := range p.Roles {
+		for _, method := range methods {
+			if method != "*" && !strings.HasPrefix(method, "/") {
+				return fmt.Errorf("auth: role '%s' references invalid method '%s', full method names are expected", role, method)
+			}
+		}
+	}
+	return nil
+}
+
+// Allowed returns true when one of the identity roles grants the given method
+func (p *Policy) Allowed(id *Identity, fullMethod string) bool {
+	if id == nil {
+		return false
+	}
+
+	for _, role := range p.Bindings[id.Principal()] {
+		for _, pattern := range p.Roles[role] {
+			if matchMethod(pattern, fullMethod) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func matchMethod(pattern, fullMethod string) bool {
+	switch {
+	case pattern == "*":
+		return true
+	case strings.HasSuffix(pattern, "/*"):
+		return strings.HasPrefix(fullMethod, strings.TrimSuffix(pattern, "*"))
+	default:
+		return pattern == fullMethod
+	}
+}
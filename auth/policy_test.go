@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testPolicy = `
+roles:
+  validator:
+    - /password.Password/Validate
+  ops:
+    - /password.Password/*
+bindings:
+  apikey:login-service: [validator]
+  apikey:ops-team: [ops]
+`
+
+func loadTestPolicy(t *testing.T, content string) (*Policy, error) {
+	dir, err := ioutil.TempDir("", "policy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "policy.yaml")
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return LoadPolicy(path)
+}
+
+func TestPolicyAllowed(t *testing.T) {
+	p, err := loadTestPolicy(t, testPolicy)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		id      *Identity
+		method  string
+		allowed bool
+	}{
+		{&Identity{Subject: "login-service", Method: MethodAPIKey}, "/password.Password/Validate", true},
+		{&Identity{Subject: "login-service", Method: MethodAPIKey}, "/password.Password/Encode", false},
+		{&Identity{Subject: "ops-team", Method: MethodAPIKey}, "/password.Password/Encode", true},
+		{&Identity{Subject: "ops-team", Method: MethodAPIKey}, "/grpc.health.v1.Health/Check", false},
+		// JWT subjects do not inherit API key bindings of the same name
+		{&Identity{Subject: "ops-team", Method: MethodJWT}, "/password.Password/Encode", false},
+		{&Identity{Subject: "unknown", Method: MethodAPIKey}, "/password.Password/Validate", false},
+		{nil, "/password.Password/Validate", false},
+	}
+	for _, tt := range tests {
+		if allowed := p.Allowed(tt.id, tt.method); allowed != tt.allowed {
+			t.Errorf("Allowed(%+v, %s) = %v, expected %v", tt.id, tt.method, allowed, tt.allowed)
+		}
+	}
+}
+
+func TestPolicyValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy Policy
+		valid  bool
+	}{
+		{"qualified", Policy{Roles: map[string][]string{"r": {"*"}}, Bindings: map[string][]string{"jwt:a": {"r"}, "apikey:b": {"r"}}}, true},
+		{"unqualified binding", Policy{Roles: map[string][]string{"r": {"*"}}, Bindings: map[string][]string{"a": {"r"}}}, false},
+		{"unknown method", Policy{Roles: map[string][]string{"r": {"*"}}, Bindings: map[string][]string{"mtls:a": {"r"}}}, false},
+		{"empty subject", Policy{Roles: map[string][]string{"r": {"*"}}, Bindings: map[string][]string{"jwt:": {"r"}}}, false},
+		{"undefined role", Policy{Roles: map[string][]string{"r": {"*"}}, Bindings: map[string][]string{"jwt:a": {"x"}}}, false},
+		{"short method name", Policy{Roles: map[string][]string{"r": {"Validate"}}}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.policy.Validate(); (err == nil) != tt.valid {
+			t.Errorf("%s: Validate() = %v, expected valid %v", tt.name, err, tt.valid)
+		}
+	}
+
+	if _, err := loadTestPolicy(t, "bindings: [broken"); err == nil {
+		t.Error("LoadPolicy should reject malformed YAML")
+	}
+}
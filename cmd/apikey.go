@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"go.zenithar.org/password/auth"
+
+	"github.com/spf13/cobra"
+)
+
+var apikeyCmd = &cobra.Command{
+	Use:   "apikey",
+	Short: "API key management",
+}
+
+var apikeyHashCmd = &cobra.Command{
+	Use:   "hash [key]",
+	Short: "compute the stored representation of the given API key",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Println(auth.HashAPIKey(strings.Join(args, " ")))
+	},
+}
+
+func init() {
+	apikeyCmd.AddCommand(apikeyHashCmd)
+	RootCmd.AddCommand(apikeyCmd)
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"go.zenithar.org/password/audit"
+	"go.zenithar.org/password/config"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var auditCmd = &cobra.Command{
+	Use:   "audit-log",
+	Short: "Audit log management",
+}
+
+var auditVerifyCmd = &cobra.Command{
+	Use:   "verify [file]",
+	Short: "verify the hash chain of an audit log file",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return verifyAuditLog(os.Stdout, args[0], auditKey)
+	},
+}
+
+// verifyAuditLog checks the audit log file chain and prints its state
+func verifyAuditLog(w io.Writer, path, key string) error {
+	if len(key) == 0 {
+		return fmt.Errorf("audit log hmac key is mandatory, use --key or PASSWORD_AUDIT_KEY")
+	}
+
+	last, err := audit.VerifyFile(path, []byte(key))
+	if err != nil {
+		return err
+	}
+
+	if last == nil {
+		fmt.Fprintln(w, "audit log is empty")
+		return nil
+	}
+	fmt.Fprintf(w, "audit log is valid, %d records, last hash %s\n", last.Sequence, last.Hash)
+	return nil
+}
+
+var auditKey string
+
+func init() {
+	auditVerifyCmd.Flags().StringVar(&auditKey, "key", os.Getenv("PASSWORD_AUDIT_KEY"), "HMAC key used to chain records")
+	auditCmd.AddCommand(auditVerifyCmd)
+	RootCmd.AddCommand(auditCmd)
+}
+
+// auditLogger builds the audit logger from configuration
+func auditLogger(conf config.Audit) (*audit.Logger, error) {
+	if !conf.Enabled {
+		return nil, nil
+	}
+
+	var (
+		sink audit.Sink
+		err  error
+	)
+
+	switch conf.Sink {
+	case "", "file":
+		sink, err = audit.FileSink(conf.File, conf.SyncInterval, func(err error) {
+			logrus.WithError(err).Error("Unable to sync audit log")
+		})
+	case "syslog":
+		sink, err = audit.SyslogSink(conf.Syslog.Network, conf.Syslog.Address, conf.Syslog.Tag)
+	case "http":
+		sink = audit.HTTPSink(
+			conf.HTTP.URL,
+			conf.HTTP.Timeout,
+			func(err error) {
+				logrus.WithError(err).Error("Unable to send audit record")
+			},
+		)
+	default:
+		err = fmt.Errorf("unsupported audit sink '%s'", conf.Sink)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	l, err := audit.NewLogger(sink, []byte(conf.HMACKey))
+	if err != nil {
+		sink.Close()
+		return nil, err
+	}
+
+	return l, nil
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.zenithar.org/password/audit"
+)
+
+func TestVerifyAuditLog(t *testing.T) {
+	const key = "0123456789abcdef"
+
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.log")
+
+	sink, err := audit.FileSink(path, time.Hour, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := audit.NewLogger(sink, []byte(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := l.Log(audit.Record{Method: "/password.Password/Encode", Outcome: audit.OutcomeSuccess}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := verifyAuditLog(&out, path, key); err != nil {
+		t.Fatalf("audit log should be valid, got %v", err)
+	}
+	if !strings.Contains(out.String(), "valid, 2 records") {
+		t.Errorf("unexpected output %q", out.String())
+	}
+
+	if err := verifyAuditLog(&out, path, ""); err == nil {
+		t.Error("verification without key should fail")
+	}
+	if err := verifyAuditLog(&out, path, "fedcba9876543210"); err == nil {
+		t.Error("verification with a wrong key should fail")
+	}
+}
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"go.zenithar.org/password/config"
+
+	"github.com/spf13/cobra"
+	yaml "gopkg.in/yaml.v2"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Configuration management",
+}
+
+var configPrintCmd = &cobra.Command{
+	Use:   "print",
+	Short: "print the effective configuration (file, environment and defaults merged)",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		conf, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		settings := conf.Settings()
+
+		// Mask secret values
+		for _, key := range config.Secrets {
+			maskSetting(settings, strings.Split(key, "."))
+		}
+
+		out, err := yaml.Marshal(settings)
+		if err != nil {
+			return err
+		}
+
+		fmt.Print(string(out))
+		return nil
+	},
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "validate the effective configuration",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if _, err := loadConfig(); err != nil {
+			return err
+		}
+
+		fmt.Println("configuration is valid")
+		return nil
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configPrintCmd)
+	configCmd.AddCommand(configValidateCmd)
+	RootCmd.AddCommand(configCmd)
+}
+
+func maskSetting(settings map[string]interface{}, path []string) {
+	value, ok := settings[path[0]]
+	if !ok {
+		return
+	}
+
+	if len(path) == 1 {
+		if s, ok := value.(string); ok && len(s) > 0 {
+			settings[path[0]] = "********"
+		}
+		return
+	}
+
+	if sub, ok := value.(map[string]interface{}); ok {
+		maskSetting(sub, path[1:])
+	}
+}
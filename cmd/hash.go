@@ -17,7 +17,12 @@ var hashCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		ctx := context.Background()
 
-		conn := grpcClientConnection(ctx, "localhost:5555")
+		conf, err := loadConfig()
+		if err != nil {
+			logrus.WithError(err).Fatal("Invalid configuration")
+		}
+
+		conn := grpcClientConnection(ctx, conf.Client, token)
 		defer conn.Close()
 
 		// Client stub
@@ -25,7 +30,7 @@ var hashCmd = &cobra.Command{
 
 		// Do the call
 		res, err := client.Encode(ctx, &pb.PasswordReq{
-			Password: strings.Join(os.Args[2:], " "),
+			Password: strings.Join(args, " "),
 		})
 		if err != nil {
 			logrus.WithError(err).Fatal("Unable to do the gRPC call")
@@ -35,6 +40,9 @@ var hashCmd = &cobra.Command{
 	},
 }
 
+var token string
+
 func init() {
+	hashCmd.Flags().StringVar(&token, "token", os.Getenv("PASSWORD_TOKEN"), "bearer token used to authenticate calls")
 	RootCmd.AddCommand(hashCmd)
 }
@@ -0,0 +1,229 @@
+package cmd
+
+import (
+	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+
+	"go.zenithar.org/password/config"
+	"go.zenithar.org/password/server"
+	"go.zenithar.org/password/utils/keypair"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+const (
+	// reloadDebounce delays reload after a file event to coalesce writes
+	reloadDebounce = 500 * time.Millisecond
+)
+
+var (
+	reloadTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "password_config_reloads_total",
+			Help: "Total number of configuration reloads partitioned by result.",
+		},
+		[]string{"result"},
+	)
+	reloadLastSuccess = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "password_config_last_reload_success_timestamp_seconds",
+			Help: "Timestamp of the last successful configuration reload.",
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(reloadTotal, reloadLastSuccess)
+}
+
+// reloader applies configuration and certificate changes at runtime
+type reloader struct {
+	mu      sync.Mutex
+	srv     *server.MicroServer
+	certs   *keypair.Store
+	current *config.Configuration
+}
+
+// readConfig reads the configuration again without altering the global one
+func readConfig() (*config.Configuration, error) {
+	v := viper.New()
+	config.SetDefaults(v)
+	bindServeFlags(v)
+
+	if path := viper.ConfigFileUsed(); len(path) > 0 {
+		v.SetConfigFile(path)
+		if err := v.ReadInConfig(); err != nil {
+			return nil, err
+		}
+	}
+
+	return config.Load(v)
+}
+
+// Reload reads the configuration and swaps runtime settings, previous
+// settings are kept if any step fails.
+func (r *reloader) Reload(trigger string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	log := logrus.WithField("trigger", trigger)
+
+	err := r.apply()
+	if err != nil {
+		reloadTotal.WithLabelValues("failure").Inc()
+		log.WithError(err).Error("Configuration reload failed, keeping previous settings")
+		return err
+	}
+
+	reloadTotal.WithLabelValues("success").Inc()
+	reloadLastSuccess.Set(float64(time.Now().Unix()))
+	log.Info("Configuration reloaded")
+
+	return nil
+}
+
+func (r *reloader) apply() error {
+	conf, err := readConfig()
+	if err != nil {
+		return err
+	}
+
+	// Prepare everything that could fail before swapping
+	authOpts, err := authOptions(conf.Auth)
+	if err != nil {
+		return err
+	}
+	level, err := logrus.ParseLevel(conf.Log.Level)
+	if err != nil {
+		return err
+	}
+	if err := r.certs.Reload(keyPairs(conf.TLS)...); err != nil {
+		return err
+	}
+
+	// Swap runtime settings
+	logrus.SetLevel(level)
+	server.SetLogLevel(level)
+	r.srv.Reload(authOpts...)
+
+	// Notify settings ignored until restart
+	if r.current.Compliance != conf.Compliance ||
+		!reflect.DeepEqual(r.current.Server, conf.Server) ||
+		!reflect.DeepEqual(r.current.Hashing, conf.Hashing) ||
+		r.current.Change != conf.Change ||
+		!reflect.DeepEqual(r.current.Audit, conf.Audit) ||
+		r.current.Store != conf.Store ||
+		!reflect.DeepEqual(r.current.Tracing, conf.Tracing) ||
+		r.current.Log.Format != conf.Log.Format ||
+		r.current.Log.ValidateSampling != conf.Log.ValidateSampling ||
+		r.current.TLS.MinVersion != conf.TLS.MinVersion ||
+		!reflect.DeepEqual(r.current.TLS.CipherSuites, conf.TLS.CipherSuites) ||
+		!reflect.DeepEqual(r.current.TLS.CurvePreferences, conf.TLS.CurvePreferences) {
+		logrus.Warn("Compliance, server, hashing, change, audit, store, tracing, log format and TLS protocol settings changes require a restart")
+	}
+
+	r.current = conf
+
+	return nil
+}
+
+// watchedFiles returns configuration and certificate files
+func (r *reloader) watchedFiles() []string {
+	files := r.certs.Files()
+	if path := viper.ConfigFileUsed(); len(path) > 0 {
+		files = append(files, path)
+	}
+	return files
+}
+
+// Watch triggers reloads on SIGHUP and, if enabled, on configuration or
+// certificate files changes until done is closed.
+func (r *reloader) Watch(hup <-chan struct{}, watchFiles bool, done <-chan struct{}) {
+	var (
+		watcher *fsnotify.Watcher
+		events  <-chan fsnotify.Event
+		dirs    = map[string]bool{}
+	)
+
+	if watchFiles {
+		var err error
+		if watcher, err = fsnotify.NewWatcher(); err != nil {
+			logrus.WithError(err).Error("Unable to watch configuration files")
+		} else {
+			defer watcher.Close()
+			r.watchDirs(watcher, dirs)
+			events = watcher.Events
+		}
+	}
+
+	// Reloaded files may have moved
+	reload := func(trigger string) {
+		if err := r.Reload(trigger); err == nil && watcher != nil {
+			r.watchDirs(watcher, dirs)
+		}
+	}
+
+	timer := time.NewTimer(reloadDebounce)
+	timer.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-hup:
+			reload("signal")
+		case ev := <-events:
+			if r.isWatched(ev.Name) {
+				timer.Reset(reloadDebounce)
+			}
+		case <-timer.C:
+			reload("file")
+		}
+	}
+}
+
+// watchDirs watches directories of the watched files to follow atomic
+// replacements (rename, symlink swap), directories no longer needed are
+// released. dirs holds the currently watched directories.
+func (r *reloader) watchDirs(watcher *fsnotify.Watcher, dirs map[string]bool) {
+	wanted := map[string]bool{}
+	for _, f := range r.watchedFiles() {
+		wanted[filepath.Dir(f)] = true
+	}
+
+	for dir := range dirs {
+		if !wanted[dir] {
+			watcher.Remove(dir)
+			delete(dirs, dir)
+		}
+	}
+	for dir := range wanted {
+		if dirs[dir] {
+			continue
+		}
+		if err := watcher.Add(dir); err != nil {
+			logrus.WithError(err).WithField("dir", dir).Error("Unable to watch directory")
+			continue
+		}
+		dirs[dir] = true
+	}
+}
+
+func (r *reloader) isWatched(name string) bool {
+	base := filepath.Base(name)
+	if base == "..data" {
+		// Kubernetes mounted volume update
+		return true
+	}
+	for _, f := range r.watchedFiles() {
+		if filepath.Clean(f) == filepath.Clean(name) || filepath.Base(f) == base {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,237 @@
+package cmd
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.zenithar.org/password/server"
+	"go.zenithar.org/password/utils/keypair"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// writeKeyPair writes a self-signed certificate and its key in dir
+func writeKeyPair(t *testing.T, dir string) keypair.Pair {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "password"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := keypair.Pair{CertFile: filepath.Join(dir, "server.crt"), KeyFile: filepath.Join(dir, "server.key")}
+	if err := ioutil.WriteFile(p.CertFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(p.KeyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func reloadCount(t *testing.T, result string) float64 {
+	var m dto.Metric
+	if err := reloadTotal.WithLabelValues(result).Write(&m); err != nil {
+		t.Fatal(err)
+	}
+	return m.GetCounter().GetValue()
+}
+
+// newTestReloader returns a reloader of the configuration file written by
+// the returned function with the given log level.
+func newTestReloader(t *testing.T) (*reloader, func(level string), func()) {
+	dir, err := ioutil.TempDir("", "reload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pair := writeKeyPair(t, dir)
+	path := filepath.Join(dir, "config.yaml")
+
+	write := func(level string) {
+		content := fmt.Sprintf("tls:\n  certificates:\n    - cert_file: %s\n      key_file: %s\nlog:\n  level: %s\n", pair.CertFile, pair.KeyFile, level)
+		if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("info")
+
+	viper.SetConfigFile(path)
+	conf, err := readConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	certs, err := keypair.New(pair)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := &reloader{
+		srv:     server.New("test", nil),
+		certs:   certs,
+		current: conf,
+	}
+	return r, write, func() {
+		viper.Reset()
+		logrus.SetLevel(logrus.InfoLevel)
+		os.RemoveAll(dir)
+	}
+}
+
+func TestReload(t *testing.T) {
+	r, write, cleanup := newTestReloader(t)
+	defer cleanup()
+
+	success, failure := reloadCount(t, "success"), reloadCount(t, "failure")
+
+	write("debug")
+	if err := r.Reload("test"); err != nil {
+		t.Fatal(err)
+	}
+	if logrus.GetLevel() != logrus.DebugLevel || r.current.Log.Level != "debug" {
+		t.Errorf("log level should be reloaded, got %s", logrus.GetLevel())
+	}
+
+	// Invalid settings keep the previous ones
+	write("verbose")
+	if err := r.Reload("test"); err == nil {
+		t.Error("Reload should fail with an invalid log level")
+	}
+	if logrus.GetLevel() != logrus.DebugLevel || r.current.Log.Level != "debug" {
+		t.Errorf("previous settings should be kept, got %s", logrus.GetLevel())
+	}
+
+	// Unreadable certificates keep the previous ones
+	write("warn")
+	if err := ioutil.WriteFile(r.certs.Pairs()[0].CertFile, []byte("broken"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Reload("test"); err == nil {
+		t.Error("Reload should fail with an invalid certificate")
+	}
+	if logrus.GetLevel() != logrus.DebugLevel {
+		t.Errorf("settings should not be swapped when certificates fail, got %s", logrus.GetLevel())
+	}
+	if _, err := r.certs.GetCertificate(&tls.ClientHelloInfo{}); err != nil {
+		t.Errorf("previous certificate should be kept, got %v", err)
+	}
+
+	if reloadCount(t, "success")-success != 1 || reloadCount(t, "failure")-failure != 2 {
+		t.Error("reloads should be counted by result")
+	}
+}
+
+func TestWatch(t *testing.T) {
+	r, write, cleanup := newTestReloader(t)
+	defer cleanup()
+
+	waitLevel := func(level logrus.Level) {
+		deadline := time.Now().Add(5 * time.Second)
+		for logrus.GetLevel() != level {
+			if time.Now().After(deadline) {
+				t.Fatalf("log level should be reloaded to %s, got %s", level, logrus.GetLevel())
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	hup := make(chan struct{})
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		r.Watch(hup, true, done)
+		close(stopped)
+	}()
+
+	// Signal
+	write("debug")
+	hup <- struct{}{}
+	waitLevel(logrus.DebugLevel)
+
+	// File change, after the debounce delay
+	write("warn")
+	waitLevel(logrus.WarnLevel)
+
+	close(done)
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Watch should return once done is closed")
+	}
+}
+
+func TestWatchMovedCertificates(t *testing.T) {
+	r, _, cleanup := newTestReloader(t)
+	defer cleanup()
+
+	dir, err := ioutil.TempDir("", "reload-moved")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	waitReload := func(success float64) {
+		deadline := time.Now().Add(5 * time.Second)
+		for reloadCount(t, "success") <= success {
+			if time.Now().After(deadline) {
+				t.Fatal("configuration should be reloaded")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	hup := make(chan struct{})
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		r.Watch(hup, true, done)
+		close(stopped)
+	}()
+	defer func() {
+		close(done)
+		<-stopped
+	}()
+
+	// Certificates moved to another directory
+	pair := writeKeyPair(t, dir)
+	content := fmt.Sprintf("tls:\n  certificates:\n    - cert_file: %s\n      key_file: %s\n", pair.CertFile, pair.KeyFile)
+	if err := ioutil.WriteFile(viper.ConfigFileUsed(), []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	success := reloadCount(t, "success")
+	hup <- struct{}{}
+	waitReload(success)
+	if pairs := r.certs.Pairs(); len(pairs) != 1 || pairs[0] != pair {
+		t.Fatalf("certificates should be reloaded from the new path, got %v", pairs)
+	}
+
+	// Changes in the new directory are watched
+	success = reloadCount(t, "success")
+	writeKeyPair(t, dir)
+	waitReload(success)
+}
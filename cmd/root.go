@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 
+	"go.zenithar.org/password/config"
+
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -37,16 +39,25 @@ func init() {
 func initConfig() {
 	if cfgFile != "" { // enable ability to specify config file via flag
 		viper.SetConfigFile(cfgFile)
+	} else {
+		viper.SetConfigName(".password") // name of config file (without extension)
+		viper.AddConfigPath("$HOME")     // adding home directory as first search path
+		viper.AddConfigPath(".")         // then current working directory
 	}
 
-	viper.SetConfigName(".password") // name of config file (without extension)
-	viper.AddConfigPath("$HOME")     // adding home directory as first search path
-	viper.AutomaticEnv()             // read in environment variables that match
-
-	viper.SetDefault("TLS.useTLS", false) // Use TLS for endpoint security
+	// Register defaults and environment variables binding
+	config.SetDefaults(viper.GetViper())
 
 	// If a config file is found, read it in.
 	if err := viper.ReadInConfig(); err == nil {
-		fmt.Println("Using config file:", viper.ConfigFileUsed())
+		fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
+	} else if cfgFile != "" {
+		fmt.Fprintln(os.Stderr, "Unable to read config file:", err)
+		os.Exit(-1)
 	}
 }
+
+// loadConfig returns the effective configuration
+func loadConfig() (*config.Configuration, error) {
+	return config.Load(viper.GetViper())
+}
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"crypto/rand"
+	"fmt"
+
+	"go.zenithar.org/password/hashing"
+
+	"github.com/spf13/cobra"
+)
+
+var selftestCmd = &cobra.Command{
+	Use:   "selftest",
+	Short: "run hashing known answer tests and entropy checks",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		conf, err := loadConfig()
+		if err != nil {
+			return err
+		}
+
+		registry, err := hashing.DefaultRegistry(hashing.NewSaltSource(rand.Reader, conf.Hashing.SaltLength))
+		if err != nil {
+			return err
+		}
+
+		results, err := hashing.SelfTest(registry)
+		for _, res := range results {
+			if len(res.Error) > 0 {
+				fmt.Printf("FAIL\t%s\t%s\n", res.Name, res.Error)
+				continue
+			}
+			fmt.Printf("ok\t%s\n", res.Name)
+		}
+
+		return err
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(selftestCmd)
+}
@@ -9,100 +9,367 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
-	"time"
 
+	"go.zenithar.org/password/auth"
+	"go.zenithar.org/password/config"
+	"go.zenithar.org/password/hashing"
 	"go.zenithar.org/password/server"
+	"go.zenithar.org/password/similarity"
+	"go.zenithar.org/password/utils/keypair"
+	"go.zenithar.org/password/utils/secret"
+	"go.zenithar.org/password/utils/tracer"
 
+	opentracing "github.com/opentracing/opentracing-go"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 // serveCmd represents the serve command
 var serveCmd = &cobra.Command{
 	Use:   "server",
-	Short: "Launches the server on http://localhost:5555",
-	RunE:  serve,
+	Short: "Launches the server (default on http://localhost:5555)",
 }
 
 func init() {
+	serveCmd.RunE = serve
+	serveCmd.Flags().String("listen", ":5555", "listen address")
+	serveCmd.Flags().String("gateway", "inprocess", "gRPC gateway transport (inprocess, unix)")
+	serveCmd.Flags().String("socket-path", "service.sock", "gRPC gateway unix socket path")
+	serveCmd.Flags().Duration("shutdown-timeout", 0, "graceful shutdown time limit")
+	serveCmd.Flags().Duration("drain-delay", 0, "time to report not ready before draining on shutdown")
+	serveCmd.Flags().String("algorithm", "", "password hashing algorithm")
+
+	bindServeFlags(viper.GetViper())
+
 	RootCmd.AddCommand(serveCmd)
 }
 
+// bindServeFlags binds server command flags to configuration keys
+func bindServeFlags(v *viper.Viper) {
+	v.BindPFlag("server.listen", serveCmd.Flags().Lookup("listen"))
+	v.BindPFlag("server.gateway", serveCmd.Flags().Lookup("gateway"))
+	v.BindPFlag("server.socket_path", serveCmd.Flags().Lookup("socket-path"))
+	v.BindPFlag("server.shutdown_timeout", serveCmd.Flags().Lookup("shutdown-timeout"))
+	v.BindPFlag("server.drain_delay", serveCmd.Flags().Lookup("drain-delay"))
+	v.BindPFlag("hashing.algorithm", serveCmd.Flags().Lookup("algorithm"))
+}
+
 func serve(cmd *cobra.Command, args []string) error {
 
+	// Configuration
+	conf, err := loadConfig()
+	if err != nil {
+		logrus.WithError(err).Error("Invalid configuration")
+		return err
+	}
+
+	// Logging
+	level, _ := logrus.ParseLevel(conf.Log.Level)
+	logrus.SetLevel(level)
+	server.SetLogLevel(level)
+	formatter, _ := conf.Log.Formatter()
+	logrus.SetFormatter(formatter)
+	server.SetLogFormatter(formatter)
+
+	// Keep cleartext passwords out of core files
+	if conf.Server.DisableCoreDumps {
+		if err := secret.DisableCoreDumps(); err != nil {
+			logrus.WithError(err).Error("Unable to disable core dumps")
+			return err
+		}
+	}
+
 	// Signal
 	signalCh := make(chan os.Signal, 1)
 	signal.Notify(signalCh, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
 
+	hupCh := make(chan os.Signal, 1)
+	signal.Notify(hupCh, syscall.SIGHUP)
+
 	errCh := make(chan error, 1)
 
 	// Initialize listener
-	conn, err := net.Listen("tcp", ":5555")
+	conn, err := net.Listen("tcp", conf.Server.Listen)
 	if err != nil {
-		panic(err)
+		logrus.WithError(err).Error("Unable to listen")
+		return err
 	}
 	defer conn.Close()
 
 	// Initialize TLS listener
-	config := &tls.Config{
-		Certificates: []tls.Certificate{
-			mustLoadX509KeyPair("./certs/server.ecdsa.crt", "./certs/server.ecdsa.key"),
-			mustLoadX509KeyPair("./certs/server.rsa.crt", "./certs/server.rsa.key"),
-		},
-		Rand:       rand.Reader,
-		NextProtos: []string{},
-		MinVersion: tls.VersionTLS12,
-		CipherSuites: []uint16{
-			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
-			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
-			tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305, // Go 1.8 only
-			tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,   // Go 1.8 only
-		},
-		PreferServerCipherSuites: true,
-		CurvePreferences: []tls.CurveID{
-			tls.CurveP384,
-			tls.X25519,
-		},
+	certs, err := keypair.New(keyPairs(conf.TLS)...)
+	if err != nil {
+		logrus.WithError(err).Error("Unable to load certificates")
+		return err
+	}
+	compliance, _ := hashing.ComplianceMode(conf.Compliance)
+	tlsConfig, err := serverTLSConfig(conf.TLS, compliance, certs)
+	if err != nil {
+		logrus.WithError(err).Error("Invalid TLS settings")
+		return err
 	}
 
-	tlsL := tls.NewListener(conn, config)
+	tlsL := tls.NewListener(conn, tlsConfig)
+
+	// Server options
+	socketMode, _ := conf.Server.FileMode()
+	trustedProxies, _ := conf.Server.TrustedNetworks()
+	opts := []server.Option{
+		server.WithGatewayTransport(conf.Server.Gateway),
+		server.WithSocketPath(conf.Server.SocketPath),
+		server.WithSocketMode(socketMode),
+		server.WithDrainDelay(conf.Server.DrainDelay),
+		server.WithHTTPTimeouts(conf.Server.HTTP.ReadTimeout, conf.Server.HTTP.WriteTimeout, conf.Server.HTTP.IdleTimeout),
+		server.WithTrustedProxies(trustedProxies...),
+		server.WithHashing(conf.Hashing.Algorithm, conf.Hashing.SaltLength),
+		server.WithNormalization(conf.Hashing.Normalization),
+		server.WithDummyValidation(conf.Hashing.DummyValidation),
+		server.WithValidateFloor(conf.Hashing.ValidateFloor),
+		server.WithCompliance(compliance),
+		server.WithWorkers(conf.Hashing.Workers, conf.Hashing.QueueSize),
+		server.WithMemoryLocking(conf.Hashing.LockMemory),
+		server.WithInputLimits(conf.Hashing.MaxPasswordLength, conf.Hashing.MaxHashLength),
+		server.WithHistory(conf.Hashing.MaxHistory, conf.Hashing.HistoryConcurrency),
+		server.WithChangePolicy(similarity.Policy{
+			MinDistance:           conf.Change.MinDistance,
+			RejectTransformations: conf.Change.RejectTransforms,
+		}),
+		server.WithValidateLogSampling(conf.Log.ValidateSampling),
+	}
+
+	authOpts, err := authOptions(conf.Auth)
+	if err != nil {
+		logrus.WithError(err).Error("Unable to initialize authentication")
+		return err
+	}
+	opts = append(opts, authOpts...)
+
+	// Audit trail
+	auditor, err := auditLogger(conf.Audit)
+	if err != nil {
+		logrus.WithError(err).Error("Unable to initialize audit log")
+		return err
+	}
+	defer auditor.Close()
+	opts = append(opts, server.WithAuditLogger(auditor))
+
+	// Managed credential store
+	credentials, err := credentialStore(conf.Store)
+	if err != nil {
+		logrus.WithError(err).Error("Unable to initialize credential store")
+		return err
+	}
+	if credentials != nil {
+		defer credentials.Close()
+		opts = append(opts, server.WithCredentialStore(credentials))
+	}
+
+	// Distributed tracing
+	closer, err := tracer.Setup(conf.Tracing, logrus.WithField("component", "tracer"))
+	if err != nil {
+		logrus.WithError(err).Error("Unable to initialize tracing")
+		return err
+	}
+	defer closer.Close()
+	opts = append(opts, server.WithTracer(opentracing.GlobalTracer()))
 
 	// Instanciate the server
-	s := server.New("localhost:5555", tlsL)
+	s := server.New(conf.Server.Name, tlsL, opts...)
 
-	// Server
+	// Runtime reload
+	reload := &reloader{
+		srv:     s,
+		certs:   certs,
+		current: conf,
+	}
+	// Signals received while a reload is pending are coalesced, forwarding
+	// never blocks
+	reloadCh := make(chan struct{}, 1)
+	done := make(chan struct{})
+	defer close(done)
 	go func() {
-		if err := func() error {
-			// Start server
-			if err := s.Start(); err != nil {
-				return err
+		for {
+			select {
+			case <-hupCh:
+				select {
+				case reloadCh <- struct{}{}:
+				default:
+				}
+			case <-done:
+				return
 			}
-			return nil
-		}(); err != nil {
-			errCh <- err
 		}
 	}()
+	go reload.Watch(reloadCh, conf.Reload.Watch, done)
+
+	// Server
+	go func() {
+		errCh <- s.Start()
+	}()
 
 	select {
 	case err := <-errCh:
-		logrus.WithError(err).Error("Server failed to start")
-		os.Exit(1)
+		if err != nil {
+			logrus.WithError(err).Error("Server failed")
+		}
+		return err
 	case sig := <-signalCh:
 		logrus.Infof("Signal received '%s'", sig)
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), conf.Server.ShutdownTimeout)
 	defer cancel()
 
-	stopped := make(chan struct{}, 1)
-	go s.Shutdown(ctx, stopped)
-	select {
-	case <-ctx.Done():
-		logrus.Warn("time limit reached, initiating hard shutdown")
-		return errors.New("Server is failed")
-	case <-stopped:
-		logrus.Info("server shutdown completed")
-		break
+	if err := s.Shutdown(ctx); err != nil {
+		logrus.WithError(err).Warn("Server shutdown did not complete gracefully")
+		return err
+	}
+	if err := <-errCh; err != nil {
+		return err
 	}
+
+	logrus.Info("server shutdown completed")
 	return nil
 }
+
+// keyPairs returns configured certificate files
+func keyPairs(conf config.TLS) []keypair.Pair {
+	var pairs []keypair.Pair
+	for _, pair := range conf.Certificates {
+		pairs = append(pairs, keypair.Pair{
+			CertFile: pair.CertFile,
+			KeyFile:  pair.KeyFile,
+		})
+	}
+	return pairs
+}
+
+// fipsCipherSuites lists cipher suites allowed in FIPS compliance mode
+var fipsCipherSuites = map[uint16]bool{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256: true,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384: true,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256:   true,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384:   true,
+}
+
+// fipsCurves lists elliptic curves allowed in FIPS compliance mode
+var fipsCurves = map[tls.CurveID]bool{
+	tls.CurveP256: true,
+	tls.CurveP384: true,
+	tls.CurveP521: true,
+}
+
+// serverTLSConfig builds listener TLS settings from configuration, certificates
+// are served from the given reloadable store. Compliance mode restricts
+// configured cipher suites and curves to approved ones.
+func serverTLSConfig(conf config.TLS, compliance *hashing.Compliance, certs *keypair.Store) (*tls.Config, error) {
+	version, err := conf.Version()
+	if err != nil {
+		return nil, err
+	}
+	ciphers, err := conf.CipherSuiteIDs()
+	if err != nil {
+		return nil, err
+	}
+	curves, err := conf.CurveIDs()
+	if err != nil {
+		return nil, err
+	}
+
+	var maxVersion uint16
+	if compliance != nil && compliance.Name == hashing.ComplianceFIPS {
+		ciphers = filterCipherSuites(ciphers, fipsCipherSuites)
+		if len(ciphers) == 0 {
+			return nil, errors.New("no approved cipher suite is configured for fips compliance mode")
+		}
+		curves = filterCurves(curves, fipsCurves)
+		if len(curves) == 0 {
+			return nil, errors.New("no approved curve is configured for fips compliance mode")
+		}
+		// TLS 1.3 cipher suites are not configurable
+		maxVersion = tls.VersionTLS12
+	}
+
+	return &tls.Config{
+		GetCertificate:           certs.GetCertificate,
+		Rand:                     rand.Reader,
+		NextProtos:               []string{},
+		MinVersion:               version,
+		MaxVersion:               maxVersion,
+		CipherSuites:             ciphers,
+		PreferServerCipherSuites: true,
+		CurvePreferences:         curves,
+	}, nil
+}
+
+func filterCipherSuites(ids []uint16, allowed map[uint16]bool) []uint16 {
+	var res []uint16
+	for _, id := range ids {
+		if allowed[id] {
+			res = append(res, id)
+		}
+	}
+	return res
+}
+
+func filterCurves(ids []tls.CurveID, allowed map[tls.CurveID]bool) []tls.CurveID {
+	var res []tls.CurveID
+	for _, id := range ids {
+		if allowed[id] {
+			res = append(res, id)
+		}
+	}
+	return res
+}
+
+// authOptions builds authentication and authorization server options from configuration
+func authOptions(conf config.Auth) ([]server.Option, error) {
+	opts := []server.Option{
+		server.WithPublicMethods(conf.PublicMethods...),
+		server.WithPublicPaths(conf.PublicPaths...),
+	}
+
+	// Authorization
+	var policy *auth.Policy
+	if len(conf.PolicyFile) > 0 {
+		var err error
+		policy, err = auth.LoadPolicy(conf.PolicyFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+	opts = append(opts, server.WithPolicy(policy))
+
+	if !conf.Enabled {
+		return append(opts, server.WithAuthenticator(nil)), nil
+	}
+
+	var authenticators []auth.Authenticator
+
+	// Static API keys
+	if len(conf.APIKeys) > 0 {
+		a, err := auth.APIKeys(conf.APIKeys)
+		if err != nil {
+			return nil, err
+		}
+		authenticators = append(authenticators, a)
+	}
+
+	// JWT
+	if len(conf.JWT.HS256Secret) > 0 || len(conf.JWT.JWKSFile) > 0 {
+		a, err := auth.JWT(conf.JWT)
+		if err != nil {
+			return nil, err
+		}
+		authenticators = append(authenticators, a)
+	}
+
+	if len(authenticators) == 0 {
+		return nil, errors.New("authentication is enabled but no api key or jwt setting is defined")
+	}
+
+	opts = append(opts, server.WithAuthenticator(auth.Chain(authenticators...)))
+
+	return opts, nil
+}
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"database/sql"
+	"fmt"
+
+	"go.zenithar.org/password/config"
+	"go.zenithar.org/password/store"
+)
+
+// credentialStore builds the managed credential store from configuration,
+// nil is returned when disabled.
+func credentialStore(conf config.Store) (store.Store, error) {
+	if !conf.Enabled {
+		return nil, nil
+	}
+
+	switch conf.Backend {
+	case "", "bolt":
+		return store.BoltStore(conf.File)
+	case "sql":
+		if !sqlDriverRegistered(conf.SQL.Driver) {
+			return nil, fmt.Errorf("sql driver '%s' is not compiled in, drivers are added with build tags (sqlite) or a blank import", conf.SQL.Driver)
+		}
+		return store.SQLStore(conf.SQL.Driver, conf.SQL.DSN)
+	}
+	return nil, fmt.Errorf("unsupported credential store backend '%s'", conf.Backend)
+}
+
+func sqlDriverRegistered(name string) bool {
+	for _, driver := range sql.Drivers() {
+		if driver == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,8 @@
+// +build sqlite
+
+package cmd
+
+import (
+	// SQLite driver of the sql credential store, requires cgo
+	_ "github.com/mattn/go-sqlite3"
+)
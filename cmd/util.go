@@ -2,30 +2,19 @@ package cmd
 
 import (
 	"context"
-	"crypto/tls"
 	"crypto/x509"
+	"fmt"
 	"io/ioutil"
 	"net"
 	"time"
 
+	"go.zenithar.org/password/config"
+
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
 )
 
-// mustLoadX509KeyPair handles certificate loading from file
-func mustLoadX509KeyPair(certFile, keyFile string) tls.Certificate {
-	pair, err := tls.LoadX509KeyPair(certFile, keyFile)
-	if err != nil {
-		logrus.WithError(err).WithFields(logrus.Fields{
-			"certFile": certFile,
-			"keyFile":  keyFile,
-		}).Fatalln("Unable to load certificates")
-	}
-
-	return pair
-}
-
 // handleShutdown handles the server shut down error.
 func handleShutdown(err error) {
 	if err != nil {
@@ -35,10 +24,11 @@ func handleShutdown(err error) {
 	}
 }
 
-func grpcClientConnection(ctx context.Context, server string) *grpc.ClientConn {
+func grpcClientConnection(ctx context.Context, conf config.Client, token string) *grpc.ClientConn {
+	server := conf.Address
 
 	// Load Certificates
-	certBytes, err := ioutil.ReadFile("./certs/server.ecdsa.crt")
+	certBytes, err := ioutil.ReadFile(conf.CAFile)
 	if err != nil {
 		logrus.WithError(err).Fatalln("Unable to read certificate")
 	}
@@ -55,6 +45,9 @@ func grpcClientConnection(ctx context.Context, server string) *grpc.ClientConn {
 	opts = append(opts, grpc.WithTransportCredentials(dcreds))
 	opts = append(opts, grpc.WithTimeout(10*time.Second))
 	opts = append(opts, grpc.WithBlock())
+	if len(token) > 0 {
+		opts = append(opts, grpc.WithPerRPCCredentials(bearerToken(token)))
+	}
 
 	// Initialize connection
 	conn, err := grpc.DialContext(ctx, server, opts...)
@@ -64,3 +57,16 @@ func grpcClientConnection(ctx context.Context, server string) *grpc.ClientConn {
 
 	return conn
 }
+
+// bearerToken implements gRPC per RPC credentials with a bearer token
+type bearerToken string
+
+func (t bearerToken) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{
+		"authorization": fmt.Sprintf("Bearer %s", string(t)),
+	}, nil
+}
+
+func (t bearerToken) RequireTransportSecurity() bool {
+	return true
+}
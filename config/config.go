@@ -0,0 +1,267 @@
+package config
+
+import (
+	"time"
+
+	"go.zenithar.org/password/auth"
+	"go.zenithar.org/password/utils/tracer"
+
+	"go.zenithar.org/butcher"
+)
+
+// Configuration contract
+type Configuration struct {
+	// Compliance restricts algorithms to an approved set ("none", "fips")
+	Compliance string `mapstructure:"compliance"`
+
+	Server  Server          `mapstructure:"server"`
+	TLS     TLS             `mapstructure:"tls"`
+	Client  Client          `mapstructure:"client"`
+	Hashing Hashing         `mapstructure:"hashing"`
+	Change  Change          `mapstructure:"change"`
+	Auth    Auth            `mapstructure:"auth"`
+	Audit   Audit           `mapstructure:"audit"`
+	Store   Store           `mapstructure:"store"`
+	Tracing tracer.Settings `mapstructure:"tracing"`
+	Log     Log             `mapstructure:"log"`
+	Reload  Reload          `mapstructure:"reload"`
+}
+
+// Server defines microserver settings
+type Server struct {
+	Listen          string        `mapstructure:"listen"`
+	Name            string        `mapstructure:"name"`
+	Gateway         string        `mapstructure:"gateway"`
+	SocketPath      string        `mapstructure:"socket_path"`
+	SocketMode      string        `mapstructure:"socket_mode"`
+	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"`
+	DrainDelay      time.Duration `mapstructure:"drain_delay"`
+	HTTP            HTTP          `mapstructure:"http"`
+	// TrustedProxies lists addresses or CIDR networks of proxies whose
+	// X-Forwarded-For values are trusted to resolve the caller address
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
+	// DisableCoreDumps sets RLIMIT_CORE to zero at startup, to never write
+	// cleartext passwords held in memory to disk
+	DisableCoreDumps bool `mapstructure:"disable_core_dumps"`
+}
+
+// HTTP defines HTTP server settings
+type HTTP struct {
+	ReadTimeout  time.Duration `mapstructure:"read_timeout"`
+	WriteTimeout time.Duration `mapstructure:"write_timeout"`
+	IdleTimeout  time.Duration `mapstructure:"idle_timeout"`
+}
+
+// TLS defines listener security settings
+type TLS struct {
+	Certificates     []Certificate `mapstructure:"certificates"`
+	MinVersion       string        `mapstructure:"min_version"`
+	CipherSuites     []string      `mapstructure:"cipher_suites"`
+	CurvePreferences []string      `mapstructure:"curve_preferences"`
+}
+
+// Certificate defines a X509 key pair
+type Certificate struct {
+	CertFile string `mapstructure:"cert_file"`
+	KeyFile  string `mapstructure:"key_file"`
+}
+
+// Client defines command line client settings
+type Client struct {
+	Address string `mapstructure:"address"`
+	CAFile  string `mapstructure:"ca_file"`
+}
+
+// Hashing defines password hashing settings
+type Hashing struct {
+	Algorithm  string `mapstructure:"algorithm"`
+	SaltLength int    `mapstructure:"salt_length"`
+	// Normalization is the Unicode normalization profile applied to passwords
+	// (none, nfc, nfkc or opaquestring), hashes record the one they use
+	Normalization string `mapstructure:"normalization"`
+	// DummyValidation verifies empty hashes against a server held hash and
+	// reports them as invalid, to hide unknown subjects
+	DummyValidation bool `mapstructure:"dummy_validation"`
+	// ValidateFloor is the minimum Validate response time, 0 disables it
+	ValidateFloor time.Duration `mapstructure:"validate_floor"`
+	// Workers bounds concurrent hashing operations, 0 uses the CPU count
+	Workers int `mapstructure:"workers"`
+	// QueueSize is the number of operations waiting for a worker before
+	// requests are rejected
+	QueueSize int `mapstructure:"queue_size"`
+	// MaxPasswordLength and MaxHashLength bound input sizes in bytes
+	MaxPasswordLength int `mapstructure:"max_password_length"`
+	MaxHashLength     int `mapstructure:"max_hash_length"`
+	// MaxHistory bounds the number of previous hashes checked for reuse, at
+	// most HistoryConcurrency of them are verified at once by a call
+	MaxHistory         int `mapstructure:"max_history"`
+	HistoryConcurrency int `mapstructure:"history_concurrency"`
+	// LockMemory locks cleartext password buffers in RAM to keep them out of
+	// swap
+	LockMemory bool `mapstructure:"lock_memory"`
+}
+
+// Change defines the similarity policy of password changes
+type Change struct {
+	// MinDistance is the minimum normalized edit distance between old and new
+	// passwords, from 0 to 1
+	MinDistance float64 `mapstructure:"min_distance"`
+	// RejectTransforms rejects new passwords derived from the old one by
+	// a case change, a number increment, an append or a prepend
+	RejectTransforms bool `mapstructure:"reject_transforms"`
+}
+
+// Auth defines caller authentication and authorization settings
+type Auth struct {
+	Enabled       bool             `mapstructure:"enabled"`
+	APIKeys       []auth.APIKey    `mapstructure:"api_keys"`
+	JWT           auth.JWTSettings `mapstructure:"jwt"`
+	PolicyFile    string           `mapstructure:"policy_file"`
+	PublicMethods []string         `mapstructure:"public_methods"`
+	PublicPaths   []string         `mapstructure:"public_paths"`
+}
+
+// Audit defines audit trail settings
+type Audit struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Sink    string `mapstructure:"sink"`
+	File    string `mapstructure:"file"`
+	// SyncInterval is the delay between file sink flushes to stable storage
+	// and anchor updates
+	SyncInterval time.Duration `mapstructure:"sync_interval"`
+	// HMACKey chains records, it is mandatory
+	HMACKey string      `mapstructure:"hmac_key"`
+	Syslog  AuditSyslog `mapstructure:"syslog"`
+	HTTP    AuditHTTP   `mapstructure:"http"`
+}
+
+// Store defines managed credential store settings
+type Store struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Backend is "bolt" or "sql"
+	Backend string   `mapstructure:"backend"`
+	File    string   `mapstructure:"file"`
+	SQL     StoreSQL `mapstructure:"sql"`
+}
+
+// StoreSQL defines SQL credential store settings
+type StoreSQL struct {
+	// Driver is a database/sql driver name compiled in the binary, "sqlite3"
+	// is available with the sqlite build tag
+	Driver string `mapstructure:"driver"`
+	DSN    string `mapstructure:"dsn"`
+}
+
+// AuditSyslog defines syslog audit sink settings
+type AuditSyslog struct {
+	Network string `mapstructure:"network"`
+	Address string `mapstructure:"address"`
+	Tag     string `mapstructure:"tag"`
+}
+
+// AuditHTTP defines HTTP audit sink settings
+type AuditHTTP struct {
+	URL     string        `mapstructure:"url"`
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+// Log defines logging settings
+type Log struct {
+	Level string `mapstructure:"level"`
+	// Format is the log output format ("logfmt", "json")
+	Format string `mapstructure:"format"`
+	// ValidateSampling logs one out of n successful Validate calls at info
+	// level, the others at debug level
+	ValidateSampling int `mapstructure:"validate_sampling"`
+}
+
+// Reload defines runtime reload settings
+type Reload struct {
+	// Watch enables file system notifications on configuration and certificate files
+	Watch bool `mapstructure:"watch"`
+}
+
+// -----------------------------------------------------------------------------
+
+// Defaults returns the default configuration values, indexed by viper key
+func Defaults() map[string]interface{} {
+	return map[string]interface{}{
+		"compliance":                "none",
+		"server.listen":             ":5555",
+		"server.name":               "localhost:5555",
+		"server.gateway":            "inprocess",
+		"server.socket_path":        "service.sock",
+		"server.socket_mode":        "0600",
+		"server.shutdown_timeout":   "10s",
+		"server.drain_delay":        "0s",
+		"server.disable_core_dumps": false,
+		"server.trusted_proxies":    []string{},
+		"server.http.read_timeout":  "5s",
+		"server.http.write_timeout": "10s",
+		"server.http.idle_timeout":  "120s",
+		"tls.certificates": []map[string]interface{}{
+			{"cert_file": "./certs/server.ecdsa.crt", "key_file": "./certs/server.ecdsa.key"},
+			{"cert_file": "./certs/server.rsa.crt", "key_file": "./certs/server.rsa.key"},
+		},
+		"tls.min_version":             "1.2",
+		"tls.cipher_suites":           []string{"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384", "TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384", "TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305", "TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305"},
+		"tls.curve_preferences":       []string{"P384", "X25519"},
+		"client.address":              "localhost:5555",
+		"client.ca_file":              "./certs/server.ecdsa.crt",
+		"hashing.algorithm":           butcher.DefaultAlgorithm,
+		"hashing.salt_length":         64,
+		"hashing.workers":             0,
+		"hashing.queue_size":          256,
+		"hashing.lock_memory":         false,
+		"hashing.normalization":       "none",
+		"hashing.dummy_validation":    false,
+		"hashing.validate_floor":      "0s",
+		"hashing.max_password_length": 1024,
+		"hashing.max_hash_length":     1024,
+		"hashing.max_history":         24,
+		"hashing.history_concurrency": 4,
+		"change.min_distance":         0.25,
+		"change.reject_transforms":    true,
+		"auth.enabled":                false,
+		"auth.api_keys":               []map[string]interface{}{},
+		"auth.policy_file":            "",
+		"auth.public_methods":         auth.DefaultPublicMethods,
+		"auth.public_paths":           auth.DefaultPublicPaths,
+		"auth.jwt.hs256_secret":       "",
+		"auth.jwt.jwks_file":          "",
+		"auth.jwt.issuer":             "",
+		"auth.jwt.audience":           "",
+		"audit.enabled":               false,
+		"audit.sink":                  "file",
+		"audit.file":                  "audit.log",
+		"audit.sync_interval":         "1s",
+		"audit.hmac_key":              "",
+		"audit.syslog.network":        "",
+		"audit.syslog.address":        "",
+		"audit.syslog.tag":            "password",
+		"audit.http.url":              "",
+		"audit.http.timeout":          "5s",
+		"store.enabled":               false,
+		"store.backend":               "bolt",
+		"store.file":                  "credentials.db",
+		"store.sql.driver":            "sqlite3",
+		"store.sql.dsn":               "",
+		"tracing.enabled":             false,
+		"tracing.service_name":        "password",
+		"tracing.endpoint":            "localhost:6831",
+		"tracing.sampler":             "probabilistic",
+		"tracing.sample_rate":         0.01,
+		"tracing.log_spans":           false,
+		"log.level":                   "info",
+		"log.format":                  "logfmt",
+		"log.validate_sampling":       1,
+		"reload.watch":                true,
+	}
+}
+
+// Secrets lists viper keys holding secret values, masked when printed
+var Secrets = []string{
+	"auth.jwt.hs256_secret",
+	"audit.hmac_key",
+	"store.sql.dsn",
+}
@@ -0,0 +1,309 @@
+package config
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.zenithar.org/password/audit"
+	"go.zenithar.org/password/hashing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"go.zenithar.org/butcher/hasher"
+)
+
+// SetDefaults registers default values and environment binding in the given
+// viper instance. Environment variables are prefixed by "PASSWORD_", with
+// "_" as key separator (e.g. PASSWORD_SERVER_LISTEN).
+func SetDefaults(v *viper.Viper) {
+	for key, value := range Defaults() {
+		v.SetDefault(key, value)
+	}
+
+	v.SetEnvPrefix("password")
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+}
+
+// Load decodes and validates the configuration from the given viper instance
+func Load(v *viper.Viper) (*Configuration, error) {
+	var cfg Configuration
+	if err := v.Unmarshal(&cfg); err != nil {
+		return nil, fmt.Errorf("config: unable to decode configuration, %v", err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &cfg, nil
+}
+
+// Validate checks configuration consistency
+func (c *Configuration) Validate() error {
+	var errs []string
+	check := func(cond bool, format string, args ...interface{}) {
+		if !cond {
+			errs = append(errs, fmt.Sprintf(format, args...))
+		}
+	}
+
+	// Server
+	check(len(c.Server.Listen) > 0, "server.listen is mandatory")
+	switch c.Server.Gateway {
+	case "inprocess":
+	case "unix":
+		check(len(c.Server.SocketPath) > 0, "server.socket_path is mandatory for unix gateway")
+		_, err := c.Server.FileMode()
+		check(err == nil, "server.socket_mode '%s' is not a valid octal file mode", c.Server.SocketMode)
+	default:
+		errs = append(errs, fmt.Sprintf("server.gateway '%s' is not supported", c.Server.Gateway))
+	}
+	check(c.Server.ShutdownTimeout > 0, "server.shutdown_timeout must be positive")
+	check(c.Server.DrainDelay >= 0 && c.Server.DrainDelay < c.Server.ShutdownTimeout, "server.drain_delay must not be negative and lower than server.shutdown_timeout")
+	check(c.Server.HTTP.ReadTimeout >= 0, "server.http.read_timeout must not be negative")
+	check(c.Server.HTTP.WriteTimeout >= 0, "server.http.write_timeout must not be negative")
+	check(c.Server.HTTP.IdleTimeout >= 0, "server.http.idle_timeout must not be negative")
+	if _, err := c.Server.TrustedNetworks(); err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	// TLS
+	check(len(c.TLS.Certificates) > 0, "tls.certificates must contain at least one key pair")
+	for i, cert := range c.TLS.Certificates {
+		check(len(cert.CertFile) > 0 && len(cert.KeyFile) > 0, "tls.certificates[%d] requires cert_file and key_file", i)
+	}
+	if _, err := c.TLS.Version(); err != nil {
+		errs = append(errs, err.Error())
+	}
+	if _, err := c.TLS.CipherSuiteIDs(); err != nil {
+		errs = append(errs, err.Error())
+	}
+	if _, err := c.TLS.CurveIDs(); err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	// Hashing
+	_, ok := hasher.Strategies[c.Hashing.Algorithm]
+	check(ok, "hashing.algorithm '%s' is not supported", c.Hashing.Algorithm)
+	check(c.Hashing.SaltLength >= 16, "hashing.salt_length must be at least 16 bytes")
+	check(c.Hashing.Workers >= 0, "hashing.workers must not be negative")
+	check(c.Hashing.QueueSize > 0, "hashing.queue_size must be positive")
+	check(c.Hashing.MaxPasswordLength > 0, "hashing.max_password_length must be positive")
+	check(c.Hashing.MaxHashLength > 0, "hashing.max_hash_length must be positive")
+	check(c.Hashing.MaxHistory > 0, "hashing.max_history must be positive")
+	check(c.Hashing.HistoryConcurrency > 0, "hashing.history_concurrency must be positive")
+	_, err := hashing.NormalizationProfile(c.Hashing.Normalization)
+	check(err == nil, "hashing.normalization '%s' is not supported", c.Hashing.Normalization)
+	check(c.Hashing.ValidateFloor >= 0, "hashing.validate_floor must not be negative")
+
+	// Change
+	check(c.Change.MinDistance >= 0 && c.Change.MinDistance <= 1, "change.min_distance must be between 0 and 1")
+
+	// Compliance
+	compliance, err := hashing.ComplianceMode(c.Compliance)
+	if err != nil {
+		errs = append(errs, fmt.Sprintf("compliance '%s' is not supported", c.Compliance))
+	}
+	if compliance != nil {
+		check(compliance.Allows(c.Hashing.Algorithm), "hashing.algorithm '%s' is not approved in %s compliance mode", c.Hashing.Algorithm, compliance.Name)
+		check(c.Hashing.SaltLength >= compliance.MinSaltLength, "hashing.salt_length must be at least %d bytes in %s compliance mode", compliance.MinSaltLength, compliance.Name)
+	}
+
+	// Auth
+	if c.Auth.Enabled {
+		check(len(c.Auth.APIKeys) > 0 || len(c.Auth.JWT.HS256Secret) > 0 || len(c.Auth.JWT.JWKSFile) > 0,
+			"auth.enabled requires api_keys or jwt settings")
+	}
+
+	// Audit
+	if c.Audit.Enabled {
+		check(len(c.Audit.HMACKey) >= audit.MinKeyLength, "audit.hmac_key of at least %d bytes is mandatory", audit.MinKeyLength)
+		switch c.Audit.Sink {
+		case "file":
+			check(len(c.Audit.File) > 0, "audit.file is mandatory for file sink")
+			check(c.Audit.SyncInterval > 0, "audit.sync_interval must be positive")
+		case "syslog":
+		case "http":
+			check(len(c.Audit.HTTP.URL) > 0, "audit.http.url is mandatory for http sink")
+		default:
+			errs = append(errs, fmt.Sprintf("audit.sink '%s' is not supported", c.Audit.Sink))
+		}
+	}
+
+	// Store
+	if c.Store.Enabled {
+		switch c.Store.Backend {
+		case "bolt":
+			check(len(c.Store.File) > 0, "store.file is mandatory for bolt backend")
+		case "sql":
+			check(len(c.Store.SQL.Driver) > 0, "store.sql.driver is mandatory for sql backend")
+			check(len(c.Store.SQL.DSN) > 0, "store.sql.dsn is mandatory for sql backend")
+		default:
+			errs = append(errs, fmt.Sprintf("store.backend '%s' is not supported", c.Store.Backend))
+		}
+	}
+
+	// Tracing
+	if err := c.Tracing.Validate(); err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	// Log
+	_, err = logrus.ParseLevel(c.Log.Level)
+	check(err == nil, "log.level '%s' is not supported", c.Log.Level)
+	_, err = c.Log.Formatter()
+	check(err == nil, "log.format '%s' is not supported", c.Log.Format)
+	check(c.Log.ValidateSampling > 0, "log.validate_sampling must be positive")
+
+	if len(errs) > 0 {
+		return errors.New("config: invalid configuration, " + strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+// FileMode returns the gateway unix socket permissions
+func (s Server) FileMode() (os.FileMode, error) {
+	mode, err := strconv.ParseUint(s.SocketMode, 8, 32)
+	if err != nil {
+		return 0, err
+	}
+	return os.FileMode(mode).Perm(), nil
+}
+
+// TrustedNetworks returns the trusted proxy networks, single addresses are
+// full length networks
+func (s Server) TrustedNetworks() ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, value := range s.TrustedProxies {
+		if ip := net.ParseIP(value); ip != nil {
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, fmt.Errorf("server.trusted_proxies '%s' is not a valid address or network", value)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// Formatter returns the log formatter of the configured format
+func (l Log) Formatter() (logrus.Formatter, error) {
+	switch strings.ToLower(l.Format) {
+	case "logfmt":
+		return &logrus.TextFormatter{DisableColors: true, FullTimestamp: true}, nil
+	case "json":
+		return &logrus.JSONFormatter{}, nil
+	default:
+		return nil, fmt.Errorf("log.format '%s' is not supported", l.Format)
+	}
+}
+
+// Settings returns the decoded configuration values as nested maps indexed
+// by viper keys, durations are formatted.
+func (c *Configuration) Settings() map[string]interface{} {
+	return settingsOf(reflect.ValueOf(*c)).(map[string]interface{})
+}
+
+func settingsOf(v reflect.Value) interface{} {
+	if d, ok := v.Interface().(time.Duration); ok {
+		return d.String()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		settings := map[string]interface{}{}
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			key := field.Tag.Get("mapstructure")
+			if len(field.PkgPath) > 0 || len(key) == 0 {
+				continue
+			}
+			settings[key] = settingsOf(v.Field(i))
+		}
+		return settings
+	case reflect.Slice:
+		values := make([]interface{}, v.Len())
+		for i := range values {
+			values[i] = settingsOf(v.Index(i))
+		}
+		return values
+	}
+
+	return v.Interface()
+}
+
+// -----------------------------------------------------------------------------
+
+var (
+	tlsVersions = map[string]uint16{
+		"1.0": tls.VersionTLS10,
+		"1.1": tls.VersionTLS11,
+		"1.2": tls.VersionTLS12,
+	}
+
+	cipherSuites = map[string]uint16{
+		"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256": tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+		"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384": tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+		"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256":   tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+		"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384":   tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+		"TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305":  tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+		"TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305":    tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+	}
+
+	curves = map[string]tls.CurveID{
+		"P256":   tls.CurveP256,
+		"P384":   tls.CurveP384,
+		"P521":   tls.CurveP521,
+		"X25519": tls.X25519,
+	}
+)
+
+// Version returns the minimal TLS protocol version
+func (t TLS) Version() (uint16, error) {
+	v, ok := tlsVersions[t.MinVersion]
+	if !ok {
+		return 0, fmt.Errorf("tls.min_version '%s' is not supported", t.MinVersion)
+	}
+	return v, nil
+}
+
+// CipherSuiteIDs returns the configured cipher suite identifiers
+func (t TLS) CipherSuiteIDs() ([]uint16, error) {
+	var ids []uint16
+	for _, name := range t.CipherSuites {
+		id, ok := cipherSuites[strings.ToUpper(name)]
+		if !ok {
+			return nil, fmt.Errorf("tls.cipher_suites '%s' is not supported", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// CurveIDs returns the configured elliptic curve preferences
+func (t TLS) CurveIDs() ([]tls.CurveID, error) {
+	var ids []tls.CurveID
+	for _, name := range t.CurvePreferences {
+		id, ok := curves[strings.ToUpper(name)]
+		if !ok {
+			return nil, fmt.Errorf("tls.curve_preferences '%s' is not supported", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
@@ -0,0 +1,141 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func defaultConfiguration(t *testing.T) *Configuration {
+	v := viper.New()
+	SetDefaults(v)
+
+	cfg, err := Load(v)
+	if err != nil {
+		t.Fatalf("default configuration should be valid, got %v", err)
+	}
+	return cfg
+}
+
+// lookup returns the value of the given viper key in nested settings
+func lookup(settings map[string]interface{}, key string) (interface{}, bool) {
+	path := strings.Split(key, ".")
+	for _, name := range path[:len(path)-1] {
+		sub, ok := settings[name].(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		settings = sub
+	}
+	value, ok := settings[path[len(path)-1]]
+	return value, ok
+}
+
+func TestDefaults(t *testing.T) {
+	settings := defaultConfiguration(t).Settings()
+
+	// Every default is decoded in a configuration field
+	for key := range Defaults() {
+		if _, ok := lookup(settings, key); !ok {
+			t.Errorf("default '%s' is not a configuration setting", key)
+		}
+	}
+	for _, key := range Secrets {
+		if _, ok := lookup(settings, key); !ok {
+			t.Errorf("secret '%s' is not a configuration setting", key)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		key    string
+		change func(c *Configuration)
+	}{
+		{"server.listen", func(c *Configuration) { c.Server.Listen = "" }},
+		{"server.gateway", func(c *Configuration) { c.Server.Gateway = "tcp" }},
+		{"server.socket_path", func(c *Configuration) { c.Server.Gateway, c.Server.SocketPath = "unix", "" }},
+		{"server.socket_mode", func(c *Configuration) { c.Server.Gateway, c.Server.SocketMode = "unix", "rw" }},
+		{"server.shutdown_timeout", func(c *Configuration) { c.Server.ShutdownTimeout = 0 }},
+		{"server.drain_delay", func(c *Configuration) { c.Server.DrainDelay = c.Server.ShutdownTimeout }},
+		{"server.http.read_timeout", func(c *Configuration) { c.Server.HTTP.ReadTimeout = -time.Second }},
+		{"server.trusted_proxies", func(c *Configuration) { c.Server.TrustedProxies = []string{"10.0.0.0/33"} }},
+		{"tls.certificates", func(c *Configuration) { c.TLS.Certificates = nil }},
+		{"tls.certificates[1]", func(c *Configuration) { c.TLS.Certificates[1].KeyFile = "" }},
+		{"tls.min_version", func(c *Configuration) { c.TLS.MinVersion = "1.3" }},
+		{"tls.cipher_suites", func(c *Configuration) { c.TLS.CipherSuites = []string{"TLS_RSA_WITH_RC4_128_SHA"} }},
+		{"tls.curve_preferences", func(c *Configuration) { c.TLS.CurvePreferences = []string{"P224"} }},
+		{"hashing.algorithm", func(c *Configuration) { c.Hashing.Algorithm = "md5" }},
+		{"hashing.salt_length", func(c *Configuration) { c.Hashing.SaltLength = 8 }},
+		{"hashing.queue_size", func(c *Configuration) { c.Hashing.QueueSize = 0 }},
+		{"hashing.max_history", func(c *Configuration) { c.Hashing.MaxHistory = 0 }},
+		{"hashing.history_concurrency", func(c *Configuration) { c.Hashing.HistoryConcurrency = 0 }},
+		{"hashing.normalization", func(c *Configuration) { c.Hashing.Normalization = "nfd" }},
+		{"hashing.validate_floor", func(c *Configuration) { c.Hashing.ValidateFloor = -time.Second }},
+		{"change.min_distance", func(c *Configuration) { c.Change.MinDistance = 1.5 }},
+		{"compliance", func(c *Configuration) { c.Compliance = "pci" }},
+		{"auth.enabled", func(c *Configuration) { c.Auth.Enabled = true }},
+		{"audit.hmac_key", func(c *Configuration) { c.Audit.Enabled, c.Audit.HMACKey = true, "short" }},
+		{"audit.sink", func(c *Configuration) {
+			c.Audit.Enabled, c.Audit.HMACKey, c.Audit.Sink = true, "0123456789abcdef0123456789abcdef", "kafka"
+		}},
+		{"store.backend", func(c *Configuration) { c.Store.Enabled, c.Store.Backend = true, "redis" }},
+		{"store.sql.dsn", func(c *Configuration) { c.Store.Enabled, c.Store.Backend = true, "sql" }},
+		{"log.format", func(c *Configuration) { c.Log.Format = "xml" }},
+		{"log.validate_sampling", func(c *Configuration) { c.Log.ValidateSampling = 0 }},
+	}
+	for _, tt := range tests {
+		cfg := defaultConfiguration(t)
+		tt.change(cfg)
+
+		err := cfg.Validate()
+		if err == nil {
+			t.Errorf("%s: Validate() should fail", tt.key)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.key) {
+			t.Errorf("%s: Validate() error should name the setting, got %v", tt.key, err)
+		}
+	}
+
+	// Several errors are reported at once
+	cfg := defaultConfiguration(t)
+	cfg.Server.Listen, cfg.Log.Format = "", "xml"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "server.listen") || !strings.Contains(err.Error(), "log.format") {
+		t.Errorf("Validate() should report every error, got %v", err)
+	}
+}
+
+func TestSettings(t *testing.T) {
+	cfg := defaultConfiguration(t)
+	cfg.Auth.JWT.HS256Secret = "secret"
+	settings := cfg.Settings()
+
+	tests := []struct {
+		key      string
+		expected interface{}
+	}{
+		{"server.shutdown_timeout", "10s"},
+		{"server.http.idle_timeout", "2m0s"},
+		{"hashing.salt_length", 64},
+		{"change.reject_transforms", true},
+		{"auth.jwt.hs256_secret", "secret"},
+		{"tracing.sample_rate", 0.01},
+	}
+	for _, tt := range tests {
+		if value, _ := lookup(settings, tt.key); value != tt.expected {
+			t.Errorf("%s: Settings() = %v, expected %v", tt.key, value, tt.expected)
+		}
+	}
+
+	certs, _ := lookup(settings, "tls.certificates")
+	list, ok := certs.([]interface{})
+	if !ok || len(list) != 2 {
+		t.Fatalf("tls.certificates should be a list of 2 key pairs, got %v", certs)
+	}
+	if cert, ok := list[0].(map[string]interface{}); !ok || cert["cert_file"] != "./certs/server.ecdsa.crt" {
+		t.Errorf("key pairs should be indexed by setting names, got %v", list[0])
+	}
+}
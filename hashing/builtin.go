@@ -0,0 +1,200 @@
+package hashing
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/base64"
+	"hash"
+	"strconv"
+	"strings"
+
+	"go.zenithar.org/password/utils/secret"
+
+	"github.com/lhecker/argon2"
+	"github.com/minio/blake2b-simd"
+	"go.zenithar.org/butcher"
+	"go.zenithar.org/butcher/hasher"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// builtinParameters defines the parameters used by vendored strategies
+var builtinParameters = map[string]map[string]string{
+	hasher.Argon2i: {
+		"v": strconv.Itoa(int(argon2.DefaultConfig().Version)),
+		"m": strconv.Itoa(int(argon2.DefaultConfig().MemoryCost)),
+		"t": strconv.Itoa(int(argon2.DefaultConfig().TimeCost)),
+		"p": strconv.Itoa(int(argon2.DefaultConfig().Parallelism)),
+	},
+	hasher.BcryptBlake2b512: {"c": "12"},
+	hasher.BcryptSha512:     {"c": "12"},
+	hasher.Pbkdf2Blake2b512: {"i": "50000", "l": "64"},
+	hasher.Pbkdf2Sha512:     {"i": "50000", "l": "64"},
+	hasher.Pbkdf2Keccak512:  {"i": "50000", "l": "64"},
+}
+
+// bcryptDigests defines the password pre-hash of bcrypt strategies
+var bcryptDigests = map[string]func() hash.Hash{
+	hasher.BcryptBlake2b512: blake2b.New512,
+	hasher.BcryptSha512:     sha512.New,
+}
+
+type builtinStrategy struct {
+	name string
+	salt func() ([]byte, error)
+}
+
+// Builtin returns the vendored butcher strategy with the given name, salts are
+// taken from the given source.
+func Builtin(name string, source *SaltSource) (Strategy, error) {
+	return newBuiltin(name, source.Salt)
+}
+
+func newBuiltin(name string, salt func() ([]byte, error)) (*builtinStrategy, error) {
+	if _, ok := builtinParameters[name]; !ok {
+		return nil, ErrUnknownStrategy
+	}
+
+	return &builtinStrategy{
+		name: name,
+		salt: salt,
+	}, nil
+}
+
+// DefaultRegistry returns a registry holding all vendored butcher strategies
+// sharing the given salt source.
+func DefaultRegistry(source *SaltSource) (*Registry, error) {
+	r, err := NewRegistry()
+	if err != nil {
+		return nil, err
+	}
+
+	for name := range builtinParameters {
+		s, err := Builtin(name, source)
+		if err != nil {
+			return nil, err
+		}
+		if err := r.Register(s); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+func (s *builtinStrategy) Name() string {
+	return s.name
+}
+
+// MemoryCost returns the argon2 memory cost, other vendored strategies are not
+// memory hard.
+func (s *builtinStrategy) MemoryCost() int64 {
+	if s.name != hasher.Argon2i {
+		return 0
+	}
+	return int64(argon2.DefaultConfig().MemoryCost) * 1024
+}
+
+// Hash generates the salt itself, butcher nonce factories can't report
+// entropy failures.
+func (s *builtinStrategy) Hash(password []byte) (string, error) {
+	salt, err := s.salt()
+	if err != nil {
+		return "", err
+	}
+
+	b, err := butcher.New(
+		butcher.WithAlgorithm(s.name),
+		butcher.WithNonce(butcher.FixedNonce(salt)),
+	)
+	if err != nil {
+		return "", err
+	}
+
+	return b.Hash(password)
+}
+
+func (s *builtinStrategy) Verify(encoded string, password []byte) (bool, error) {
+	if strings.Count(encoded, "$") < 4 {
+		return false, ErrMalformedHash
+	}
+	if digest, ok := bcryptDigests[s.name]; ok {
+		return verifyBcrypt(digest, encoded, password)
+	}
+	return butcher.Verify([]byte(encoded), password)
+}
+
+// verifyBcrypt checks bcrypt based hashes, butcher.Verify can't as bcrypt
+// embeds its own random salt.
+func verifyBcrypt(digest func() hash.Hash, encoded string, password []byte) (bool, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 5 {
+		return false, ErrMalformedHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[3])
+	if err != nil {
+		return false, ErrMalformedHash
+	}
+	hashed, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false, ErrMalformedHash
+	}
+
+	// Password derived digest and MAC state are cleared after use
+	mac := hmac.New(digest, salt)
+	mac.Write(password)
+	prehash := mac.Sum(nil)
+	mac.Reset()
+	defer secret.Wipe(prehash)
+
+	switch err := bcrypt.CompareHashAndPassword(hashed, prehash); err {
+	case nil:
+		return true, nil
+	case bcrypt.ErrMismatchedHashAndPassword:
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+func (s *builtinStrategy) Parameters(encoded string) (map[string]string, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 5 || parts[0] != s.name {
+		return nil, ErrMalformedHash
+	}
+
+	params := map[string]string{}
+	for _, segment := range parts[1:3] {
+		if len(segment) == 0 {
+			continue
+		}
+		for _, pair := range strings.Split(segment, ",") {
+			kv := strings.SplitN(pair, "=", 2)
+			if len(kv) != 2 {
+				return nil, ErrMalformedHash
+			}
+			params[kv[0]] = kv[1]
+		}
+	}
+
+	return params, nil
+}
+
+func (s *builtinStrategy) NeedsUpgrade(encoded string) bool {
+	params, err := s.Parameters(encoded)
+	if err != nil {
+		return true
+	}
+
+	expected := builtinParameters[s.name]
+	if len(params) != len(expected) {
+		return true
+	}
+	for k, v := range expected {
+		if params[k] != v {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,97 @@
+package hashing
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.zenithar.org/butcher/hasher"
+)
+
+const (
+	// ComplianceNone disables algorithm restrictions
+	ComplianceNone = "none"
+	// ComplianceFIPS restricts algorithms to FIPS approved primitives
+	ComplianceFIPS = "fips"
+)
+
+// Compliance restricts strategies usable to produce new hashes. Hashes from
+// other strategies are still verified but reported as needing a rehash.
+type Compliance struct {
+	// Name identifies the compliance mode
+	Name string
+	// Approved lists strategies allowed for new hashes
+	Approved []string
+	// MinIterations is the minimum PBKDF2 iteration count
+	MinIterations int
+	// MinSaltLength is the minimum salt length in bytes
+	MinSaltLength int
+}
+
+// FIPS allows PBKDF2 with SHA-512 only (SP 800-132), with at least 128 bits
+// of salt and 10000 iterations (SP 800-63B).
+var FIPS = Compliance{
+	Name:          ComplianceFIPS,
+	Approved:      []string{hasher.Pbkdf2Sha512},
+	MinIterations: 10000,
+	MinSaltLength: 16,
+}
+
+// ComplianceMode returns the compliance settings of the given mode, nil is
+// returned when no restriction applies.
+func ComplianceMode(mode string) (*Compliance, error) {
+	switch mode {
+	case "", ComplianceNone:
+		return nil, nil
+	case ComplianceFIPS:
+		c := FIPS
+		return &c, nil
+	}
+	return nil, fmt.Errorf("hashing: unsupported compliance mode '%s'", mode)
+}
+
+// Allows returns true when the strategy can produce new hashes
+func (c *Compliance) Allows(name string) bool {
+	if c == nil {
+		return true
+	}
+	for _, approved := range c.Approved {
+		if approved == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Compliant returns true when the encoded hash was produced by an approved
+// strategy with sufficient parameters.
+func (c *Compliance) Compliant(s Strategy, encoded string) bool {
+	if c == nil {
+		return true
+	}
+	if !c.Allows(s.Name()) {
+		return false
+	}
+
+	params, err := s.Parameters(encoded)
+	if err != nil {
+		return false
+	}
+	if value, ok := params["i"]; ok {
+		iterations, err := strconv.Atoi(value)
+		if err != nil || iterations < c.MinIterations {
+			return false
+		}
+	}
+
+	// Salt is the fourth segment of vendored encodings
+	if parts := strings.Split(encoded, "$"); len(parts) == 5 {
+		salt, err := base64.RawStdEncoding.DecodeString(parts[3])
+		if err != nil || len(salt) < c.MinSaltLength {
+			return false
+		}
+	}
+
+	return true
+}
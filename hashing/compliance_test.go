@@ -0,0 +1,83 @@
+package hashing
+
+import (
+	"strings"
+	"testing"
+
+	"go.zenithar.org/butcher/hasher"
+)
+
+func TestComplianceMode(t *testing.T) {
+	for _, mode := range []string{"", ComplianceNone} {
+		c, err := ComplianceMode(mode)
+		if err != nil || c != nil {
+			t.Errorf("ComplianceMode(%q) = %v %v, expected no restriction", mode, c, err)
+		}
+		if !c.Allows(hasher.Argon2i) || !c.Compliant(&plainStrategy{name: "plain"}, "plain$v=1$secret") {
+			t.Errorf("ComplianceMode(%q) should allow every strategy", mode)
+		}
+	}
+
+	if _, err := ComplianceMode("pci"); err == nil {
+		t.Error("ComplianceMode should reject unknown modes")
+	}
+
+	// Returned settings are a copy
+	c, err := ComplianceMode(ComplianceFIPS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Approved = append(c.Approved, hasher.Argon2i)
+	if FIPS.Approved[0] != hasher.Pbkdf2Sha512 || len(FIPS.Approved) != 1 {
+		t.Error("ComplianceMode should not share the FIPS settings")
+	}
+}
+
+func TestCompliant(t *testing.T) {
+	c, err := ComplianceMode(ComplianceFIPS)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{hasher.Argon2i, hasher.BcryptSha512, hasher.Pbkdf2Blake2b512, hasher.Pbkdf2Keccak512} {
+		if c.Allows(name) {
+			t.Errorf("fips mode should reject %s", name)
+		}
+	}
+	if !c.Allows(hasher.Pbkdf2Sha512) {
+		t.Errorf("fips mode should allow %s", hasher.Pbkdf2Sha512)
+	}
+
+	pbkdf2, err := newBuiltin(hasher.Pbkdf2Sha512, func() ([]byte, error) {
+		return []byte("01234567"), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	shortSalt, err := pbkdf2.Hash(katPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	argon2, _ := newBuiltin(hasher.Argon2i, nil)
+	expected := knownAnswers[hasher.Pbkdf2Sha512]
+
+	tests := []struct {
+		name      string
+		strategy  Strategy
+		encoded   string
+		compliant bool
+	}{
+		{"pbkdf2", pbkdf2, expected, true},
+		{"more iterations", pbkdf2, strings.Replace(expected, "i=50000", "i=100000", 1), true},
+		{"few iterations", pbkdf2, strings.Replace(expected, "i=50000", "i=1000", 1), false},
+		{"invalid iterations", pbkdf2, strings.Replace(expected, "i=50000", "i=many", 1), false},
+		{"short salt", pbkdf2, shortSalt, false},
+		{"malformed", pbkdf2, "pbkdf2+sha512$", false},
+		{"unapproved strategy", argon2, knownAnswers[hasher.Argon2i], false},
+	}
+	for _, tt := range tests {
+		if compliant := c.Compliant(tt.strategy, tt.encoded); compliant != tt.compliant {
+			t.Errorf("%s: Compliant() = %v, expected %v", tt.name, compliant, tt.compliant)
+		}
+	}
+}
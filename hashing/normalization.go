@@ -0,0 +1,125 @@
+package hashing
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/secure/precis"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	// NormalizationNone hashes passwords as received
+	NormalizationNone = "none"
+	// NormalizationNFC applies Unicode canonical composition
+	NormalizationNFC = "nfc"
+	// NormalizationNFKC applies Unicode compatibility composition
+	NormalizationNFKC = "nfkc"
+	// NormalizationOpaqueString applies the RFC 8265 OpaqueString profile
+	NormalizationOpaqueString = "opaquestring"
+)
+
+// normalizationSeparator ends the normalization profile prefix of encoded
+// hashes, strategy names can't contain it.
+const normalizationSeparator = ":"
+
+var (
+	// ErrUnknownNormalization is raised when an encoded hash records an
+	// unsupported normalization profile
+	ErrUnknownNormalization = errors.New("hashing: unknown normalization profile")
+	// ErrInvalidPassword is raised when a password can't be normalized
+	ErrInvalidPassword = errors.New("hashing: password contains invalid UTF-8 or disallowed characters")
+)
+
+// NormalizationProfile validates the given normalization profile name
+func NormalizationProfile(profile string) (string, error) {
+	switch profile {
+	case "":
+		return NormalizationNone, nil
+	case NormalizationNone, NormalizationNFC, NormalizationNFKC, NormalizationOpaqueString:
+		return profile, nil
+	}
+	return "", fmt.Errorf("hashing: unsupported normalization profile '%s'", profile)
+}
+
+// Normalize returns the password normalized with the given profile, the
+// result may share the password memory. Except with NormalizationNone, which
+// returns the password unchanged, invalid UTF-8 and control characters are
+// rejected.
+func Normalize(profile string, password []byte) ([]byte, error) {
+	switch profile {
+	case "", NormalizationNone:
+		return password, nil
+	case NormalizationNFC, NormalizationNFKC, NormalizationOpaqueString:
+	default:
+		return nil, ErrUnknownNormalization
+	}
+
+	// PRECIS replaces invalid UTF-8 sequences instead of rejecting them
+	if !utf8.Valid(password) {
+		return nil, ErrInvalidPassword
+	}
+
+	if profile == NormalizationOpaqueString {
+		out, err := precis.OpaqueString.Bytes(password)
+		if err != nil {
+			return nil, ErrInvalidPassword
+		}
+		return out, nil
+	}
+
+	if hasControl(password) {
+		return nil, ErrInvalidPassword
+	}
+	if profile == NormalizationNFKC {
+		return norm.NFKC.Bytes(password), nil
+	}
+	return norm.NFC.Bytes(password), nil
+}
+
+// hasControl returns true when the valid UTF-8 password contains a control
+// character
+func hasControl(password []byte) bool {
+	for i := 0; i < len(password); {
+		r, size := utf8.DecodeRune(password[i:])
+		if unicode.IsControl(r) {
+			return true
+		}
+		i += size
+	}
+	return false
+}
+
+// RecordNormalization prefixes the encoded hash with the normalization
+// profile applied to the password, so that verification uses the same
+// profile whatever the current settings.
+func RecordNormalization(profile, encoded string) string {
+	if profile == "" || profile == NormalizationNone {
+		return encoded
+	}
+	return profile + normalizationSeparator + encoded
+}
+
+// SplitNormalization returns the normalization profile recorded in the
+// encoded hash and the strategy encoded hash. Hashes without profile were
+// produced from passwords as received.
+func SplitNormalization(encoded string) (string, string, error) {
+	i := strings.Index(encoded, "$")
+	if i <= 0 {
+		return "", "", ErrMalformedHash
+	}
+	j := strings.Index(encoded[:i], normalizationSeparator)
+	if j < 0 {
+		return NormalizationNone, encoded, nil
+	}
+
+	profile := encoded[:j]
+	switch profile {
+	case NormalizationNFC, NormalizationNFKC, NormalizationOpaqueString:
+		return profile, encoded[j+len(normalizationSeparator):], nil
+	}
+	return "", "", ErrUnknownNormalization
+}
@@ -0,0 +1,87 @@
+package hashing
+
+import (
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		profile  string
+		password string
+		expected string
+		err      error
+	}{
+		{"", "cafe\u0301\x00", "cafe\u0301\x00", nil},
+		{NormalizationNone, "\xff", "\xff", nil},
+		{NormalizationNFC, "cafe\u0301", "caf\u00e9", nil},
+		{NormalizationNFC, "\ufb01le", "\ufb01le", nil},
+		{NormalizationNFKC, "\ufb01le", "file", nil},
+		{NormalizationOpaqueString, "pass\u00a0word", "pass word", nil},
+		{NormalizationNFC, "\xff", "", ErrInvalidPassword},
+		{NormalizationNFKC, "pass\x00word", "", ErrInvalidPassword},
+		{NormalizationOpaqueString, "pass\tword", "", ErrInvalidPassword},
+		{NormalizationOpaqueString, "", "", ErrInvalidPassword},
+		{"nfd", "password", "", ErrUnknownNormalization},
+	}
+	for _, tt := range tests {
+		out, err := Normalize(tt.profile, []byte(tt.password))
+		if err != tt.err {
+			t.Errorf("Normalize(%s, %q) error = %v, expected %v", tt.profile, tt.password, err, tt.err)
+			continue
+		}
+		if err == nil && string(out) != tt.expected {
+			t.Errorf("Normalize(%s, %q) = %q, expected %q", tt.profile, tt.password, out, tt.expected)
+		}
+	}
+}
+
+func TestNormalizationProfile(t *testing.T) {
+	for _, profile := range []string{NormalizationNone, NormalizationNFC, NormalizationNFKC, NormalizationOpaqueString} {
+		if p, err := NormalizationProfile(profile); err != nil || p != profile {
+			t.Errorf("NormalizationProfile(%s) = %s %v", profile, p, err)
+		}
+	}
+	if p, err := NormalizationProfile(""); err != nil || p != NormalizationNone {
+		t.Errorf("NormalizationProfile() = %s %v, expected none by default", p, err)
+	}
+	if _, err := NormalizationProfile("nfd"); err == nil {
+		t.Error("NormalizationProfile should reject unknown profiles")
+	}
+}
+
+func TestNormalizationRecord(t *testing.T) {
+	const encoded = "pbkdf2+sha512$$i=50000,l=64$c2FsdA$aGFzaA"
+
+	for _, profile := range []string{"", NormalizationNone, NormalizationNFC, NormalizationNFKC, NormalizationOpaqueString} {
+		recorded := RecordNormalization(profile, encoded)
+		if (profile == "" || profile == NormalizationNone) != (recorded == encoded) {
+			t.Errorf("RecordNormalization(%s) = %s, only applied profiles are recorded", profile, recorded)
+		}
+
+		p, e, err := SplitNormalization(recorded)
+		if err != nil {
+			t.Errorf("SplitNormalization(%s) error = %v", recorded, err)
+			continue
+		}
+		if expected, _ := NormalizationProfile(profile); p != expected || e != encoded {
+			t.Errorf("SplitNormalization(%s) = %s %s, expected %s %s", recorded, p, e, expected, encoded)
+		}
+	}
+
+	tests := []struct {
+		encoded string
+		err     error
+	}{
+		{"nfd:" + encoded, ErrUnknownNormalization},
+		{"none:" + encoded, ErrUnknownNormalization},
+		{":" + encoded, ErrUnknownNormalization},
+		{"nfc:", ErrMalformedHash},
+		{"$" + encoded, ErrMalformedHash},
+		{"", ErrMalformedHash},
+	}
+	for _, tt := range tests {
+		if _, _, err := SplitNormalization(tt.encoded); err != tt.err {
+			t.Errorf("SplitNormalization(%q) error = %v, expected %v", tt.encoded, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package hashing
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	// ErrUnknownStrategy is raised when no registered strategy matches the algorithm
+	ErrUnknownStrategy = errors.New("hashing: unknown strategy")
+	// ErrMalformedHash is raised when an encoded hash can't be parsed
+	ErrMalformedHash = errors.New("hashing: malformed encoded hash")
+)
+
+// Strategy defines a password hashing algorithm. Encoded hashes must start
+// with the strategy name followed by a '$' separator.
+type Strategy interface {
+	// Name returns the algorithm identifier used as encoded hash prefix
+	Name() string
+	// Hash returns the encoded hash of the given password
+	Hash(password []byte) (string, error)
+	// Verify checks the password against the encoded hash
+	Verify(encoded string, password []byte) (bool, error)
+	// Parameters returns the cost parameters of the encoded hash
+	Parameters(encoded string) (map[string]string, error)
+	// NeedsUpgrade returns true when the encoded hash parameters differ from
+	// the strategy current ones.
+	NeedsUpgrade(encoded string) bool
+}
+
+// MemoryCoster is implemented by memory hard strategies, it returns the memory
+// used by a single operation in bytes.
+type MemoryCoster interface {
+	MemoryCost() int64
+}
+
+// Registry holds the strategies available to a server
+type Registry struct {
+	mu         sync.RWMutex
+	strategies map[string]Strategy
+}
+
+// NewRegistry returns a registry initialized with the given strategies
+func NewRegistry(strategies ...Strategy) (*Registry, error) {
+	r := &Registry{
+		strategies: map[string]Strategy{},
+	}
+	for _, s := range strategies {
+		if err := r.Register(s); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Register adds a strategy to the registry, names must be unique
+func (r *Registry) Register(s Strategy) error {
+	name := s.Name()
+	if len(name) == 0 || strings.ContainsAny(name, "$"+normalizationSeparator) {
+		return fmt.Errorf("hashing: invalid strategy name '%s'", name)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.strategies[name]; ok {
+		return fmt.Errorf("hashing: strategy '%s' is already registered", name)
+	}
+	r.strategies[name] = s
+
+	return nil
+}
+
+// Lookup returns the strategy registered with the given name
+func (r *Registry) Lookup(name string) (Strategy, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	s, ok := r.strategies[name]
+	if !ok {
+		return nil, ErrUnknownStrategy
+	}
+	return s, nil
+}
+
+// Names returns the sorted list of registered strategy names
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.strategies))
+	for name := range r.strategies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Identify returns the strategy which produced the encoded hash, a recorded
+// normalization profile is skipped.
+func (r *Registry) Identify(encoded string) (Strategy, error) {
+	_, encoded, err := SplitNormalization(encoded)
+	if err != nil {
+		return nil, err
+	}
+	i := strings.Index(encoded, "$")
+	if i <= 0 {
+		return nil, ErrMalformedHash
+	}
+	return r.Lookup(encoded[:i])
+}
+
+// Verify checks the password against the encoded hash using the matching
+// strategy, after normalizing it with the recorded profile.
+func (r *Registry) Verify(encoded string, password []byte) (bool, error) {
+	s, err := r.Identify(encoded)
+	if err != nil {
+		return false, err
+	}
+	profile, encoded, err := SplitNormalization(encoded)
+	if err != nil {
+		return false, err
+	}
+	normalized, err := Normalize(profile, password)
+	if err != nil {
+		return false, err
+	}
+	return s.Verify(encoded, normalized)
+}
+
+// NeedsUpgrade returns true when the encoded hash was not produced by the
+// preferred strategy or with its current parameters.
+func (r *Registry) NeedsUpgrade(encoded, preferred string) bool {
+	s, err := r.Identify(encoded)
+	if err != nil {
+		return true
+	}
+	_, encoded, _ = SplitNormalization(encoded)
+	return s.Name() != preferred || s.NeedsUpgrade(encoded)
+}
@@ -0,0 +1,152 @@
+package hashing
+
+import (
+	"strings"
+	"testing"
+)
+
+// plainStrategy encodes passwords as is, it stands for embedder strategies
+type plainStrategy struct {
+	name string
+}
+
+func (s *plainStrategy) Name() string {
+	return s.name
+}
+
+func (s *plainStrategy) Hash(password []byte) (string, error) {
+	return s.name + "$v=1$" + string(password), nil
+}
+
+func (s *plainStrategy) Verify(encoded string, password []byte) (bool, error) {
+	parts := strings.SplitN(encoded, "$", 3)
+	if len(parts) != 3 || parts[0] != s.name {
+		return false, ErrMalformedHash
+	}
+	return parts[2] == string(password), nil
+}
+
+func (s *plainStrategy) Parameters(encoded string) (map[string]string, error) {
+	parts := strings.SplitN(encoded, "$", 3)
+	if len(parts) != 3 || parts[0] != s.name {
+		return nil, ErrMalformedHash
+	}
+	kv := strings.SplitN(parts[1], "=", 2)
+	if len(kv) != 2 {
+		return nil, ErrMalformedHash
+	}
+	return map[string]string{kv[0]: kv[1]}, nil
+}
+
+func (s *plainStrategy) NeedsUpgrade(encoded string) bool {
+	params, err := s.Parameters(encoded)
+	return err != nil || params["v"] != "1"
+}
+
+func TestRegister(t *testing.T) {
+	r, err := NewRegistry(&plainStrategy{name: "plain"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"", "plain$v1", "nfc:plain"} {
+		if err := r.Register(&plainStrategy{name: name}); err == nil {
+			t.Errorf("Register(%q) should reject the invalid name", name)
+		}
+	}
+	if err := r.Register(&plainStrategy{name: "plain"}); err == nil {
+		t.Error("Register should reject a name already registered")
+	}
+	if _, err := NewRegistry(&plainStrategy{name: "a"}, &plainStrategy{name: "a"}); err == nil {
+		t.Error("NewRegistry should reject duplicate strategies")
+	}
+
+	if err := r.Register(&plainStrategy{name: "other"}); err != nil {
+		t.Fatal(err)
+	}
+	if names := r.Names(); strings.Join(names, ",") != "other,plain" {
+		t.Errorf("Names() = %v, expected sorted names", names)
+	}
+	if _, err := r.Lookup("unknown"); err != ErrUnknownStrategy {
+		t.Errorf("Lookup() error = %v, expected ErrUnknownStrategy", err)
+	}
+}
+
+func TestIdentify(t *testing.T) {
+	r, err := NewRegistry(&plainStrategy{name: "plain"}, &plainStrategy{name: "plain2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		encoded string
+		name    string
+		err     error
+	}{
+		{"plain$v=1$secret", "plain", nil},
+		{"plain2$v=1$secret", "plain2", nil},
+		{"nfc:plain$v=1$secret", "plain", nil},
+		{"opaquestring:plain2$v=1$secret", "plain2", nil},
+		{"unknown$v=1$secret", "", ErrUnknownStrategy},
+		{"plain", "", ErrMalformedHash},
+		{"$v=1$secret", "", ErrMalformedHash},
+		{"", "", ErrMalformedHash},
+		{"rot13:plain$v=1$secret", "", ErrUnknownNormalization},
+	}
+	for _, tt := range tests {
+		s, err := r.Identify(tt.encoded)
+		if err != tt.err {
+			t.Errorf("Identify(%q) error = %v, expected %v", tt.encoded, err, tt.err)
+			continue
+		}
+		if err == nil && s.Name() != tt.name {
+			t.Errorf("Identify(%q) = %s, expected %s", tt.encoded, s.Name(), tt.name)
+		}
+	}
+}
+
+func TestRegistryVerify(t *testing.T) {
+	r, err := NewRegistry(&plainStrategy{name: "plain"}, &plainStrategy{name: "legacy"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Passwords are normalized with the recorded profile
+	composed, err := Normalize(NormalizationNFC, []byte("cafe\u0301"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, _ := r.strategies["plain"].Hash(composed)
+	encoded = RecordNormalization(NormalizationNFC, encoded)
+	for _, password := range []string{"caf\u00e9", "cafe\u0301"} {
+		if valid, err := r.Verify(encoded, []byte(password)); err != nil || !valid {
+			t.Errorf("Verify(%q) = %v %v, expected valid", password, valid, err)
+		}
+	}
+	if valid, err := r.Verify(encoded, []byte("cafe")); err != nil || valid {
+		t.Errorf("Verify() = %v %v, expected invalid", valid, err)
+	}
+
+	// Without profile, passwords are compared as received
+	encoded, _ = r.strategies["plain"].Hash(composed)
+	if valid, err := r.Verify(encoded, []byte("cafe\u0301")); err != nil || valid {
+		t.Errorf("Verify() = %v %v, expected invalid without profile", valid, err)
+	}
+
+	tests := []struct {
+		encoded string
+		upgrade bool
+	}{
+		{"plain$v=1$secret", false},
+		{"nfkc:plain$v=1$secret", false},
+		{"plain$v=0$secret", true},
+		{"legacy$v=1$secret", true},
+		{"unknown$v=1$secret", true},
+		{"malformed", true},
+	}
+	for _, tt := range tests {
+		if upgrade := r.NeedsUpgrade(tt.encoded, "plain"); upgrade != tt.upgrade {
+			t.Errorf("NeedsUpgrade(%q) = %v, expected %v", tt.encoded, upgrade, tt.upgrade)
+		}
+	}
+}
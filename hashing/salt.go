@@ -0,0 +1,121 @@
+package hashing
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+const (
+	// repetitionCutoff is the number of identical consecutive bytes rejected
+	// by the repetition count test. A full entropy source produces such a run
+	// at a given offset with probability 2^-48, so a salt of n bytes is
+	// rejected with probability about (n-6)·2^-48, below 2^-40 up to 256 bytes.
+	repetitionCutoff = 7
+	// recentWindow is the number of previous salts checked for duplicates
+	recentWindow = 64
+)
+
+// Salt source health tests
+const (
+	TestRead       = "read"
+	TestRepetition = "repetition"
+	TestDuplicate  = "duplicate"
+)
+
+// EntropyError is raised when the salt source fails or its output fails a
+// health test, no salt is returned in this case.
+type EntropyError struct {
+	Test string
+	Err  error
+}
+
+func (e *EntropyError) Error() string {
+	return fmt.Sprintf("hashing: salt source failed %s test, %v", e.Test, e.Err)
+}
+
+// SaltSource generates random salts and checks them continuously
+type SaltSource struct {
+	reader   io.Reader
+	length   int
+	observer func(err error)
+
+	mu     sync.Mutex
+	recent []string
+	seen   map[string]bool
+	next   int
+}
+
+// NewSaltSource returns a salt source of the given length reading the given
+// entropy source, usually crypto/rand.Reader.
+func NewSaltSource(reader io.Reader, length int) *SaltSource {
+	return &SaltSource{
+		reader: reader,
+		length: length,
+		recent: make([]string, recentWindow),
+		seen:   map[string]bool{},
+	}
+}
+
+// Observe registers a function invoked with the result of each generation,
+// err is nil on success. It must be called before the source is used.
+func (s *SaltSource) Observe(fn func(err error)) {
+	s.observer = fn
+}
+
+// Salt returns a new salt
+func (s *SaltSource) Salt() ([]byte, error) {
+	salt, err := s.generate()
+	if s.observer != nil {
+		s.observer(err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return salt, nil
+}
+
+func (s *SaltSource) generate() ([]byte, error) {
+	salt := make([]byte, s.length)
+	if _, err := io.ReadFull(s.reader, salt); err != nil {
+		return nil, &EntropyError{Test: TestRead, Err: err}
+	}
+
+	if err := repetitionCount(salt); err != nil {
+		return nil, &EntropyError{Test: TestRepetition, Err: err}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := string(salt)
+	if s.seen[key] {
+		return nil, &EntropyError{Test: TestDuplicate, Err: fmt.Errorf("salt repeated within the last %d salts", recentWindow)}
+	}
+
+	// Slide the recent window
+	if previous := s.recent[s.next]; len(previous) > 0 {
+		delete(s.seen, previous)
+	}
+	s.recent[s.next] = key
+	s.seen[key] = true
+	s.next = (s.next + 1) % recentWindow
+
+	return salt, nil
+}
+
+// repetitionCount rejects runs of identical bytes
+func repetitionCount(salt []byte) error {
+	run := 1
+	for i := 1; i < len(salt); i++ {
+		if salt[i] != salt[i-1] {
+			run = 1
+			continue
+		}
+		run++
+		if run >= repetitionCutoff {
+			return fmt.Errorf("byte 0x%02x repeated %d times", salt[i], run)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package hashing
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+// sequenceReader returns the given salts in turn
+type sequenceReader struct {
+	salts [][]byte
+}
+
+func (r *sequenceReader) Read(p []byte) (int, error) {
+	if len(r.salts) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, r.salts[0])
+	r.salts = r.salts[1:]
+	return n, nil
+}
+
+// counterSalt returns a salt without repetition derived from i
+func counterSalt(i int) []byte {
+	salt := []byte("0123456789abcdef")
+	salt[0], salt[1] = byte(i), byte(i>>8)
+	return salt
+}
+
+func entropyTest(err error) string {
+	if e, ok := err.(*EntropyError); ok {
+		return e.Test
+	}
+	return ""
+}
+
+func TestSaltRepetition(t *testing.T) {
+	tests := []struct {
+		name string
+		salt []byte
+		err  string
+	}{
+		{"distinct", []byte("0123456789abcdef"), ""},
+		{"run below cutoff", []byte("01234xxxxxxabcde"), ""},
+		{"run at cutoff", []byte("01234xxxxxxxbcde"), TestRepetition},
+		{"leading run", []byte("\x00\x00\x00\x00\x00\x00\x006789abcdef"), TestRepetition},
+		{"trailing run", []byte("012345678fffffff"), TestRepetition},
+	}
+	for _, tt := range tests {
+		s := NewSaltSource(&sequenceReader{salts: [][]byte{tt.salt}}, len(tt.salt))
+		salt, err := s.Salt()
+		if test := entropyTest(err); test != tt.err {
+			t.Errorf("%s: Salt() error = %v, expected %q test failure", tt.name, err, tt.err)
+			continue
+		}
+		if err == nil && !bytes.Equal(salt, tt.salt) {
+			t.Errorf("%s: Salt() = %q, expected %q", tt.name, salt, tt.salt)
+		}
+		if err != nil && salt != nil {
+			t.Errorf("%s: Salt() should not return a salt on failure", tt.name)
+		}
+	}
+}
+
+func TestSaltDuplicate(t *testing.T) {
+	var salts [][]byte
+	for i := 0; i < recentWindow; i++ {
+		salts = append(salts, counterSalt(i))
+	}
+	// Repeated within the window, then once it left the window
+	salts = append(salts, counterSalt(1), counterSalt(recentWindow), counterSalt(0))
+
+	var results []error
+	s := NewSaltSource(&sequenceReader{salts: salts}, 16)
+	s.Observe(func(err error) {
+		results = append(results, err)
+	})
+
+	for i := 0; i < recentWindow; i++ {
+		if _, err := s.Salt(); err != nil {
+			t.Fatalf("Salt() = %v, expected distinct salts to be accepted", err)
+		}
+	}
+	if _, err := s.Salt(); entropyTest(err) != TestDuplicate {
+		t.Errorf("Salt() error = %v, expected duplicate test failure", err)
+	}
+	if _, err := s.Salt(); err != nil {
+		t.Errorf("Salt() = %v, expected a new salt", err)
+	}
+	if _, err := s.Salt(); err != nil {
+		t.Errorf("Salt() = %v, expected a salt out of the recent window", err)
+	}
+
+	if len(results) != recentWindow+3 || entropyTest(results[recentWindow]) != TestDuplicate {
+		t.Errorf("observer should receive every result, got %d", len(results))
+	}
+}
+
+func TestSaltRead(t *testing.T) {
+	failure := errors.New("device unavailable")
+	sources := map[string]io.Reader{
+		"failing": io.MultiReader(bytes.NewReader([]byte("0123")), &failingReader{err: failure}),
+		"short":   bytes.NewReader([]byte("0123456789")),
+	}
+	for name, source := range sources {
+		s := NewSaltSource(source, 16)
+		if salt, err := s.Salt(); entropyTest(err) != TestRead || salt != nil {
+			t.Errorf("%s: Salt() = %q %v, expected read test failure", name, salt, err)
+		}
+	}
+}
+
+type failingReader struct {
+	err error
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	return 0, r.err
+}
@@ -0,0 +1,149 @@
+package hashing
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+
+	"go.zenithar.org/butcher/hasher"
+)
+
+const (
+	// RandomSource is the self-test result name of the salt entropy check
+	RandomSource = "crypto/rand"
+)
+
+var (
+	katPassword = []byte("password")
+	katWrong    = []byte("passw0rd")
+	katSalt     = []byte("0123456789abcdef0123456789abcdef")
+)
+
+// knownAnswers defines the expected encoding of katPassword with katSalt.
+// bcrypt generates its own salt, so its vectors are only checked by Verify.
+var knownAnswers = map[string]string{
+	hasher.Argon2i:          "argon2i$v=19$m=4096,t=3,p=1$MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY$rXk6jiFflFEuOAY99u94ySUNqukeWQn85XqzmKhIcYU",
+	hasher.Pbkdf2Blake2b512: "pbkdf2+blake2b-512$$i=50000,l=64$MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY$4NFJ7RmcpFZG6+08cmu46bmJNyejBloYlwSlkOoeFOE9EVRhzXIAlzH2mk8BHTr0S3QZ7g3+ALSeBqXaAGvkPQ",
+	hasher.Pbkdf2Sha512:     "pbkdf2+sha512$$i=50000,l=64$MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY$DC5rY0Xr7+wqmZPhJj/+McKWXyp+ffs1g4Gelrtpiz16glaazWPXq75+vVlWYVKVCB6Ru1NPQ6WzmT4MWQ3LRQ",
+	hasher.Pbkdf2Keccak512:  "pbkdf2+sha3-512$$i=50000,l=64$MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY$3Mu+pXL+6CJ6aHHTo42CHYjSZvvXwW/54BeffqYoW//tMEdUL5aSqF5kowiXPeixQttLkBIxPJhFOTtDYe8pSQ",
+	hasher.BcryptBlake2b512: "bcrypt+blake2b-512$$c=12$MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY$JDJhJDEyJFkweFo3dVBIZHJnTzdzRm5ISGRoMS5hM0xueXNtYzZPTFBwYzFETmNUVlZYWXRKVXBoQTdT",
+	hasher.BcryptSha512:     "bcrypt+sha512$$c=12$MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY$JDJhJDEyJDRsYzYvQmY5YlJxTW9WeWFLUHE2V2VuLk9PcW1pQ2NEQVQ1Vk41Z1cxS1JWcW93RDNrcnR5",
+}
+
+// TestResult describes the self-test outcome of a strategy
+type TestResult struct {
+	Name  string `json:"name"`
+	Error string `json:"error,omitempty"`
+}
+
+// SelfTest checks the salt entropy source and every registered strategy.
+// Builtin strategies are checked against known answers, all strategies must
+// verify their own output and reject a wrong password. An error is returned
+// when any check fails.
+func SelfTest(r *Registry) ([]TestResult, error) {
+	results := []TestResult{
+		result(RandomSource, checkRandom(rand.Reader)),
+	}
+
+	for _, name := range r.Names() {
+		s, err := r.Lookup(name)
+		if err == nil {
+			err = checkStrategy(s)
+		}
+		results = append(results, result(name, err))
+	}
+
+	failed := 0
+	for _, res := range results {
+		if len(res.Error) > 0 {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return results, fmt.Errorf("hashing: %d self-test(s) failed", failed)
+	}
+
+	return results, nil
+}
+
+func result(name string, err error) TestResult {
+	res := TestResult{Name: name}
+	if err != nil {
+		res.Error = err.Error()
+	}
+	return res
+}
+
+// checkRandom ensures the entropy source returns distinct non zero output
+func checkRandom(source io.Reader) error {
+	a := make([]byte, 32)
+	b := make([]byte, 32)
+	if _, err := io.ReadFull(source, a); err != nil {
+		return fmt.Errorf("unable to read random source, %v", err)
+	}
+	if _, err := io.ReadFull(source, b); err != nil {
+		return fmt.Errorf("unable to read random source, %v", err)
+	}
+	if bytes.Equal(a, make([]byte, 32)) || bytes.Equal(a, b) {
+		return errors.New("random source output is not random")
+	}
+	return nil
+}
+
+func checkStrategy(s Strategy) error {
+	// Known answers of vendored implementations
+	if _, builtin := s.(*builtinStrategy); builtin {
+		expected := knownAnswers[s.Name()]
+		if err := checkKnownAnswer(s, expected); err != nil {
+			return err
+		}
+	}
+
+	// Round trip
+	encoded, err := s.Hash(katPassword)
+	if err != nil {
+		return fmt.Errorf("unable to hash, %v", err)
+	}
+	return checkVerify(s, encoded)
+}
+
+func checkKnownAnswer(s Strategy, expected string) error {
+	if _, ok := bcryptDigests[s.Name()]; !ok {
+		fixed, err := newBuiltin(s.Name(), func() ([]byte, error) {
+			return katSalt, nil
+		})
+		if err != nil {
+			return err
+		}
+		encoded, err := fixed.Hash(katPassword)
+		if err != nil {
+			return fmt.Errorf("unable to hash known answer, %v", err)
+		}
+		if encoded != expected {
+			return errors.New("known answer mismatch")
+		}
+	}
+	return checkVerify(s, expected)
+}
+
+func checkVerify(s Strategy, encoded string) error {
+	valid, err := s.Verify(encoded, katPassword)
+	if err != nil {
+		return fmt.Errorf("unable to verify, %v", err)
+	}
+	if !valid {
+		return errors.New("valid password is rejected")
+	}
+
+	valid, err = s.Verify(encoded, katWrong)
+	if err != nil {
+		return fmt.Errorf("unable to verify, %v", err)
+	}
+	if valid {
+		return errors.New("wrong password is accepted")
+	}
+
+	return nil
+}
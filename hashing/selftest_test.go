@@ -0,0 +1,87 @@
+package hashing
+
+import (
+	"bytes"
+	"crypto/rand"
+	"strings"
+	"testing"
+
+	"go.zenithar.org/butcher/hasher"
+)
+
+// acceptingStrategy verifies any password
+type acceptingStrategy struct {
+	plainStrategy
+}
+
+func (s *acceptingStrategy) Verify(encoded string, password []byte) (bool, error) {
+	return true, nil
+}
+
+func TestSelfTest(t *testing.T) {
+	r, err := DefaultRegistry(NewSaltSource(rand.Reader, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Register(&plainStrategy{name: "plain"}); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := SelfTest(r)
+	if err != nil {
+		t.Fatalf("SelfTest() = %v %v", results, err)
+	}
+	if len(results) != len(r.Names())+1 || results[0].Name != RandomSource {
+		t.Errorf("SelfTest() = %v, expected the random source and every strategy", results)
+	}
+
+	// Failures are reported per strategy
+	r, err = NewRegistry(&plainStrategy{name: "plain"}, &acceptingStrategy{plainStrategy{name: "accepting"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err = SelfTest(r)
+	if err == nil {
+		t.Fatal("SelfTest should fail when a strategy accepts a wrong password")
+	}
+	for _, res := range results {
+		if failed := len(res.Error) > 0; failed != (res.Name == "accepting") {
+			t.Errorf("SelfTest() result %+v is unexpected", res)
+		}
+	}
+}
+
+func TestKnownAnswer(t *testing.T) {
+	s, err := newBuiltin(hasher.Pbkdf2Sha512, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := checkKnownAnswer(s, knownAnswers[hasher.Pbkdf2Sha512]); err != nil {
+		t.Errorf("checkKnownAnswer() = %v", err)
+	}
+	if err := checkKnownAnswer(s, knownAnswers[hasher.Pbkdf2Keccak512]); err == nil {
+		t.Error("checkKnownAnswer should detect a mismatching answer")
+	}
+}
+
+func TestCheckRandom(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		valid  bool
+	}{
+		{"zero", strings.Repeat("\x00", 64), false},
+		{"repeated", strings.Repeat("0123456789abcdef", 4), false},
+		{"short", "0123456789abcdef", false},
+	}
+	for _, tt := range tests {
+		if err := checkRandom(strings.NewReader(tt.source)); (err == nil) != tt.valid {
+			t.Errorf("%s: checkRandom() = %v, expected valid %v", tt.name, err, tt.valid)
+		}
+	}
+
+	distinct := bytes.NewReader(append(bytes.Repeat([]byte{1}, 32), bytes.Repeat([]byte{2}, 32)...))
+	if err := checkRandom(distinct); err != nil {
+		t.Errorf("checkRandom() = %v, expected distinct outputs to pass", err)
+	}
+}
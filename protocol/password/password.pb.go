@@ -13,6 +13,15 @@ It has these top-level messages:
 	PasswordReq
 	EncodedPasswordRes
 	PasswordValidationRes
+	HistoryReq
+	HistoryRes
+	ChangeReq
+	ChangeRes
+	CredentialReq
+	SubjectReq
+	SetPasswordRes
+	VerifyPasswordRes
+	DeletePasswordRes
 	PongRes
 */
 package password
@@ -54,6 +63,17 @@ type PasswordClient interface {
 	Encode(ctx context.Context, in *PasswordReq, opts ...grpc.CallOption) (*EncodedPasswordRes, error)
 	// Validate a password hash encoded by Butcher
 	Validate(ctx context.Context, in *PasswordReq, opts ...grpc.CallOption) (*PasswordValidationRes, error)
+	// Check a password against previous hashes, to prevent its reuse
+	CheckHistory(ctx context.Context, in *HistoryReq, opts ...grpc.CallOption) (*HistoryRes, error)
+	// Check a password change, new passwords too similar to the old one are
+	// rejected
+	CheckChange(ctx context.Context, in *ChangeReq, opts ...grpc.CallOption) (*ChangeRes, error)
+	// Store the password of a subject, in managed credential store mode
+	SetPassword(ctx context.Context, in *CredentialReq, opts ...grpc.CallOption) (*SetPasswordRes, error)
+	// Verify the password of a subject, in managed credential store mode
+	VerifyPassword(ctx context.Context, in *CredentialReq, opts ...grpc.CallOption) (*VerifyPasswordRes, error)
+	// Delete the password of a subject, in managed credential store mode
+	DeletePassword(ctx context.Context, in *SubjectReq, opts ...grpc.CallOption) (*DeletePasswordRes, error)
 	// Ping the password server. Example for empty query
 	Ping(ctx context.Context, in *google_protobuf2.Empty, opts ...grpc.CallOption) (*PongRes, error)
 }
@@ -84,6 +104,51 @@ func (c *passwordClient) Validate(ctx context.Context, in *PasswordReq, opts ...
 	return out, nil
 }
 
+func (c *passwordClient) CheckHistory(ctx context.Context, in *HistoryReq, opts ...grpc.CallOption) (*HistoryRes, error) {
+	out := new(HistoryRes)
+	err := grpc.Invoke(ctx, "/password.Password/CheckHistory", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *passwordClient) CheckChange(ctx context.Context, in *ChangeReq, opts ...grpc.CallOption) (*ChangeRes, error) {
+	out := new(ChangeRes)
+	err := grpc.Invoke(ctx, "/password.Password/CheckChange", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *passwordClient) SetPassword(ctx context.Context, in *CredentialReq, opts ...grpc.CallOption) (*SetPasswordRes, error) {
+	out := new(SetPasswordRes)
+	err := grpc.Invoke(ctx, "/password.Password/SetPassword", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *passwordClient) VerifyPassword(ctx context.Context, in *CredentialReq, opts ...grpc.CallOption) (*VerifyPasswordRes, error) {
+	out := new(VerifyPasswordRes)
+	err := grpc.Invoke(ctx, "/password.Password/VerifyPassword", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *passwordClient) DeletePassword(ctx context.Context, in *SubjectReq, opts ...grpc.CallOption) (*DeletePasswordRes, error) {
+	out := new(DeletePasswordRes)
+	err := grpc.Invoke(ctx, "/password.Password/DeletePassword", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *passwordClient) Ping(ctx context.Context, in *google_protobuf2.Empty, opts ...grpc.CallOption) (*PongRes, error) {
 	out := new(PongRes)
 	err := grpc.Invoke(ctx, "/password.Password/Ping", in, out, c.cc, opts...)
@@ -100,6 +165,17 @@ type PasswordServer interface {
 	Encode(context.Context, *PasswordReq) (*EncodedPasswordRes, error)
 	// Validate a password hash encoded by Butcher
 	Validate(context.Context, *PasswordReq) (*PasswordValidationRes, error)
+	// Check a password against previous hashes, to prevent its reuse
+	CheckHistory(context.Context, *HistoryReq) (*HistoryRes, error)
+	// Check a password change, new passwords too similar to the old one are
+	// rejected
+	CheckChange(context.Context, *ChangeReq) (*ChangeRes, error)
+	// Store the password of a subject, in managed credential store mode
+	SetPassword(context.Context, *CredentialReq) (*SetPasswordRes, error)
+	// Verify the password of a subject, in managed credential store mode
+	VerifyPassword(context.Context, *CredentialReq) (*VerifyPasswordRes, error)
+	// Delete the password of a subject, in managed credential store mode
+	DeletePassword(context.Context, *SubjectReq) (*DeletePasswordRes, error)
 	// Ping the password server. Example for empty query
 	Ping(context.Context, *google_protobuf2.Empty) (*PongRes, error)
 }
@@ -144,6 +220,96 @@ func _Password_Validate_Handler(srv interface{}, ctx context.Context, dec func(i
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Password_CheckHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HistoryReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PasswordServer).CheckHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/password.Password/CheckHistory",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PasswordServer).CheckHistory(ctx, req.(*HistoryReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Password_CheckChange_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ChangeReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PasswordServer).CheckChange(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/password.Password/CheckChange",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PasswordServer).CheckChange(ctx, req.(*ChangeReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Password_SetPassword_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CredentialReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PasswordServer).SetPassword(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/password.Password/SetPassword",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PasswordServer).SetPassword(ctx, req.(*CredentialReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Password_VerifyPassword_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CredentialReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PasswordServer).VerifyPassword(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/password.Password/VerifyPassword",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PasswordServer).VerifyPassword(ctx, req.(*CredentialReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Password_DeletePassword_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SubjectReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PasswordServer).DeletePassword(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/password.Password/DeletePassword",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PasswordServer).DeletePassword(ctx, req.(*SubjectReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Password_Ping_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(google_protobuf2.Empty)
 	if err := dec(in); err != nil {
@@ -174,6 +340,26 @@ var _Password_serviceDesc = grpc.ServiceDesc{
 			MethodName: "Validate",
 			Handler:    _Password_Validate_Handler,
 		},
+		{
+			MethodName: "CheckHistory",
+			Handler:    _Password_CheckHistory_Handler,
+		},
+		{
+			MethodName: "CheckChange",
+			Handler:    _Password_CheckChange_Handler,
+		},
+		{
+			MethodName: "SetPassword",
+			Handler:    _Password_SetPassword_Handler,
+		},
+		{
+			MethodName: "VerifyPassword",
+			Handler:    _Password_VerifyPassword_Handler,
+		},
+		{
+			MethodName: "DeletePassword",
+			Handler:    _Password_DeletePassword_Handler,
+		},
 		{
 			MethodName: "Ping",
 			Handler:    _Password_Ping_Handler,
@@ -186,20 +372,31 @@ var _Password_serviceDesc = grpc.ServiceDesc{
 func init() { proto.RegisterFile("password.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 226 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0xe2, 0x2b, 0x48, 0x2c, 0x2e,
-	0x2e, 0xcf, 0x2f, 0x4a, 0xd1, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0xe2, 0x80, 0xf1, 0xa5, 0xf8,
-	0xc0, 0x02, 0xc9, 0xf9, 0x39, 0x10, 0x19, 0x29, 0x99, 0xf4, 0xfc, 0xfc, 0xf4, 0x9c, 0x54, 0xfd,
-	0xc4, 0x82, 0x4c, 0xfd, 0xc4, 0xbc, 0xbc, 0xfc, 0x92, 0xc4, 0x92, 0xcc, 0xfc, 0xbc, 0x62, 0xa8,
-	0xac, 0x34, 0x54, 0x16, 0xcc, 0x4b, 0x2a, 0x4d, 0xd3, 0x4f, 0xcd, 0x2d, 0x28, 0xa9, 0x84, 0x48,
-	0x1a, 0xb5, 0x30, 0x71, 0x71, 0x04, 0x40, 0xcd, 0x15, 0x0a, 0xe3, 0x62, 0x73, 0xcd, 0x4b, 0xce,
-	0x4f, 0x49, 0x15, 0x12, 0xd5, 0x83, 0x5b, 0x0e, 0x93, 0x0d, 0x4a, 0x2d, 0x94, 0x92, 0x41, 0x08,
-	0x43, 0x14, 0xa6, 0x20, 0x64, 0x8b, 0x95, 0xc4, 0x9b, 0x2e, 0x3f, 0x99, 0xcc, 0x24, 0xa8, 0xc4,
-	0xa3, 0x5f, 0x66, 0xa8, 0x0f, 0x53, 0x68, 0xc5, 0xa8, 0x25, 0x14, 0xcd, 0xc5, 0x11, 0x96, 0x98,
-	0x93, 0x99, 0x92, 0x58, 0x82, 0xd3, 0x64, 0x79, 0x4c, 0x61, 0xa8, 0x96, 0xcc, 0xfc, 0x3c, 0x0c,
-	0xc3, 0xcb, 0xa0, 0xa6, 0x81, 0x0c, 0x77, 0xe6, 0x62, 0x09, 0xc8, 0xcc, 0x4b, 0x17, 0x12, 0xd3,
-	0x83, 0xf8, 0x53, 0x0f, 0xe6, 0x4f, 0x3d, 0x57, 0x90, 0x3f, 0xa5, 0x04, 0x91, 0x4c, 0xce, 0xcf,
-	0x4b, 0x07, 0x99, 0x25, 0x00, 0x36, 0x8b, 0x4b, 0x88, 0x03, 0xec, 0xd0, 0xcc, 0xbc, 0xf4, 0x24,
-	0x36, 0xb0, 0x26, 0x63, 0x40, 0x00, 0x00, 0x00, 0xff, 0xff, 0x82, 0x50, 0x85, 0x13, 0x74, 0x01,
-	0x00, 0x00,
+	// 403 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x84, 0x92, 0x4f, 0x4f, 0xe2, 0x40,
+	0x18, 0xc6, 0xc3, 0x66, 0x97, 0x34, 0x03, 0xe9, 0xc2, 0xec, 0x02, 0xbb, 0x85, 0xf8, 0xa7, 0xd1,
+	0x4b, 0x4d, 0xda, 0xa8, 0x37, 0xae, 0x48, 0xe2, 0xc9, 0x10, 0x48, 0xb8, 0x78, 0x1a, 0xda, 0x69,
+	0x19, 0xad, 0x33, 0xb5, 0x33, 0x60, 0x88, 0xf1, 0xe2, 0x57, 0xf0, 0xa3, 0xf9, 0x15, 0xbc, 0xfa,
+	0x1d, 0xcc, 0x4c, 0xff, 0x22, 0xa0, 0xc7, 0x79, 0x9e, 0xf7, 0xf9, 0x3d, 0xed, 0x9b, 0x17, 0xe8,
+	0x11, 0xe2, 0xfc, 0x81, 0xc5, 0x9e, 0x1d, 0xc5, 0x4c, 0x30, 0xa8, 0x65, 0x6f, 0x43, 0x57, 0x82,
+	0xcb, 0xc2, 0xc4, 0x31, 0x7a, 0x01, 0x63, 0x41, 0x88, 0x1d, 0x14, 0x11, 0x07, 0x51, 0xca, 0x04,
+	0x12, 0x84, 0x51, 0x9e, 0xba, 0xdd, 0xd4, 0x55, 0xaf, 0xd9, 0xc2, 0x77, 0xf0, 0x5d, 0x24, 0x56,
+	0x89, 0x79, 0xf6, 0xfe, 0x0b, 0x68, 0xa3, 0x94, 0x0b, 0xa7, 0xa0, 0x3a, 0xa4, 0x2e, 0xf3, 0x30,
+	0x6c, 0xd9, 0x79, 0x79, 0xe6, 0x8e, 0xf1, 0xbd, 0xd1, 0x2b, 0xe4, 0x64, 0xd0, 0x2b, 0x5c, 0x6e,
+	0x76, 0x9e, 0x5f, 0xdf, 0x5e, 0x7e, 0x34, 0xcd, 0xba, 0xb3, 0x3c, 0x75, 0xb2, 0xc1, 0x7e, 0xc5,
+	0x82, 0xd7, 0x40, 0x9b, 0xa2, 0x90, 0x78, 0x48, 0xec, 0x24, 0xef, 0x6f, 0xca, 0x69, 0x84, 0x30,
+	0xba, 0x01, 0x5f, 0xa6, 0x34, 0x09, 0x1f, 0x83, 0xfa, 0x60, 0x8e, 0xdd, 0xdb, 0x4b, 0xc2, 0x05,
+	0x8b, 0x57, 0xf0, 0x6f, 0x41, 0x4a, 0x25, 0xc9, 0xdf, 0xa6, 0x72, 0xb3, 0xad, 0xa0, 0x8d, 0x7e,
+	0xc5, 0x32, 0x6b, 0x92, 0x3b, 0x4f, 0x19, 0x57, 0xa0, 0xa6, 0x98, 0x83, 0x39, 0xa2, 0x01, 0x86,
+	0x7f, 0x8a, 0x70, 0xa2, 0x48, 0xe2, 0x16, 0x91, 0x9b, 0x2d, 0x05, 0xfc, 0x6d, 0x02, 0x49, 0x73,
+	0x95, 0x2c, 0xbf, 0xd1, 0x07, 0xb5, 0x09, 0x16, 0xf9, 0x9e, 0x3b, 0xa5, 0x68, 0x8c, 0x3d, 0x4c,
+	0x05, 0x41, 0xa1, 0x64, 0xfe, 0x2b, 0x8c, 0xd2, 0xbc, 0x04, 0x1f, 0x29, 0xf0, 0x5e, 0xbf, 0x62,
+	0x19, 0xff, 0x15, 0x3b, 0xcf, 0x71, 0xe7, 0x91, 0x2f, 0x66, 0x37, 0xd8, 0x15, 0x4f, 0x90, 0x03,
+	0x7d, 0x8a, 0x63, 0xe2, 0xaf, 0xbe, 0xaf, 0xea, 0x16, 0xc6, 0x7a, 0x44, 0xb6, 0x9d, 0xa8, 0xb6,
+	0x63, 0xb9, 0x97, 0x83, 0x9d, 0x6d, 0xce, 0x52, 0xe5, 0xa0, 0x0f, 0xf4, 0x0b, 0x1c, 0x62, 0x81,
+	0xf3, 0xd2, 0xd2, 0xb2, 0x27, 0xc9, 0xf0, 0xa7, 0xc6, 0xf5, 0x79, 0xd9, 0x78, 0xa8, 0x1a, 0xbb,
+	0xd6, 0x17, 0x3f, 0x37, 0x00, 0x3f, 0x47, 0x84, 0x06, 0xb0, 0x6d, 0x27, 0x07, 0x6d, 0x67, 0x07,
+	0x6d, 0x0f, 0xe5, 0x41, 0x1b, 0xcd, 0xd2, 0x09, 0x31, 0x1a, 0x48, 0x6a, 0x43, 0x51, 0x01, 0xd4,
+	0xd4, 0x45, 0x12, 0x1a, 0xcc, 0xaa, 0x2a, 0x74, 0xfe, 0x31, 0x00, 0x81, 0x79, 0xd3, 0x92, 0x5d,
+	0x03, 0x00, 0x00,
 }
@@ -40,6 +40,10 @@ func (m *Error) GetMessage() string {
 type PasswordReq struct {
 	Password string `protobuf:"bytes,1,opt,name=password" json:"password,omitempty"`
 	Hash     string `protobuf:"bytes,2,opt,name=hash" json:"hash,omitempty"`
+	// Opaque account identifier, only used for audit records
+	Subject string `protobuf:"bytes,3,opt,name=subject" json:"subject,omitempty"`
+	// Previous hashes, Encode rejects a password matching one of them
+	History []string `protobuf:"bytes,4,rep,name=history" json:"history,omitempty"`
 }
 
 func (m *PasswordReq) Reset()                    { *m = PasswordReq{} }
@@ -61,6 +65,20 @@ func (m *PasswordReq) GetHash() string {
 	return ""
 }
 
+func (m *PasswordReq) GetSubject() string {
+	if m != nil {
+		return m.Subject
+	}
+	return ""
+}
+
+func (m *PasswordReq) GetHistory() []string {
+	if m != nil {
+		return m.History
+	}
+	return nil
+}
+
 type EncodedPasswordRes struct {
 	Error *Error `protobuf:"bytes,1,opt,name=error" json:"error,omitempty"`
 	Hash  string `protobuf:"bytes,2,opt,name=hash" json:"hash,omitempty"`
@@ -88,6 +106,8 @@ func (m *EncodedPasswordRes) GetHash() string {
 type PasswordValidationRes struct {
 	Error *Error `protobuf:"bytes,1,opt,name=error" json:"error,omitempty"`
 	Valid bool   `protobuf:"varint,2,opt,name=valid" json:"valid,omitempty"`
+	// Hash should be replaced by a new Encode result once validated
+	NeedsRehash bool `protobuf:"varint,3,opt,name=needs_rehash,json=needsRehash" json:"needs_rehash,omitempty"`
 }
 
 func (m *PasswordValidationRes) Reset()                    { *m = PasswordValidationRes{} }
@@ -109,6 +129,290 @@ func (m *PasswordValidationRes) GetValid() bool {
 	return false
 }
 
+func (m *PasswordValidationRes) GetNeedsRehash() bool {
+	if m != nil {
+		return m.NeedsRehash
+	}
+	return false
+}
+
+type HistoryReq struct {
+	Password string `protobuf:"bytes,1,opt,name=password" json:"password,omitempty"`
+	// Previous hashes, in any supported format
+	Hashes []string `protobuf:"bytes,2,rep,name=hashes" json:"hashes,omitempty"`
+	// Opaque account identifier, only used for audit records
+	Subject string `protobuf:"bytes,3,opt,name=subject" json:"subject,omitempty"`
+}
+
+func (m *HistoryReq) Reset()                    { *m = HistoryReq{} }
+func (m *HistoryReq) String() string            { return proto.CompactTextString(m) }
+func (*HistoryReq) ProtoMessage()               {}
+func (*HistoryReq) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{4} }
+
+func (m *HistoryReq) GetPassword() string {
+	if m != nil {
+		return m.Password
+	}
+	return ""
+}
+
+func (m *HistoryReq) GetHashes() []string {
+	if m != nil {
+		return m.Hashes
+	}
+	return nil
+}
+
+func (m *HistoryReq) GetSubject() string {
+	if m != nil {
+		return m.Subject
+	}
+	return ""
+}
+
+type HistoryRes struct {
+	Error   *Error `protobuf:"bytes,1,opt,name=error" json:"error,omitempty"`
+	Matched bool   `protobuf:"varint,2,opt,name=matched" json:"matched,omitempty"`
+	// Index of the first matching hash, -1 when none matched
+	Index int32 `protobuf:"varint,3,opt,name=index" json:"index,omitempty"`
+}
+
+func (m *HistoryRes) Reset()                    { *m = HistoryRes{} }
+func (m *HistoryRes) String() string            { return proto.CompactTextString(m) }
+func (*HistoryRes) ProtoMessage()               {}
+func (*HistoryRes) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{5} }
+
+func (m *HistoryRes) GetError() *Error {
+	if m != nil {
+		return m.Error
+	}
+	return nil
+}
+
+func (m *HistoryRes) GetMatched() bool {
+	if m != nil {
+		return m.Matched
+	}
+	return false
+}
+
+func (m *HistoryRes) GetIndex() int32 {
+	if m != nil {
+		return m.Index
+	}
+	return 0
+}
+
+type ChangeReq struct {
+	// Current password, verified against its hash
+	OldPassword string `protobuf:"bytes,1,opt,name=old_password,json=oldPassword" json:"old_password,omitempty"`
+	Hash        string `protobuf:"bytes,2,opt,name=hash" json:"hash,omitempty"`
+	NewPassword string `protobuf:"bytes,3,opt,name=new_password,json=newPassword" json:"new_password,omitempty"`
+	// Opaque account identifier, only used for audit records
+	Subject string `protobuf:"bytes,4,opt,name=subject" json:"subject,omitempty"`
+}
+
+func (m *ChangeReq) Reset()                    { *m = ChangeReq{} }
+func (m *ChangeReq) String() string            { return proto.CompactTextString(m) }
+func (*ChangeReq) ProtoMessage()               {}
+func (*ChangeReq) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{6} }
+
+func (m *ChangeReq) GetOldPassword() string {
+	if m != nil {
+		return m.OldPassword
+	}
+	return ""
+}
+
+func (m *ChangeReq) GetHash() string {
+	if m != nil {
+		return m.Hash
+	}
+	return ""
+}
+
+func (m *ChangeReq) GetNewPassword() string {
+	if m != nil {
+		return m.NewPassword
+	}
+	return ""
+}
+
+func (m *ChangeReq) GetSubject() string {
+	if m != nil {
+		return m.Subject
+	}
+	return ""
+}
+
+type ChangeRes struct {
+	Error *Error `protobuf:"bytes,1,opt,name=error" json:"error,omitempty"`
+	// Old password matches the hash, similarity is only checked when valid
+	Valid bool `protobuf:"varint,2,opt,name=valid" json:"valid,omitempty"`
+	// New password is not too similar to the old one
+	Accepted bool `protobuf:"varint,3,opt,name=accepted" json:"accepted,omitempty"`
+	// Normalized edit distance, from 0 (same) to 1 (nothing in common)
+	Distance float64 `protobuf:"fixed64,4,opt,name=distance" json:"distance,omitempty"`
+	// Transformation deriving the new password from the old one (case,
+	// increment, append, prepend), empty when none is detected
+	Transformation string `protobuf:"bytes,5,opt,name=transformation" json:"transformation,omitempty"`
+}
+
+func (m *ChangeRes) Reset()                    { *m = ChangeRes{} }
+func (m *ChangeRes) String() string            { return proto.CompactTextString(m) }
+func (*ChangeRes) ProtoMessage()               {}
+func (*ChangeRes) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{7} }
+
+func (m *ChangeRes) GetError() *Error {
+	if m != nil {
+		return m.Error
+	}
+	return nil
+}
+
+func (m *ChangeRes) GetValid() bool {
+	if m != nil {
+		return m.Valid
+	}
+	return false
+}
+
+func (m *ChangeRes) GetAccepted() bool {
+	if m != nil {
+		return m.Accepted
+	}
+	return false
+}
+
+func (m *ChangeRes) GetDistance() float64 {
+	if m != nil {
+		return m.Distance
+	}
+	return 0
+}
+
+func (m *ChangeRes) GetTransformation() string {
+	if m != nil {
+		return m.Transformation
+	}
+	return ""
+}
+
+type CredentialReq struct {
+	// Opaque account identifier owning the credential
+	Subject  string `protobuf:"bytes,1,opt,name=subject" json:"subject,omitempty"`
+	Password string `protobuf:"bytes,2,opt,name=password" json:"password,omitempty"`
+}
+
+func (m *CredentialReq) Reset()                    { *m = CredentialReq{} }
+func (m *CredentialReq) String() string            { return proto.CompactTextString(m) }
+func (*CredentialReq) ProtoMessage()               {}
+func (*CredentialReq) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{8} }
+
+func (m *CredentialReq) GetSubject() string {
+	if m != nil {
+		return m.Subject
+	}
+	return ""
+}
+
+func (m *CredentialReq) GetPassword() string {
+	if m != nil {
+		return m.Password
+	}
+	return ""
+}
+
+type SubjectReq struct {
+	// Opaque account identifier owning the credential
+	Subject string `protobuf:"bytes,1,opt,name=subject" json:"subject,omitempty"`
+}
+
+func (m *SubjectReq) Reset()                    { *m = SubjectReq{} }
+func (m *SubjectReq) String() string            { return proto.CompactTextString(m) }
+func (*SubjectReq) ProtoMessage()               {}
+func (*SubjectReq) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{9} }
+
+func (m *SubjectReq) GetSubject() string {
+	if m != nil {
+		return m.Subject
+	}
+	return ""
+}
+
+type SetPasswordRes struct {
+	Error *Error `protobuf:"bytes,1,opt,name=error" json:"error,omitempty"`
+}
+
+func (m *SetPasswordRes) Reset()                    { *m = SetPasswordRes{} }
+func (m *SetPasswordRes) String() string            { return proto.CompactTextString(m) }
+func (*SetPasswordRes) ProtoMessage()               {}
+func (*SetPasswordRes) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{10} }
+
+func (m *SetPasswordRes) GetError() *Error {
+	if m != nil {
+		return m.Error
+	}
+	return nil
+}
+
+type VerifyPasswordRes struct {
+	Error *Error `protobuf:"bytes,1,opt,name=error" json:"error,omitempty"`
+	Valid bool   `protobuf:"varint,2,opt,name=valid" json:"valid,omitempty"`
+	// Stored hash was replaced by one of the current algorithm
+	Rehashed bool `protobuf:"varint,3,opt,name=rehashed" json:"rehashed,omitempty"`
+}
+
+func (m *VerifyPasswordRes) Reset()                    { *m = VerifyPasswordRes{} }
+func (m *VerifyPasswordRes) String() string            { return proto.CompactTextString(m) }
+func (*VerifyPasswordRes) ProtoMessage()               {}
+func (*VerifyPasswordRes) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{11} }
+
+func (m *VerifyPasswordRes) GetError() *Error {
+	if m != nil {
+		return m.Error
+	}
+	return nil
+}
+
+func (m *VerifyPasswordRes) GetValid() bool {
+	if m != nil {
+		return m.Valid
+	}
+	return false
+}
+
+func (m *VerifyPasswordRes) GetRehashed() bool {
+	if m != nil {
+		return m.Rehashed
+	}
+	return false
+}
+
+type DeletePasswordRes struct {
+	Error   *Error `protobuf:"bytes,1,opt,name=error" json:"error,omitempty"`
+	Deleted bool   `protobuf:"varint,2,opt,name=deleted" json:"deleted,omitempty"`
+}
+
+func (m *DeletePasswordRes) Reset()                    { *m = DeletePasswordRes{} }
+func (m *DeletePasswordRes) String() string            { return proto.CompactTextString(m) }
+func (*DeletePasswordRes) ProtoMessage()               {}
+func (*DeletePasswordRes) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{12} }
+
+func (m *DeletePasswordRes) GetError() *Error {
+	if m != nil {
+		return m.Error
+	}
+	return nil
+}
+
+func (m *DeletePasswordRes) GetDeleted() bool {
+	if m != nil {
+		return m.Deleted
+	}
+	return false
+}
+
 type PongRes struct {
 	Timestamp *google_protobuf.Timestamp `protobuf:"bytes,1,opt,name=timestamp" json:"timestamp,omitempty"`
 }
@@ -116,7 +420,7 @@ type PongRes struct {
 func (m *PongRes) Reset()                    { *m = PongRes{} }
 func (m *PongRes) String() string            { return proto.CompactTextString(m) }
 func (*PongRes) ProtoMessage()               {}
-func (*PongRes) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{4} }
+func (*PongRes) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{13} }
 
 func (m *PongRes) GetTimestamp() *google_protobuf.Timestamp {
 	if m != nil {
@@ -130,27 +434,53 @@ func init() {
 	proto.RegisterType((*PasswordReq)(nil), "password.PasswordReq")
 	proto.RegisterType((*EncodedPasswordRes)(nil), "password.EncodedPasswordRes")
 	proto.RegisterType((*PasswordValidationRes)(nil), "password.PasswordValidationRes")
+	proto.RegisterType((*HistoryReq)(nil), "password.HistoryReq")
+	proto.RegisterType((*HistoryRes)(nil), "password.HistoryRes")
+	proto.RegisterType((*ChangeReq)(nil), "password.ChangeReq")
+	proto.RegisterType((*ChangeRes)(nil), "password.ChangeRes")
+	proto.RegisterType((*CredentialReq)(nil), "password.CredentialReq")
+	proto.RegisterType((*SubjectReq)(nil), "password.SubjectReq")
+	proto.RegisterType((*SetPasswordRes)(nil), "password.SetPasswordRes")
+	proto.RegisterType((*VerifyPasswordRes)(nil), "password.VerifyPasswordRes")
+	proto.RegisterType((*DeletePasswordRes)(nil), "password.DeletePasswordRes")
 	proto.RegisterType((*PongRes)(nil), "password.PongRes")
 }
 
 func init() { proto.RegisterFile("protocol.proto", fileDescriptor1) }
 
 var fileDescriptor1 = []byte{
-	// 246 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0xd0, 0x51, 0x4b, 0xc3, 0x30,
-	0x10, 0x00, 0x60, 0x2a, 0xd6, 0xad, 0x37, 0x50, 0x08, 0x0a, 0xa5, 0x2f, 0x8e, 0x82, 0xb0, 0xa7,
-	0x0c, 0x14, 0xc1, 0x17, 0x9f, 0x64, 0xcf, 0x8e, 0x30, 0x7c, 0xcf, 0x96, 0x98, 0x15, 0xda, 0x5e,
-	0x4d, 0xa2, 0xfe, 0x7d, 0xc9, 0xa5, 0xe9, 0x5e, 0x7c, 0xd9, 0xdb, 0x5d, 0xee, 0xf2, 0xdd, 0x71,
-	0x70, 0x3d, 0x58, 0xf4, 0x78, 0xc0, 0x96, 0x53, 0xc0, 0xe6, 0x83, 0x74, 0xee, 0x17, 0xad, 0xaa,
-	0xee, 0x0d, 0xa2, 0x69, 0xf5, 0x9a, 0xde, 0xf7, 0xdf, 0x9f, 0x6b, 0xdf, 0x74, 0xda, 0x79, 0xd9,
-	0x0d, 0xb1, 0xb5, 0x7e, 0x86, 0x7c, 0x63, 0x2d, 0x5a, 0xc6, 0xe0, 0xf2, 0x80, 0x4a, 0x97, 0xd9,
-	0x32, 0x5b, 0xe5, 0x82, 0x62, 0x56, 0xc2, 0xac, 0xd3, 0xce, 0x49, 0xa3, 0xcb, 0x8b, 0x65, 0xb6,
-	0x2a, 0x44, 0x4a, 0xeb, 0x57, 0x58, 0x6c, 0xc7, 0x19, 0x42, 0x7f, 0xb1, 0x0a, 0xa6, 0x91, 0x04,
-	0x14, 0x62, 0xca, 0x03, 0x7c, 0x94, 0xee, 0x38, 0x0a, 0x14, 0xd7, 0xef, 0xc0, 0x36, 0x7d, 0x18,
-	0xa1, 0x4e, 0x8a, 0x63, 0x0f, 0x90, 0xeb, 0xb0, 0x0b, 0x11, 0x8b, 0xc7, 0x1b, 0x9e, 0x0c, 0x4e,
-	0x2b, 0x8a, 0x58, 0xfd, 0x17, 0xdc, 0xc1, 0x5d, 0x92, 0x3e, 0x64, 0xdb, 0x28, 0xe9, 0x1b, 0xec,
-	0xcf, 0x30, 0x6f, 0x21, 0xff, 0x09, 0xff, 0x08, 0x9d, 0x8b, 0x98, 0xd4, 0x6f, 0x30, 0xdb, 0x62,
-	0x6f, 0x82, 0xf3, 0x02, 0xc5, 0x74, 0xba, 0xd1, 0xaa, 0x78, 0x3c, 0x2e, 0x4f, 0xc7, 0xe5, 0xbb,
-	0xd4, 0x21, 0x4e, 0xcd, 0xfb, 0x2b, 0x2a, 0x3f, 0xfd, 0x05, 0x00, 0x00, 0xff, 0xff, 0x8a, 0xe0,
-	0x7a, 0x5f, 0xa5, 0x01, 0x00, 0x00,
+	// 514 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xa4, 0x54, 0xc1, 0x6e, 0x13, 0x31,
+	0x10, 0xd5, 0x36, 0xd9, 0x26, 0x99, 0x94, 0xa0, 0xae, 0x00, 0xad, 0x72, 0x21, 0xb5, 0x44, 0xd5,
+	0xd3, 0x56, 0x02, 0x21, 0xb8, 0x87, 0x48, 0xdc, 0xa8, 0xdc, 0xaa, 0x07, 0x2e, 0x95, 0xb3, 0x9e,
+	0x24, 0x8b, 0x36, 0x76, 0x6a, 0xbb, 0x84, 0x9e, 0xf8, 0x1d, 0x3e, 0x13, 0x79, 0xbc, 0xbb, 0x49,
+	0xaa, 0x16, 0x68, 0xb9, 0xf9, 0x79, 0x3c, 0xef, 0xcd, 0x1b, 0x8f, 0x0d, 0x83, 0x95, 0xd1, 0x4e,
+	0xe7, 0xba, 0xcc, 0x68, 0x91, 0x74, 0x57, 0xc2, 0xda, 0xb5, 0x36, 0x72, 0xf8, 0x7a, 0xae, 0xf5,
+	0xbc, 0xc4, 0x53, 0xda, 0x9f, 0xde, 0xcc, 0x4e, 0x5d, 0xb1, 0x44, 0xeb, 0xc4, 0x72, 0x15, 0x8e,
+	0xb2, 0xf7, 0x10, 0x4f, 0x8c, 0xd1, 0x26, 0x49, 0xa0, 0x9d, 0x6b, 0x89, 0x69, 0x34, 0x8a, 0x4e,
+	0x62, 0x4e, 0xeb, 0x24, 0x85, 0xce, 0x12, 0xad, 0x15, 0x73, 0x4c, 0xf7, 0x46, 0xd1, 0x49, 0x8f,
+	0xd7, 0x90, 0x5d, 0x43, 0xff, 0xac, 0xd2, 0xe0, 0x78, 0x9d, 0x0c, 0xa1, 0x91, 0x24, 0x82, 0x1e,
+	0x6f, 0xb0, 0x27, 0x5e, 0x08, 0xbb, 0xa8, 0x18, 0x68, 0xed, 0x89, 0xed, 0xcd, 0xf4, 0x1b, 0xe6,
+	0x2e, 0x6d, 0x05, 0xe2, 0x0a, 0xfa, 0xc8, 0xa2, 0xb0, 0x4e, 0x9b, 0xdb, 0xb4, 0x3d, 0x6a, 0xf9,
+	0x48, 0x05, 0xd9, 0x17, 0x48, 0x26, 0xca, 0x97, 0x25, 0x37, 0xca, 0x36, 0x79, 0x03, 0x31, 0xfa,
+	0xfa, 0x49, 0xb6, 0xff, 0xf6, 0x79, 0x56, 0xeb, 0x66, 0x64, 0x8b, 0x87, 0xe8, 0x7d, 0x45, 0xb0,
+	0x35, 0xbc, 0xac, 0x99, 0x2e, 0x45, 0x59, 0x48, 0xe1, 0x0a, 0xad, 0x1e, 0xc1, 0xf9, 0x02, 0xe2,
+	0xef, 0x3e, 0x8f, 0x48, 0xbb, 0x3c, 0x80, 0xe4, 0x08, 0x0e, 0x14, 0xa2, 0xb4, 0x57, 0x06, 0x49,
+	0xb1, 0x45, 0xc1, 0x3e, 0xed, 0x71, 0xda, 0x62, 0x5f, 0x01, 0x3e, 0x07, 0x53, 0x7f, 0xeb, 0xdd,
+	0x2b, 0xd8, 0xf7, 0x19, 0x68, 0xd3, 0x3d, 0x6a, 0x46, 0x85, 0x1e, 0xee, 0x1f, 0xcb, 0xb7, 0xb8,
+	0xff, 0xd9, 0x89, 0xbf, 0x67, 0xe1, 0xf2, 0x05, 0xd6, 0x5e, 0x6a, 0xe8, 0x3d, 0x16, 0x4a, 0xe2,
+	0x0f, 0x92, 0x89, 0x79, 0x00, 0xec, 0x27, 0xf4, 0xc6, 0x0b, 0xa1, 0xe6, 0xe8, 0xeb, 0x3f, 0x82,
+	0x03, 0x5d, 0xca, 0xab, 0x3b, 0x1e, 0xfa, 0xba, 0x6c, 0xee, 0xe9, 0xde, 0x11, 0xa0, 0x3e, 0xad,
+	0x37, 0x69, 0xc1, 0x47, 0x5f, 0xe1, 0xba, 0x49, 0xdb, 0x72, 0xd9, 0xde, 0x75, 0xf9, 0x2b, 0xda,
+	0x54, 0xf0, 0x9f, 0xf7, 0x35, 0x84, 0xae, 0xc8, 0x73, 0x5c, 0x39, 0x94, 0xd5, 0x5d, 0x35, 0xd8,
+	0xc7, 0x64, 0x61, 0x9d, 0x50, 0x39, 0x52, 0x05, 0x11, 0x6f, 0x70, 0x72, 0x0c, 0x03, 0x67, 0x84,
+	0xb2, 0x33, 0x6d, 0x96, 0x34, 0x39, 0x69, 0x4c, 0x35, 0xde, 0xd9, 0x65, 0x13, 0x78, 0x36, 0x36,
+	0x28, 0x51, 0xb9, 0x42, 0x94, 0xbe, 0x5f, 0x5b, 0xae, 0xa2, 0xdd, 0xd9, 0xdf, 0x9e, 0x84, 0xbd,
+	0xdd, 0x49, 0x60, 0xc7, 0x00, 0xe7, 0xe1, 0xd8, 0x1f, 0x39, 0xd8, 0x07, 0x18, 0x9c, 0xa3, 0x7b,
+	0xfc, 0x0b, 0x61, 0x25, 0x1c, 0x5e, 0xa2, 0x29, 0x66, 0xb7, 0x4f, 0x78, 0x5d, 0x0f, 0x76, 0x36,
+	0xbc, 0x81, 0x4d, 0x67, 0x6b, 0xcc, 0x2e, 0xe0, 0xf0, 0x13, 0x96, 0xe8, 0xf0, 0x09, 0x6a, 0x29,
+	0x74, 0x24, 0xe5, 0x36, 0xd3, 0x5a, 0x41, 0x36, 0x86, 0xce, 0x99, 0x56, 0x73, 0xcf, 0xf5, 0x11,
+	0x7a, 0xcd, 0x57, 0x57, 0xf1, 0x0d, 0xb3, 0xf0, 0x19, 0x66, 0xf5, 0x67, 0x98, 0x5d, 0xd4, 0x27,
+	0xf8, 0xe6, 0xf0, 0x74, 0x9f, 0xc2, 0xef, 0x7e, 0x0f, 0x00, 0xcc, 0x58, 0xda, 0x01, 0x55, 0x05,
+	0x00, 0x00,
 }
@@ -0,0 +1,121 @@
+package password
+
+import (
+	"fmt"
+	"strconv"
+)
+
+const redacted = "[REDACTED]"
+
+// Format masks password and hash values whatever the formatting verb, to keep
+// them out of logs and error messages.
+func (m *PasswordReq) Format(f fmt.State, verb rune) {
+	if m == nil {
+		fmt.Fprint(f, "<nil>")
+		return
+	}
+
+	fmt.Fprintf(f, "password:%s hash:%s", mask(m.Password), mask(m.Hash))
+	if len(m.Subject) > 0 {
+		fmt.Fprintf(f, " subject:%s", strconv.Quote(m.Subject))
+	}
+	if len(m.History) > 0 {
+		fmt.Fprintf(f, " history:%d", len(m.History))
+	}
+}
+
+// Secrets returns the secret values carried by the request
+func (m *PasswordReq) Secrets() []string {
+	if m == nil {
+		return nil
+	}
+	return append([]string{m.Password, m.Hash}, m.History...)
+}
+
+// Format masks password and hash values, only the number of hashes is kept
+func (m *HistoryReq) Format(f fmt.State, verb rune) {
+	if m == nil {
+		fmt.Fprint(f, "<nil>")
+		return
+	}
+
+	fmt.Fprintf(f, "password:%s hashes:%d", mask(m.Password), len(m.Hashes))
+	if len(m.Subject) > 0 {
+		fmt.Fprintf(f, " subject:%s", strconv.Quote(m.Subject))
+	}
+}
+
+// Secrets returns the secret values carried by the request
+func (m *HistoryReq) Secrets() []string {
+	if m == nil {
+		return nil
+	}
+	return append([]string{m.Password}, m.Hashes...)
+}
+
+// Format masks passwords and hash values
+func (m *ChangeReq) Format(f fmt.State, verb rune) {
+	if m == nil {
+		fmt.Fprint(f, "<nil>")
+		return
+	}
+
+	fmt.Fprintf(f, "old_password:%s hash:%s new_password:%s", mask(m.OldPassword), mask(m.Hash), mask(m.NewPassword))
+	if len(m.Subject) > 0 {
+		fmt.Fprintf(f, " subject:%s", strconv.Quote(m.Subject))
+	}
+}
+
+// Secrets returns the secret values carried by the request
+func (m *ChangeReq) Secrets() []string {
+	if m == nil {
+		return nil
+	}
+	return []string{m.OldPassword, m.Hash, m.NewPassword}
+}
+
+// Format masks the password value
+func (m *CredentialReq) Format(f fmt.State, verb rune) {
+	if m == nil {
+		fmt.Fprint(f, "<nil>")
+		return
+	}
+
+	fmt.Fprintf(f, "subject:%s password:%s", strconv.Quote(m.Subject), mask(m.Password))
+}
+
+// Secrets returns the secret values carried by the request
+func (m *CredentialReq) Secrets() []string {
+	if m == nil {
+		return nil
+	}
+	return []string{m.Password}
+}
+
+// MarshalText masks secrets of the text format used by the generated String()
+// method, proto.CompactTextString and proto.MarshalTextString
+func (m *PasswordReq) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprint(m)), nil
+}
+
+// MarshalText masks secrets of the text format
+func (m *HistoryReq) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprint(m)), nil
+}
+
+// MarshalText masks secrets of the text format
+func (m *ChangeReq) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprint(m)), nil
+}
+
+// MarshalText masks secrets of the text format
+func (m *CredentialReq) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprint(m)), nil
+}
+
+func mask(v string) string {
+	if len(v) == 0 {
+		return `""`
+	}
+	return strconv.Quote(redacted)
+}
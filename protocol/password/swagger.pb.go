@@ -18,8 +18,152 @@ const (
     "application/json"
   ],
   "paths": {
+    "/v1/change": {
+      "post": {
+        "summary": "Check a password change, new passwords too similar to the old one are\nrejected",
+        "operationId": "CheckChange",
+        "responses": {
+          "200": {
+            "description": "",
+            "schema": {
+              "$ref": "#/definitions/passwordChangeRes"
+            }
+          }
+        },
+        "parameters": [
+          {
+            "name": "body",
+            "in": "body",
+            "required": true,
+            "schema": {
+              "$ref": "#/definitions/passwordChangeReq"
+            }
+          }
+        ],
+        "tags": [
+          "Password"
+        ]
+      }
+    },
+    "/v1/credentials/{subject}": {
+      "delete": {
+        "summary": "Delete the password of a subject, in managed credential store mode",
+        "operationId": "DeletePassword",
+        "responses": {
+          "200": {
+            "description": "",
+            "schema": {
+              "$ref": "#/definitions/passwordDeletePasswordRes"
+            }
+          }
+        },
+        "parameters": [
+          {
+            "name": "subject",
+            "in": "path",
+            "required": true,
+            "type": "string"
+          }
+        ],
+        "tags": [
+          "Password"
+        ]
+      },
+      "put": {
+        "summary": "Store the password of a subject, in managed credential store mode",
+        "operationId": "SetPassword",
+        "responses": {
+          "200": {
+            "description": "",
+            "schema": {
+              "$ref": "#/definitions/passwordSetPasswordRes"
+            }
+          }
+        },
+        "parameters": [
+          {
+            "name": "subject",
+            "in": "path",
+            "required": true,
+            "type": "string"
+          },
+          {
+            "name": "body",
+            "in": "body",
+            "required": true,
+            "schema": {
+              "$ref": "#/definitions/passwordCredentialReq"
+            }
+          }
+        ],
+        "tags": [
+          "Password"
+        ]
+      }
+    },
+    "/v1/credentials/{subject}/verify": {
+      "post": {
+        "summary": "Verify the password of a subject, in managed credential store mode",
+        "operationId": "VerifyPassword",
+        "responses": {
+          "200": {
+            "description": "",
+            "schema": {
+              "$ref": "#/definitions/passwordVerifyPasswordRes"
+            }
+          }
+        },
+        "parameters": [
+          {
+            "name": "subject",
+            "in": "path",
+            "required": true,
+            "type": "string"
+          },
+          {
+            "name": "body",
+            "in": "body",
+            "required": true,
+            "schema": {
+              "$ref": "#/definitions/passwordCredentialReq"
+            }
+          }
+        ],
+        "tags": [
+          "Password"
+        ]
+      }
+    },
+    "/v1/history": {
+      "post": {
+        "summary": "Check a password against previous hashes, to prevent its reuse",
+        "operationId": "CheckHistory",
+        "responses": {
+          "200": {
+            "description": "",
+            "schema": {
+              "$ref": "#/definitions/passwordHistoryRes"
+            }
+          }
+        },
+        "parameters": [
+          {
+            "name": "body",
+            "in": "body",
+            "required": true,
+            "schema": {
+              "$ref": "#/definitions/passwordHistoryReq"
+            }
+          }
+        ],
+        "tags": [
+          "Password"
+        ]
+      }
+    },
     "/v1/password": {
       "post": {
+        "summary": "Encode a given password using default Butcher strategy",
         "operationId": "Encode",
         "responses": {
           "200": {
@@ -44,8 +188,26 @@ const (
         ]
       }
     },
+    "/v1/ping": {
+      "get": {
+        "summary": "Ping the password server. Example for empty query",
+        "operationId": "Ping",
+        "responses": {
+          "200": {
+            "description": "",
+            "schema": {
+              "$ref": "#/definitions/passwordPongRes"
+            }
+          }
+        },
+        "tags": [
+          "Password"
+        ]
+      }
+    },
     "/v1/validate": {
       "post": {
+        "summary": "Validate a password hash encoded by Butcher",
         "operationId": "Validate",
         "responses": {
           "200": {
@@ -72,6 +234,76 @@ const (
     }
   },
   "definitions": {
+    "passwordChangeReq": {
+      "type": "object",
+      "properties": {
+        "old_password": {
+          "type": "string",
+          "title": "Current password, verified against its hash"
+        },
+        "hash": {
+          "type": "string"
+        },
+        "new_password": {
+          "type": "string"
+        },
+        "subject": {
+          "type": "string",
+          "title": "Opaque account identifier, only used for audit records"
+        }
+      }
+    },
+    "passwordChangeRes": {
+      "type": "object",
+      "properties": {
+        "error": {
+          "$ref": "#/definitions/passwordError"
+        },
+        "valid": {
+          "type": "boolean",
+          "format": "boolean",
+          "title": "Old password matches the hash, similarity is only checked when valid"
+        },
+        "accepted": {
+          "type": "boolean",
+          "format": "boolean",
+          "title": "New password is not too similar to the old one"
+        },
+        "distance": {
+          "type": "number",
+          "format": "double",
+          "title": "Normalized edit distance, from 0 (same) to 1 (nothing in common)"
+        },
+        "transformation": {
+          "type": "string",
+          "title": "Transformation deriving the new password from the old one (case,\nincrement, append, prepend), empty when none is detected"
+        }
+      }
+    },
+    "passwordCredentialReq": {
+      "type": "object",
+      "properties": {
+        "subject": {
+          "type": "string",
+          "title": "Opaque account identifier owning the credential"
+        },
+        "password": {
+          "type": "string"
+        }
+      }
+    },
+    "passwordDeletePasswordRes": {
+      "type": "object",
+      "properties": {
+        "error": {
+          "$ref": "#/definitions/passwordError"
+        },
+        "deleted": {
+          "type": "boolean",
+          "format": "boolean"
+        }
+      }
+    },
     "passwordEncodedPasswordRes": {
       "type": "object",
       "properties": {
@@ -95,6 +327,42 @@ const (
         }
       }
     },
+    "passwordHistoryReq": {
+      "type": "object",
+      "properties": {
+        "password": {
+          "type": "string"
+        },
+        "hashes": {
+          "type": "array",
+          "items": {
+            "type": "string"
+          },
+          "title": "Previous hashes, in any supported format"
+        },
+        "subject": {
+          "type": "string",
+          "title": "Opaque account identifier, only used for audit records"
+        }
+      }
+    },
+    "passwordHistoryRes": {
+      "type": "object",
+      "properties": {
+        "error": {
+          "$ref": "#/definitions/passwordError"
+        },
+        "matched": {
+          "type": "boolean",
+          "format": "boolean"
+        },
+        "index": {
+          "type": "integer",
+          "format": "int32",
+          "title": "Index of the first matching hash, -1 when none matched"
+        }
+      }
+    },
     "passwordPasswordReq": {
       "type": "object",
       "properties": {
@@ -103,6 +371,17 @@ const (
         },
         "hash": {
           "type": "string"
+        },
+        "subject": {
+          "type": "string",
+          "title": "Opaque account identifier, only used for audit records"
+        },
+        "history": {
+          "type": "array",
+          "items": {
+            "type": "string"
+          },
+          "title": "Previous hashes, Encode rejects a password matching one of them"
         }
       }
     },
@@ -115,6 +394,45 @@ const (
         "valid": {
           "type": "boolean",
           "format": "boolean"
+        },
+        "needs_rehash": {
+          "type": "boolean",
+          "format": "boolean",
+          "title": "Hash should be replaced by a new Encode result once validated"
+        }
+      }
+    },
+    "passwordPongRes": {
+      "type": "object",
+      "properties": {
+        "timestamp": {
+          "type": "string",
+          "format": "date-time"
+        }
+      }
+    },
+    "passwordSetPasswordRes": {
+      "type": "object",
+      "properties": {
+        "error": {
+          "$ref": "#/definitions/passwordError"
+        }
+      }
+    },
+    "passwordVerifyPasswordRes": {
+      "type": "object",
+      "properties": {
+        "error": {
+          "$ref": "#/definitions/passwordError"
+        },
+        "valid": {
+          "type": "boolean",
+          "format": "boolean"
+        },
+        "rehashed": {
+          "type": "boolean",
+          "format": "boolean",
+          "title": "Stored hash was replaced by one of the current algorithm"
         }
       }
     }
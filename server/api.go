@@ -2,19 +2,49 @@ package server
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
 	"net/http"
 	"strings"
 	"time"
 
+	"go.zenithar.org/password/hashing"
 	pb "go.zenithar.org/password/protocol/password"
+	"go.zenithar.org/password/similarity"
+	"go.zenithar.org/password/store"
+	"go.zenithar.org/password/utils/redact"
+	"go.zenithar.org/password/utils/secret"
 
 	"github.com/golang/protobuf/ptypes"
 	"github.com/golang/protobuf/ptypes/empty"
-	"go.zenithar.org/butcher"
+	opentracing "github.com/opentracing/opentracing-go"
+	otlog "github.com/opentracing/opentracing-go/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 )
 
+// customAlgorithm is the metric label of hashes produced by a custom butcher
+const customAlgorithm = "custom"
+
 type myService struct {
-	butch *butcher.Butcher
+	registry      *hashing.Registry
+	algorithm     string
+	normalization string
+	dummyHash     string
+	validateFloor time.Duration
+	compliance    *hashing.Compliance
+	workers       *workerPool
+	lockMemory    bool
+	maxPassword   int
+	maxHash       int
+	maxHistory    int
+	historySlots  int
+	changePolicy  similarity.Policy
+	store         store.Store
+	tracer        opentracing.Tracer
+	strategy      hashing.Strategy
+	hash          func(password []byte) (string, error)
 }
 
 func (m *myService) Encode(c context.Context, s *pb.PasswordReq) (*pb.EncodedPasswordRes, error) {
@@ -22,34 +52,62 @@ func (m *myService) Encode(c context.Context, s *pb.PasswordReq) (*pb.EncodedPas
 
 	// Check mandatory fields
 	if len(strings.TrimSpace(s.Password)) == 0 {
+		policyRejections.WithLabelValues(rejectEmptyPassword).Inc()
 		res.Error = &pb.Error{
 			Code:    http.StatusPreconditionFailed,
 			Message: "Password value is mandatory !",
 		}
 		return res, nil
 	}
+	if err := m.checkHistory(s.Password, s.History); err != nil {
+		return nil, err
+	}
+
+	// Reject reused passwords
+	if len(s.History) > 0 {
+		index, perr, err := m.historyMatch(c, s.Password, s.History)
+		if err != nil {
+			return nil, err
+		}
+		if perr != nil {
+			res.Error = perr
+			return res, nil
+		}
+		if index >= 0 {
+			policyRejections.WithLabelValues(rejectPasswordReused).Inc()
+			res.Error = &pb.Error{
+				Code:    http.StatusPreconditionFailed,
+				Message: fmt.Sprintf("Password matches previous password %d !", index),
+			}
+			return res, nil
+		}
+	}
 
 	// Hash given password
-	passwd, err := m.butch.Hash([]byte(s.Password))
+	hash, perr, err := m.encode(c, s.Password)
 	if err != nil {
-		res.Error = &pb.Error{
-			Code:    http.StatusBadRequest,
-			Message: err.Error(),
-		}
+		return nil, err
+	}
+	if perr != nil {
+		res.Error = perr
 		return res, nil
 	}
 
 	// Return the result
-	res.Hash = passwd
+	res.Hash = hash
 
 	return res, nil
 }
 
 func (m *myService) Validate(c context.Context, s *pb.PasswordReq) (*pb.PasswordValidationRes, error) {
 	res := &pb.PasswordValidationRes{}
+	if m.validateFloor > 0 {
+		defer m.wait(c, time.Now().Add(m.validateFloor))
+	}
 
 	// Check mandatory fields
 	if len(strings.TrimSpace(s.Password)) == 0 {
+		policyRejections.WithLabelValues(rejectEmptyPassword).Inc()
 		res.Error = &pb.Error{
 			Code:    http.StatusPreconditionFailed,
 			Message: "Password value is mandatory !",
@@ -57,26 +115,73 @@ func (m *myService) Validate(c context.Context, s *pb.PasswordReq) (*pb.Password
 		return res, nil
 	}
 
+	// Unknown subjects are verified against the dummy hash
+	hash, dummy := s.Hash, false
 	if len(strings.TrimSpace(s.Hash)) == 0 {
-		res.Error = &pb.Error{
-			Code:    http.StatusPreconditionFailed,
-			Message: "Hash value is mandatory !",
+		if len(m.dummyHash) == 0 {
+			policyRejections.WithLabelValues(rejectEmptyHash).Inc()
+			res.Error = &pb.Error{
+				Code:    http.StatusPreconditionFailed,
+				Message: "Hash value is mandatory !",
+			}
+			return res, nil
 		}
-		return res, nil
+		hash, dummy = m.dummyHash, true
+	}
+	if err := m.checkLengths(s.Password, s.Hash); err != nil {
+		return nil, err
 	}
 
-	// Hash given password
-	valid, err := butcher.Verify([]byte(s.Hash), []byte(s.Password))
+	// Resolve hash strategy
+	strategy, err := m.registry.Identify(hash)
 	if err != nil {
+		if err == hashing.ErrUnknownStrategy {
+			validations.WithLabelValues("unsupported").Inc()
+		} else {
+			validations.WithLabelValues("malformed").Inc()
+		}
 		res.Error = &pb.Error{
 			Code:    http.StatusBadRequest,
 			Message: err.Error(),
 		}
 		return res, nil
 	}
+	profile, encoded, _ := hashing.SplitNormalization(hash)
+
+	// Hash given password, normalized as it was when the hash was produced
+	v, werr := m.verify(c, strategy, hash, s.Password)
+	if werr != nil {
+		m.rejected(werr)
+		return nil, werr
+	}
+	if v.err == hashing.ErrInvalidPassword {
+		return nil, m.invalidPassword()
+	}
+	if v.err != nil {
+		validations.WithLabelValues("malformed").Inc()
+		res.Error = &pb.Error{
+			Code:    http.StatusBadRequest,
+			Message: v.err.Error(),
+		}
+		return res, nil
+	}
 
 	// Return result
-	res.Valid = valid
+	if dummy {
+		validations.WithLabelValues("dummy").Inc()
+		return res, nil
+	}
+	res.Valid = v.valid
+	if !v.valid {
+		validations.WithLabelValues("invalid").Inc()
+		return res, nil
+	}
+
+	validations.WithLabelValues("valid").Inc()
+	res.NeedsRehash = m.needsRehash(strategy, profile, encoded)
+	if res.NeedsRehash {
+		needsRehash.WithLabelValues(strategy.Name()).Inc()
+	}
 
 	return res, nil
 }
@@ -88,9 +193,248 @@ func (m *myService) Ping(c context.Context, s *empty.Empty) (*pb.PongRes, error)
 	}, nil
 }
 
-func newServer() *myService {
-	butch, _ := butcher.New()
-	return &myService{
-		butch: butch,
+// checkLengths rejects passwords and hashes exceeding the configured limits,
+// before any hashing work.
+func (m *myService) checkLengths(password string, hashes ...string) error {
+	if len(password) > m.maxPassword {
+		policyRejections.WithLabelValues(rejectPasswordTooLong).Inc()
+		return grpc.Errorf(codes.InvalidArgument, "password exceeds %d bytes", m.maxPassword)
+	}
+	for _, hash := range hashes {
+		if len(hash) > m.maxHash {
+			policyRejections.WithLabelValues(rejectHashTooLong).Inc()
+			return grpc.Errorf(codes.InvalidArgument, "hash exceeds %d bytes", m.maxHash)
+		}
+	}
+	return nil
+}
+
+// encode hashes the password on a hashing worker, after normalizing it with
+// the current profile, the returned hash records the profile. Invalid
+// passwords are reported as a response error.
+func (m *myService) encode(ctx context.Context, password string) (string, *pb.Error, error) {
+	var (
+		hash string
+		err  error
+	)
+	if werr := m.process(ctx, "password.hash", m.algorithmLabel(), func() error {
+		defer m.track(m.strategy)()
+
+		buf := secret.FromString(password, m.lockMemory)
+		defer buf.Destroy()
+
+		var normalized []byte
+		if normalized, err = hashing.Normalize(m.normalization, buf.Bytes()); err != nil {
+			return err
+		}
+		defer secret.Wipe(normalized)
+
+		start := time.Now()
+		hash, err = m.hash(normalized)
+		observe(ctx, hashDuration.WithLabelValues(m.algorithmLabel()), time.Since(start).Seconds())
+		return err
+	}); werr != nil {
+		m.rejected(werr)
+		return "", nil, werr
+	}
+	if err == hashing.ErrInvalidPassword {
+		return "", nil, m.invalidPassword()
+	}
+
+	// Salt source failures are not caller errors
+	if _, ok := err.(*hashing.EntropyError); ok {
+		return "", nil, grpc.Errorf(codes.Unavailable, "salt generation is unavailable")
+	}
+	if err != nil {
+		return "", &pb.Error{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		}, nil
+	}
+
+	return hashing.RecordNormalization(m.normalization, hash), nil, nil
+}
+
+// verification is the result of a password verification
+type verification struct {
+	valid bool
+	// err is a normalization or verification error
+	err error
+}
+
+// verify checks the password against the encoded hash on a hashing worker,
+// after normalizing it with the profile recorded in the hash. Only worker
+// errors are returned.
+func (m *myService) verify(ctx context.Context, strategy hashing.Strategy, hash, password string) (verification, error) {
+	profile, encoded, _ := hashing.SplitNormalization(hash)
+
+	var v verification
+	werr := m.process(ctx, "password.verify", strategy.Name(), func() error {
+		defer m.track(strategy)()
+
+		buf := secret.FromString(password, m.lockMemory)
+		defer buf.Destroy()
+
+		var normalized []byte
+		if normalized, v.err = hashing.Normalize(profile, buf.Bytes()); v.err != nil {
+			return v.err
+		}
+		defer secret.Wipe(normalized)
+
+		start := time.Now()
+		v.valid, v.err = strategy.Verify(encoded, normalized)
+		observe(ctx, verifyDuration.WithLabelValues(strategy.Name()), time.Since(start).Seconds())
+		return v.err
+	})
+
+	return v, werr
+}
+
+// wait returns at the given deadline, or when the call is cancelled
+func (m *myService) wait(ctx context.Context, deadline time.Time) {
+	delay := time.Until(deadline)
+	if delay <= 0 {
+		return
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
+
+// withDummy prepares the dummy hash verified for unknown subjects, from a
+// random password hashed with the current algorithm and normalization profile.
+func (m *myService) withDummy(enabled bool) (*myService, error) {
+	if !enabled {
+		return m, nil
+	}
+
+	password := make([]byte, 32)
+	if _, err := rand.Read(password); err != nil {
+		return nil, fmt.Errorf("server: unable to generate the dummy password, %v", err)
+	}
+	encoded, err := m.hash([]byte(hex.EncodeToString(password)))
+	if err != nil {
+		return nil, fmt.Errorf("server: unable to generate the dummy hash, %v", err)
+	}
+	encoded = hashing.RecordNormalization(m.normalization, encoded)
+	if _, err := m.registry.Identify(encoded); err != nil {
+		return nil, fmt.Errorf("server: dummy hash can't be verified, %v", err)
+	}
+	m.dummyHash = encoded
+
+	return m, nil
+}
+
+// invalidPassword rejects passwords the normalization profile can't accept
+func (m *myService) invalidPassword() error {
+	policyRejections.WithLabelValues(rejectInvalidPassword).Inc()
+	return grpc.Errorf(codes.InvalidArgument, "password contains invalid UTF-8 or disallowed characters")
+}
+
+// needsRehash returns true when the hash was not produced by the current
+// algorithm, parameters and normalization profile, or is not compliant.
+func (m *myService) needsRehash(strategy hashing.Strategy, profile, encoded string) bool {
+	return profile != m.normalization || m.registry.NeedsUpgrade(encoded, m.algorithm) || !m.compliance.Compliant(strategy, encoded)
+}
+
+func (m *myService) algorithmLabel() string {
+	if m.strategy == nil {
+		return customAlgorithm
 	}
+	return m.strategy.Name()
+}
+
+// track accounts the memory of a memory hard operation until the returned
+// function is invoked.
+func (m *myService) track(strategy hashing.Strategy) func() {
+	coster, ok := strategy.(hashing.MemoryCoster)
+	if !ok || coster.MemoryCost() == 0 {
+		return func() {}
+	}
+
+	gauge := memoryInUse.WithLabelValues(strategy.Name())
+	gauge.Add(float64(coster.MemoryCost()))
+	return func() {
+		gauge.Sub(float64(coster.MemoryCost()))
+	}
+}
+
+// process runs fn on a hashing worker, within a child span of the call
+// covering the queue wait and the hashing work. Only worker errors are
+// returned.
+func (m *myService) process(ctx context.Context, operation, algorithm string, fn func() error) error {
+	span := startSpan(ctx, m.tracer, operation)
+	span.SetTag("hashing.algorithm", algorithm)
+
+	var err error
+	werr := m.workers.do(ctx, func() {
+		span.LogFields(otlog.String("event", "worker acquired"))
+		err = fn()
+	})
+	if werr != nil {
+		err = werr
+	}
+	finishSpan(span, redact.FromContext(ctx).Error(err))
+
+	return werr
+}
+
+func (m *myService) rejected(err error) {
+	if err == errQueueFull {
+		policyRejections.WithLabelValues(rejectQueueFull).Inc()
+	}
+}
+
+func newServer(ms *MicroServer, registry *hashing.Registry) (*myService, error) {
+	svc := &myService{
+		registry:      registry,
+		algorithm:     ms.algorithm,
+		compliance:    ms.compliance,
+		workers:       ms.workers,
+		lockMemory:    ms.lockMemory,
+		maxPassword:   ms.maxPasswordLength,
+		maxHash:       ms.maxHashLength,
+		maxHistory:    ms.maxHistory,
+		historySlots:  ms.historyConcurrency,
+		changePolicy:  ms.changePolicy,
+		store:         ms.store,
+		tracer:        ms.tracer,
+		validateFloor: ms.validateFloor,
+	}
+	compliance := ms.compliance
+
+	normalization, err := hashing.NormalizationProfile(ms.normalization)
+	if err != nil {
+		return nil, fmt.Errorf("server: unsupported normalization profile '%s'", ms.normalization)
+	}
+	svc.normalization = normalization
+
+	// Custom hasher instance takes precedence over registered strategies
+	if ms.butcher != nil {
+		if compliance != nil {
+			return nil, fmt.Errorf("server: custom hasher can't be used in '%s' compliance mode", compliance.Name)
+		}
+		svc.hash = ms.butcher.Hash
+		return svc.withDummy(ms.dummyValidation)
+	}
+
+	if !compliance.Allows(ms.algorithm) {
+		return nil, fmt.Errorf("server: hashing algorithm '%s' is not approved in '%s' compliance mode", ms.algorithm, compliance.Name)
+	}
+	if compliance != nil && ms.saltLength < compliance.MinSaltLength {
+		return nil, fmt.Errorf("server: salt length must be at least %d bytes in '%s' compliance mode", compliance.MinSaltLength, compliance.Name)
+	}
+
+	strategy, err := registry.Lookup(ms.algorithm)
+	if err != nil {
+		return nil, fmt.Errorf("server: hashing algorithm '%s' is not registered", ms.algorithm)
+	}
+	svc.strategy = strategy
+	svc.hash = strategy.Hash
+
+	return svc.withDummy(ms.dummyValidation)
 }
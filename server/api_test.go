@@ -0,0 +1,112 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"go.zenithar.org/password/hashing"
+	pb "go.zenithar.org/password/protocol/password"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+func TestPasswordBuffersWiped(t *testing.T) {
+	const password = "correct horse battery staple"
+
+	strategy := &fakeStrategy{name: "plain"}
+	client, stop := startFakeServer(t, []*fakeStrategy{strategy})
+	defer stop()
+
+	encoded, err := client.Encode(context.Background(), &pb.PasswordReq{Password: password})
+	if err != nil || encoded.Error != nil {
+		t.Fatalf("Encode failed: %v %v", err, encoded.GetError())
+	}
+	validated, err := client.Validate(context.Background(), &pb.PasswordReq{Password: password, Hash: encoded.Hash})
+	if err != nil || validated.Error != nil || !validated.Valid {
+		t.Fatalf("Validate failed: %v %v", err, validated.GetError())
+	}
+
+	strategy.mu.Lock()
+	defer strategy.mu.Unlock()
+	if len(strategy.buffers) != 2 {
+		t.Fatalf("expected 2 hashing calls, got %d", len(strategy.buffers))
+	}
+	for i, buf := range strategy.buffers {
+		if strategy.contents[i] != password {
+			t.Errorf("call %d received %q instead of the password", i, strategy.contents[i])
+		}
+		if !bytes.Equal(buf, make([]byte, len(buf))) {
+			t.Errorf("call %d password buffer is not wiped: %q", i, buf)
+		}
+	}
+}
+
+func TestPasswordBuffersLocked(t *testing.T) {
+	const password = "correct horse battery staple"
+
+	strategy := &fakeStrategy{name: "plain"}
+	client, stop := startFakeServer(t, []*fakeStrategy{strategy}, WithMemoryLocking(true))
+	defer stop()
+
+	// Locked buffers are unmapped once wiped, only their content during the
+	// call is checked
+	encoded, err := client.Encode(context.Background(), &pb.PasswordReq{Password: password})
+	if err != nil || encoded.Error != nil {
+		t.Fatalf("Encode failed: %v %v", err, encoded.GetError())
+	}
+
+	strategy.mu.Lock()
+	defer strategy.mu.Unlock()
+	if len(strategy.contents) != 1 || strategy.contents[0] != password {
+		t.Errorf("hashing should receive the password, got %q", strategy.contents)
+	}
+}
+
+func TestPasswordNormalization(t *testing.T) {
+	const (
+		decomposed = "cafe\u0301"
+		composed   = "caf\u00e9"
+	)
+
+	strategy := &fakeStrategy{name: "plain"}
+	client, stop := startFakeServer(t, []*fakeStrategy{strategy}, WithNormalization(hashing.NormalizationNFC))
+	defer stop()
+
+	encoded, err := client.Encode(context.Background(), &pb.PasswordReq{Password: decomposed})
+	if err != nil || encoded.Error != nil {
+		t.Fatalf("Encode failed: %v %v", err, encoded.GetError())
+	}
+	if !strings.HasPrefix(encoded.Hash, "nfc:plain$") {
+		t.Errorf("hash should record the normalization profile, got %s", encoded.Hash)
+	}
+
+	for _, password := range []string{"\xffpassword", "pass\x00word", "pass\x1bword"} {
+		_, err := client.Encode(context.Background(), &pb.PasswordReq{Password: password})
+		if grpc.Code(err) != codes.InvalidArgument {
+			t.Errorf("Encode(%q) should be rejected, got %v", password, err)
+		}
+	}
+
+	// The recorded profile applies after a configuration change
+	client, stop = startFakeServer(t, []*fakeStrategy{strategy}, WithNormalization(hashing.NormalizationNone))
+	defer stop()
+
+	validated, err := client.Validate(context.Background(), &pb.PasswordReq{Password: composed, Hash: encoded.Hash})
+	if err != nil || validated.Error != nil || !validated.Valid {
+		t.Fatalf("Validate failed: %v %v", err, validated.GetError())
+	}
+	if !validated.NeedsRehash {
+		t.Error("hash of another normalization profile should need a rehash")
+	}
+
+	strategy.mu.Lock()
+	defer strategy.mu.Unlock()
+	for i, content := range strategy.contents {
+		if content != composed {
+			t.Errorf("call %d received %q instead of the normalized password", i, content)
+		}
+	}
+}
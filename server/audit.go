@@ -0,0 +1,169 @@
+package server
+
+import (
+	"context"
+	"strings"
+
+	"go.zenithar.org/password/audit"
+	"go.zenithar.org/password/auth"
+	"go.zenithar.org/password/hashing"
+	pb "go.zenithar.org/password/protocol/password"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+)
+
+const (
+	// auditedService defines the gRPC service prefix of audited methods
+	auditedService = "/password.Password/"
+)
+
+type subjectHolder interface {
+	GetSubject() string
+}
+
+// auditRecord returns a record prefilled with caller information
+func auditRecord(ctx context.Context, fullMethod string) audit.Record {
+	r := audit.Record{
+		Method:   fullMethod,
+		ClientIP: peerAddress(ctx),
+	}
+	if id, ok := auth.FromContext(ctx); ok {
+		r.Identity = id.Principal()
+	}
+	return r
+}
+
+func writeAudit(logger *logrus.Entry, auditor *audit.Logger, r audit.Record) {
+	if err := auditor.Log(r); err != nil {
+		logger.WithError(err).WithField("grpc.method", r.Method).Error("Unable to write audit record")
+	}
+}
+
+// auditUnaryServerInterceptor records every password operation outcome
+func auditUnaryServerInterceptor(logger *logrus.Entry, auditor *audit.Logger, registry *hashing.Registry) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		res, err := handler(ctx, req)
+		if auditor == nil || !isAudited(info.FullMethod) {
+			return res, err
+		}
+
+		r := auditRecord(ctx, info.FullMethod)
+		if s, ok := req.(subjectHolder); ok {
+			r.Subject = s.GetSubject()
+		}
+
+		switch {
+		case err != nil:
+			r.Outcome = audit.OutcomeError
+			r.Reason = grpc.Code(err).String()
+		default:
+			r.Outcome, r.Algorithm, r.Reason = describeResult(registry, req, res)
+		}
+
+		writeAudit(logger, auditor, r)
+
+		return res, err
+	}
+}
+
+func isAudited(fullMethod string) bool {
+	return strings.HasPrefix(fullMethod, auditedService) && fullMethod != auditedService+"Ping"
+}
+
+// describeResult extracts outcome, algorithm and failure reason of a call.
+// Password and hash values are never returned.
+func describeResult(registry *hashing.Registry, req, res interface{}) (outcome, algorithm, reason string) {
+	outcome = audit.OutcomeSuccess
+
+	switch r := res.(type) {
+	case *pb.EncodedPasswordRes:
+		if r.Error != nil {
+			return audit.OutcomeError, "", r.Error.Message
+		}
+		algorithm = algorithmOf(registry, r.Hash)
+	case *pb.PasswordValidationRes:
+		if in, ok := req.(*pb.PasswordReq); ok {
+			algorithm = algorithmOf(registry, in.Hash)
+		}
+		if r.Error != nil {
+			return audit.OutcomeError, algorithm, r.Error.Message
+		}
+		if r.Valid {
+			outcome = audit.OutcomeValid
+		} else {
+			outcome = audit.OutcomeInvalid
+		}
+	case *pb.ChangeRes:
+		if in, ok := req.(*pb.ChangeReq); ok {
+			algorithm = algorithmOf(registry, in.Hash)
+		}
+		if r.Error != nil {
+			return audit.OutcomeError, algorithm, r.Error.Message
+		}
+		if !r.Valid {
+			return audit.OutcomeInvalid, algorithm, ""
+		}
+		if !r.Accepted {
+			reason = "too similar"
+		}
+	case *pb.VerifyPasswordRes:
+		if r.Error != nil {
+			return audit.OutcomeError, "", r.Error.Message
+		}
+		if r.Valid {
+			outcome = audit.OutcomeValid
+		} else {
+			outcome = audit.OutcomeInvalid
+		}
+		if r.Rehashed {
+			reason = "rehashed"
+		}
+	case *pb.SetPasswordRes:
+		if r.Error != nil {
+			return audit.OutcomeError, "", r.Error.Message
+		}
+	case *pb.DeletePasswordRes:
+		if r.Error != nil {
+			return audit.OutcomeError, "", r.Error.Message
+		}
+		if !r.Deleted {
+			reason = "not found"
+		}
+	case *pb.HistoryRes:
+		if r.Error != nil {
+			return audit.OutcomeError, "", r.Error.Message
+		}
+		if r.Matched {
+			reason = "password reused"
+		}
+	}
+
+	return outcome, algorithm, reason
+}
+
+// algorithmOf returns the strategy name of an encoded hash, only known
+// strategy names are returned to never leak hash content.
+func algorithmOf(registry *hashing.Registry, encoded string) string {
+	if !strings.Contains(encoded, "$") {
+		return ""
+	}
+	s, err := registry.Identify(encoded)
+	if err != nil {
+		return "unknown"
+	}
+	return s.Name()
+}
+
+// auditDecision records an authentication or authorization failure
+func auditDecision(ctx context.Context, logger *logrus.Entry, auditor *audit.Logger, fullMethod, reason string) {
+	if auditor == nil {
+		return
+	}
+
+	r := auditRecord(ctx, fullMethod)
+	r.Outcome = audit.OutcomeDenied
+	r.Reason = reason
+
+	writeAudit(logger, auditor, r)
+}
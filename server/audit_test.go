@@ -0,0 +1,25 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"go.zenithar.org/password/auth"
+)
+
+func TestAuditRecordIdentity(t *testing.T) {
+	ctx := context.Background()
+	if r := auditRecord(ctx, "/password.Password/Encode"); len(r.Identity) > 0 {
+		t.Errorf("anonymous calls should have no identity, got %s", r.Identity)
+	}
+
+	// Callers of different authentication methods never share an identity
+	key := auditRecord(auth.NewContext(ctx, &auth.Identity{Subject: "alice", Method: auth.MethodAPIKey}), "/password.Password/Encode")
+	jwt := auditRecord(auth.NewContext(ctx, &auth.Identity{Subject: "alice", Method: auth.MethodJWT}), "/password.Password/Encode")
+	if key.Identity == jwt.Identity {
+		t.Errorf("identities should be qualified by method, got %s twice", key.Identity)
+	}
+	if key.Identity != auth.MethodAPIKey+":alice" {
+		t.Errorf("identity should be the caller principal, got %s", key.Identity)
+	}
+}
@@ -0,0 +1,189 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+
+	"go.zenithar.org/password/audit"
+	"go.zenithar.org/password/auth"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/tags"
+	"github.com/sirupsen/logrus"
+	"go.zenithar.org/common/web/utils"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+// authState is an immutable authentication and authorization snapshot
+type authState struct {
+	authenticator auth.Authenticator
+	policy        *auth.Policy
+	publicMethods map[string]bool
+	publicPaths   map[string]bool
+}
+
+type authGuard struct {
+	state   atomic.Value
+	logger  *logrus.Entry
+	auditor *audit.Logger
+}
+
+func newAuthGuard(logger *logrus.Entry, authenticator auth.Authenticator, policy *auth.Policy, auditor *audit.Logger, methods, paths []string) *authGuard {
+	g := &authGuard{
+		logger:  logger,
+		auditor: auditor,
+	}
+	g.update(authenticator, policy, methods, paths)
+	return g
+}
+
+// update atomically replaces the guard settings, calls in progress keep
+// using the previous snapshot.
+func (g *authGuard) update(authenticator auth.Authenticator, policy *auth.Policy, methods, paths []string) {
+	st := &authState{
+		authenticator: authenticator,
+		policy:        policy,
+		publicMethods: map[string]bool{},
+		publicPaths:   map[string]bool{},
+	}
+	for _, m := range methods {
+		st.publicMethods[m] = true
+	}
+	for _, p := range paths {
+		st.publicPaths[p] = true
+	}
+	g.state.Store(st)
+}
+
+func (g *authGuard) snapshot() *authState {
+	return g.state.Load().(*authState)
+}
+
+// authenticate resolves the caller identity from the given authorization value
+func (st *authState) authenticate(ctx context.Context, authorization string) (*auth.Identity, error) {
+	token, err := auth.BearerToken(authorization)
+	if err != nil {
+		return nil, err
+	}
+	return st.authenticator.Authenticate(ctx, token)
+}
+
+func (g *authGuard) grpcContext(ctx context.Context, fullMethod string) (context.Context, error) {
+	st := g.snapshot()
+	if st.publicMethods[fullMethod] {
+		return ctx, nil
+	}
+
+	// Authentication
+	newCtx, err := st.authenticateContext(ctx)
+	if err != nil {
+		policyRejections.WithLabelValues(rejectUnauthenticated).Inc()
+		auditDecision(ctx, g.logger, g.auditor, fullMethod, "unauthenticated")
+		return nil, err
+	}
+
+	// Authorization
+	if st.policy != nil {
+		id, _ := auth.FromContext(newCtx)
+		if !st.policy.Allowed(id, fullMethod) {
+			g.denied(newCtx, id, fullMethod)
+			return nil, grpc.Errorf(codes.PermissionDenied, "caller is not allowed to invoke %s", fullMethod)
+		}
+	}
+
+	return newCtx, nil
+}
+
+func (st *authState) authenticateContext(ctx context.Context) (context.Context, error) {
+	if st.authenticator == nil {
+		return ctx, nil
+	}
+
+	var authorization string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md["authorization"]; len(values) > 0 {
+			authorization = values[0]
+		}
+	}
+
+	id, err := st.authenticate(ctx, authorization)
+	if err != nil {
+		return nil, grpc.Errorf(codes.Unauthenticated, "authentication required")
+	}
+
+	grpc_ctxtags.Extract(ctx).Set("auth.sub", id.Subject).Set("auth.method", id.Method)
+
+	return auth.NewContext(ctx, id), nil
+}
+
+func (g *authGuard) denied(ctx context.Context, id *auth.Identity, fullMethod string) {
+	subject := ""
+	if id != nil {
+		subject = id.Subject
+	}
+	policyRejections.WithLabelValues(rejectPermissionDenied).Inc()
+	g.logger.WithFields(logrus.Fields{
+		"grpc.method": fullMethod,
+		"auth.sub":    subject,
+		"peer":        peerAddress(ctx),
+	}).Warn("authorization denied")
+
+	auditDecision(ctx, g.logger, g.auditor, fullMethod, "permission denied")
+}
+
+// UnaryServerInterceptor returns the authentication unary interceptor
+func (g *authGuard) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		newCtx, err := g.grpcContext(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(newCtx, req)
+	}
+}
+
+// StreamServerInterceptor returns the authentication stream interceptor
+func (g *authGuard) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		newCtx, err := g.grpcContext(stream.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &authenticatedStream{ServerStream: stream, ctx: newCtx})
+	}
+}
+
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+// Handler protects the given HTTP handler unless the path is public.
+// Gateway routes are not wrapped, they are enforced by the gRPC interceptor.
+func (g *authGuard) Handler(path string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		st := g.snapshot()
+		if st.authenticator == nil || st.publicPaths[path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		id, err := st.authenticate(r.Context(), r.Header.Get("Authorization"))
+		if err != nil {
+			policyRejections.WithLabelValues(rejectUnauthenticated).Inc()
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			utils.JSONResponse(w, http.StatusUnauthorized, map[string]interface{}{
+				"status":  http.StatusUnauthorized,
+				"message": "authentication required",
+			})
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(auth.NewContext(r.Context(), id)))
+	})
+}
@@ -0,0 +1,104 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"go.zenithar.org/password/auth"
+	pb "go.zenithar.org/password/protocol/password"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestAuthentication(t *testing.T) {
+	keys, err := auth.APIKeys([]auth.APIKey{
+		{Name: "login-service", Hash: auth.HashAPIKey("login-k3y")},
+		{Name: "ops-team", Hash: auth.HashAPIKey("ops-k3y")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy := &auth.Policy{
+		Roles:    map[string][]string{"validator": {"/password.Password/Validate"}},
+		Bindings: map[string][]string{"apikey:login-service": {"validator"}},
+	}
+
+	private := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	addr, stop := startTestServer(t,
+		WithAuthenticator(keys),
+		WithPolicy(policy),
+		WithPublicPaths(auth.DefaultPublicPaths...),
+		WithHandler("/private", private),
+	)
+	defer stop()
+
+	// HTTP handlers
+	httpTests := []struct {
+		path   string
+		token  string
+		status int
+	}{
+		{"/private", "", http.StatusUnauthorized},
+		{"/private", "wrong-k3y", http.StatusUnauthorized},
+		{"/private", "ops-k3y", http.StatusNoContent},
+		{"/livez", "", http.StatusOK},
+	}
+	for _, tt := range httpTests {
+		req, _ := http.NewRequest(http.MethodGet, "http://"+addr+tt.path, nil)
+		if len(tt.token) > 0 {
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if res.StatusCode != tt.status {
+			t.Errorf("GET %s with %q = %d, expected %d", tt.path, tt.token, res.StatusCode, tt.status)
+		}
+		if res.StatusCode == http.StatusUnauthorized && res.Header.Get("WWW-Authenticate") != "Bearer" {
+			t.Errorf("GET %s should challenge for a bearer token", tt.path)
+		}
+	}
+
+	// Gateway calls are enforced by the gRPC interceptor
+	res, err := http.Post("http://"+addr+"/v1/validate", "application/json", strings.NewReader(`{"password":"p","hash":"h"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("gateway call without token = %d, expected %d", res.StatusCode, http.StatusUnauthorized)
+	}
+
+	// gRPC calls
+	client, closeClient := dialTestServer(t, addr)
+	defer closeClient()
+
+	grpcTests := []struct {
+		token string
+		code  codes.Code
+	}{
+		{"", codes.Unauthenticated},
+		{"wrong-k3y", codes.Unauthenticated},
+		{"login-k3y", codes.OK},
+		// Authenticated but not bound to any role
+		{"ops-k3y", codes.PermissionDenied},
+	}
+	for _, tt := range grpcTests {
+		ctx := context.Background()
+		if len(tt.token) > 0 {
+			ctx = metadata.NewOutgoingContext(ctx, metadata.Pairs("authorization", "Bearer "+tt.token))
+		}
+		_, err := client.Validate(ctx, &pb.PasswordReq{Password: "password", Hash: "hash"})
+		if grpc.Code(err) != tt.code {
+			t.Errorf("Validate with %q = %v, expected %s", tt.token, err, tt.code)
+		}
+	}
+}
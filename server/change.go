@@ -0,0 +1,129 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"go.zenithar.org/password/hashing"
+	pb "go.zenithar.org/password/protocol/password"
+	"go.zenithar.org/password/similarity"
+	"go.zenithar.org/password/utils/secret"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+func (m *myService) CheckChange(c context.Context, s *pb.ChangeReq) (*pb.ChangeRes, error) {
+	res := &pb.ChangeRes{}
+
+	// Check mandatory fields
+	if len(strings.TrimSpace(s.OldPassword)) == 0 || len(strings.TrimSpace(s.NewPassword)) == 0 {
+		policyRejections.WithLabelValues(rejectEmptyPassword).Inc()
+		res.Error = &pb.Error{
+			Code:    http.StatusPreconditionFailed,
+			Message: "Old and new password values are mandatory !",
+		}
+		return res, nil
+	}
+	if len(strings.TrimSpace(s.Hash)) == 0 {
+		policyRejections.WithLabelValues(rejectEmptyHash).Inc()
+		res.Error = &pb.Error{
+			Code:    http.StatusPreconditionFailed,
+			Message: "Hash value is mandatory !",
+		}
+		return res, nil
+	}
+	if err := m.checkLengths(s.OldPassword, s.Hash); err != nil {
+		return nil, err
+	}
+	if err := m.checkLengths(s.NewPassword); err != nil {
+		return nil, err
+	}
+
+	// Resolve hash strategy
+	strategy, err := m.registry.Identify(s.Hash)
+	if err != nil {
+		changeChecks.WithLabelValues("malformed").Inc()
+		res.Error = &pb.Error{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		}
+		return res, nil
+	}
+
+	// Old password must match before comparing passwords
+	v, werr := m.verify(c, strategy, s.Hash, s.OldPassword)
+	if werr != nil {
+		m.rejected(werr)
+		return nil, werr
+	}
+	if v.err == hashing.ErrInvalidPassword {
+		return nil, m.invalidPassword()
+	}
+	if v.err != nil {
+		changeChecks.WithLabelValues("malformed").Inc()
+		res.Error = &pb.Error{
+			Code:    http.StatusBadRequest,
+			Message: v.err.Error(),
+		}
+		return res, nil
+	}
+	if !v.valid {
+		changeChecks.WithLabelValues("invalid").Inc()
+		return res, nil
+	}
+
+	// Compare passwords in wiped copies, normalized with the current profile
+	// as the new password would be hashed. An old password the profile
+	// rejects was accepted by the profile of its hash, it is compared as is.
+	next, err := m.normalizedRunes(s.NewPassword)
+	if err == hashing.ErrInvalidPassword {
+		return nil, m.invalidPassword()
+	}
+	if err != nil {
+		return nil, grpc.Errorf(codes.Internal, "password can't be normalized")
+	}
+	defer similarity.Wipe(next)
+	old, err := m.normalizedRunes(s.OldPassword)
+	if err != nil {
+		old = similarity.Runes(s.OldPassword)
+	}
+	defer similarity.Wipe(old)
+	result := m.changePolicy.Check(old, next)
+
+	// Return result
+	res.Valid = true
+	res.Accepted = !result.Rejected
+	res.Distance = result.Distance
+	res.Transformation = result.Transformation
+	if res.Accepted {
+		changeChecks.WithLabelValues("accepted").Inc()
+	} else {
+		changeChecks.WithLabelValues("similar").Inc()
+	}
+
+	return res, nil
+}
+
+// normalizedRunes returns the password normalized with the current profile,
+// intermediate buffers are wiped.
+func (m *myService) normalizedRunes(password string) ([]rune, error) {
+	buf := secret.FromString(password, m.lockMemory)
+	defer buf.Destroy()
+
+	normalized, err := hashing.Normalize(m.normalization, buf.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	defer secret.Wipe(normalized)
+
+	r := make([]rune, 0, utf8.RuneCount(normalized))
+	for b := normalized; len(b) > 0; {
+		c, size := utf8.DecodeRune(b)
+		r = append(r, c)
+		b = b[size:]
+	}
+	return r, nil
+}
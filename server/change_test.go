@@ -0,0 +1,76 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"go.zenithar.org/password/hashing"
+	pb "go.zenithar.org/password/protocol/password"
+	"go.zenithar.org/password/similarity"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+func TestCheckChange(t *testing.T) {
+	client, stop := startFakeServer(t, []*fakeStrategy{{name: "plain"}}, WithChangePolicy(similarity.DefaultPolicy))
+	defer stop()
+
+	tests := []struct {
+		old, next      string
+		valid          bool
+		accepted       bool
+		transformation string
+	}{
+		{"summer2017", "summer2018", true, false, similarity.TransformationIncrement},
+		{"summer2017", "Summer2017!", true, false, similarity.TransformationAppend},
+		{"summer2017", "correct horse battery staple", true, true, ""},
+		{"winter2017", "correct horse battery staple", false, false, ""},
+	}
+	for _, tt := range tests {
+		res, err := client.CheckChange(context.Background(), &pb.ChangeReq{OldPassword: tt.old, Hash: "plain$summer2017", NewPassword: tt.next})
+		if err != nil || res.Error != nil {
+			t.Fatalf("CheckChange failed: %v %v", err, res.GetError())
+		}
+		if res.Valid != tt.valid || res.Accepted != tt.accepted || res.Transformation != tt.transformation {
+			t.Errorf("CheckChange(%q, %q) = %v, expected valid %v accepted %v transformation %q", tt.old, tt.next, res, tt.valid, tt.accepted, tt.transformation)
+		}
+	}
+}
+
+func TestCheckChangeNormalization(t *testing.T) {
+	client, stop := startFakeServer(t, []*fakeStrategy{{name: "plain"}}, WithChangePolicy(similarity.DefaultPolicy), WithNormalization(hashing.NormalizationNFC))
+	defer stop()
+
+	// Old password hashed without normalization, in NFD form
+	const old = "cre\u0300me brule\u0301e 2017"
+
+	tests := []struct {
+		next           string
+		accepted       bool
+		distance       float64
+		transformation string
+	}{
+		{"cr\u00e8me brul\u00e9e 2017", false, 0, ""},
+		{"cr\u00e8me brul\u00e9e 2018", false, 1.0 / 17, similarity.TransformationIncrement},
+		{"correct horse battery staple", true, -1, ""},
+	}
+	for _, tt := range tests {
+		res, err := client.CheckChange(context.Background(), &pb.ChangeReq{OldPassword: old, Hash: "plain$" + old, NewPassword: tt.next})
+		if err != nil || res.Error != nil {
+			t.Fatalf("CheckChange failed: %v %v", err, res.GetError())
+		}
+		if !res.Valid || res.Accepted != tt.accepted || res.Transformation != tt.transformation {
+			t.Errorf("CheckChange(%q) = %v, expected accepted %v transformation %q", tt.next, res, tt.accepted, tt.transformation)
+		}
+		if tt.distance >= 0 && res.Distance != tt.distance {
+			t.Errorf("CheckChange(%q) distance = %v, expected %v", tt.next, res.Distance, tt.distance)
+		}
+	}
+
+	// New passwords the profile rejects can't be compared
+	_, err := client.CheckChange(context.Background(), &pb.ChangeReq{OldPassword: old, Hash: "plain$" + old, NewPassword: "\xffpassword"})
+	if grpc.Code(err) != codes.InvalidArgument {
+		t.Errorf("invalid new password should be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,248 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.zenithar.org/password/hashing"
+	pb "go.zenithar.org/password/protocol/password"
+	"go.zenithar.org/password/store"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+var (
+	errStoreDisabled    = grpc.Errorf(codes.Unimplemented, "credential store is disabled")
+	errStoreUnavailable = grpc.Errorf(codes.Unavailable, "credential store is unavailable")
+)
+
+func (m *myService) SetPassword(c context.Context, s *pb.CredentialReq) (*pb.SetPasswordRes, error) {
+	res := &pb.SetPasswordRes{}
+	if m.store == nil {
+		return nil, errStoreDisabled
+	}
+
+	// Check mandatory fields
+	if perr := checkCredential(s); perr != nil {
+		res.Error = perr
+		return res, nil
+	}
+	if err := checkSubject(s.Subject); err != nil {
+		return nil, err
+	}
+	if err := m.checkLengths(s.Password); err != nil {
+		return nil, err
+	}
+
+	// Hash given password
+	hash, perr, err := m.encode(c, s.Password)
+	if err != nil {
+		return nil, err
+	}
+	if perr != nil {
+		res.Error = perr
+		return res, nil
+	}
+
+	// Replace the stored credential
+	now := time.Now().UTC()
+	if err := m.store.Update(c, s.Subject, func(r *store.Record) error {
+		if r.CreatedAt.IsZero() {
+			r.CreatedAt = now
+		}
+		r.Hash = hash
+		r.ChangedAt = now
+		r.FailedCount = 0
+		return nil
+	}); err != nil {
+		return nil, m.storeError(c, "set", err)
+	}
+
+	storeOperations.WithLabelValues("set", "success").Inc()
+	return res, nil
+}
+
+func (m *myService) VerifyPassword(c context.Context, s *pb.CredentialReq) (*pb.VerifyPasswordRes, error) {
+	res := &pb.VerifyPasswordRes{}
+	if m.store == nil {
+		return nil, errStoreDisabled
+	}
+	if m.validateFloor > 0 {
+		defer m.wait(c, time.Now().Add(m.validateFloor))
+	}
+
+	// Check mandatory fields
+	if perr := checkCredential(s); perr != nil {
+		res.Error = perr
+		return res, nil
+	}
+	if err := checkSubject(s.Subject); err != nil {
+		return nil, err
+	}
+	if err := m.checkLengths(s.Password); err != nil {
+		return nil, err
+	}
+
+	// Unknown subjects are verified against the dummy hash, when enabled
+	record, err := m.store.Get(c, s.Subject)
+	if err == store.ErrNotFound {
+		storeOperations.WithLabelValues("verify", "unknown").Inc()
+		if len(m.dummyHash) == 0 {
+			return res, nil
+		}
+		strategy, err := m.registry.Identify(m.dummyHash)
+		if err != nil {
+			return nil, grpc.Errorf(codes.Internal, "dummy hash is not supported")
+		}
+		v, werr := m.verify(c, strategy, m.dummyHash, s.Password)
+		if werr != nil {
+			m.rejected(werr)
+			return nil, werr
+		}
+		// Invalid passwords are rejected as for known subjects
+		if v.err == hashing.ErrInvalidPassword {
+			return nil, m.invalidPassword()
+		}
+		return res, nil
+	}
+	if err != nil {
+		return nil, m.storeError(c, "verify", err)
+	}
+
+	// Hash given password
+	strategy, err := m.registry.Identify(record.Hash)
+	if err != nil {
+		storeOperations.WithLabelValues("verify", "error").Inc()
+		return nil, grpc.Errorf(codes.Internal, "stored hash is not supported")
+	}
+	v, werr := m.verify(c, strategy, record.Hash, s.Password)
+	if werr != nil {
+		m.rejected(werr)
+		return nil, werr
+	}
+	if v.err == hashing.ErrInvalidPassword {
+		return nil, m.invalidPassword()
+	}
+	if v.err != nil {
+		storeOperations.WithLabelValues("verify", "error").Inc()
+		return nil, grpc.Errorf(codes.Internal, "stored hash is malformed")
+	}
+
+	// Failed verifications are counted for lockout policies
+	if !v.valid {
+		if err := m.store.Update(c, s.Subject, func(r *store.Record) error {
+			if r.CreatedAt.IsZero() {
+				return store.ErrNotFound
+			}
+			r.FailedCount++
+			return nil
+		}); err != nil && err != store.ErrNotFound {
+			return nil, m.storeError(c, "verify", err)
+		}
+		storeOperations.WithLabelValues("verify", "invalid").Inc()
+		return res, nil
+	}
+
+	// Outdated hashes are replaced, unless the password changed meanwhile.
+	// Encoding failures keep the stored hash.
+	var rehash string
+	profile, encoded, _ := hashing.SplitNormalization(record.Hash)
+	if m.needsRehash(strategy, profile, encoded) {
+		needsRehash.WithLabelValues(strategy.Name()).Inc()
+		rehash, _, _ = m.encode(c, s.Password)
+	}
+
+	now := time.Now().UTC()
+	if err := m.store.Update(c, s.Subject, func(r *store.Record) error {
+		if r.CreatedAt.IsZero() {
+			return store.ErrNotFound
+		}
+		r.VerifiedAt = now
+		r.FailedCount = 0
+		if len(rehash) > 0 && r.Hash == record.Hash {
+			r.Hash = rehash
+			res.Rehashed = true
+		}
+		return nil
+	}); err != nil && err != store.ErrNotFound {
+		return nil, m.storeError(c, "verify", err)
+	}
+
+	res.Valid = true
+	storeOperations.WithLabelValues("verify", "valid").Inc()
+	if res.Rehashed {
+		storeOperations.WithLabelValues("verify", "rehashed").Inc()
+	}
+
+	return res, nil
+}
+
+func (m *myService) DeletePassword(c context.Context, s *pb.SubjectReq) (*pb.DeletePasswordRes, error) {
+	res := &pb.DeletePasswordRes{}
+	if m.store == nil {
+		return nil, errStoreDisabled
+	}
+
+	// Check mandatory fields
+	if len(strings.TrimSpace(s.Subject)) == 0 {
+		res.Error = &pb.Error{
+			Code:    http.StatusPreconditionFailed,
+			Message: "Subject value is mandatory !",
+		}
+		return res, nil
+	}
+	if err := checkSubject(s.Subject); err != nil {
+		return nil, err
+	}
+
+	switch err := m.store.Delete(c, s.Subject); err {
+	case nil:
+		res.Deleted = true
+	case store.ErrNotFound:
+	default:
+		return nil, m.storeError(c, "delete", err)
+	}
+
+	storeOperations.WithLabelValues("delete", "success").Inc()
+	return res, nil
+}
+
+// checkCredential returns a response error when subject or password is
+// missing
+func checkCredential(s *pb.CredentialReq) *pb.Error {
+	if len(strings.TrimSpace(s.Subject)) == 0 {
+		return &pb.Error{
+			Code:    http.StatusPreconditionFailed,
+			Message: "Subject value is mandatory !",
+		}
+	}
+	if len(strings.TrimSpace(s.Password)) == 0 {
+		policyRejections.WithLabelValues(rejectEmptyPassword).Inc()
+		return &pb.Error{
+			Code:    http.StatusPreconditionFailed,
+			Message: "Password value is mandatory !",
+		}
+	}
+	return nil
+}
+
+// checkSubject rejects subjects exceeding the store limit, before any hashing
+// work.
+func checkSubject(subject string) error {
+	if len(subject) > store.MaxSubjectLength {
+		policyRejections.WithLabelValues(rejectSubjectTooLong).Inc()
+		return grpc.Errorf(codes.InvalidArgument, "subject exceeds %d bytes", store.MaxSubjectLength)
+	}
+	return nil
+}
+
+// storeError logs a storage failure, its details are hidden from callers
+func (m *myService) storeError(ctx context.Context, operation string, err error) error {
+	storeOperations.WithLabelValues(operation, "error").Inc()
+	grpc_logrus.Extract(ctx).WithError(err).WithField("store.operation", operation).Error("Credential store failure")
+	return errStoreUnavailable
+}
@@ -0,0 +1,157 @@
+package server
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.zenithar.org/password/hashing"
+	pb "go.zenithar.org/password/protocol/password"
+	"go.zenithar.org/password/store"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+func TestCredentialStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "credentials")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := store.BoltStore(filepath.Join(dir, "credentials.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	strategy := &fakeStrategy{name: "plain"}
+
+	ctx := context.Background()
+	client, stop := startFakeServer(t, []*fakeStrategy{strategy}, WithCredentialStore(s))
+
+	set, err := client.SetPassword(ctx, &pb.CredentialReq{Subject: "alice", Password: "correct horse"})
+	if err != nil || set.Error != nil {
+		t.Fatalf("SetPassword failed: %v %v", err, set.GetError())
+	}
+	set, err = client.SetPassword(ctx, &pb.CredentialReq{Subject: "alice"})
+	if err != nil || set.Error == nil {
+		t.Errorf("SetPassword without password should be rejected, got %v %v", err, set.GetError())
+	}
+
+	// Failed verifications are counted, then reset
+	res, err := client.VerifyPassword(ctx, &pb.CredentialReq{Subject: "alice", Password: "battery staple"})
+	if err != nil || res.Valid {
+		t.Fatalf("VerifyPassword of wrong password should be invalid, got %v %v", err, res)
+	}
+	if r, _ := s.Get(ctx, "alice"); r == nil || r.FailedCount != 1 {
+		t.Errorf("failed verification should be counted, got %+v", r)
+	}
+	res, err = client.VerifyPassword(ctx, &pb.CredentialReq{Subject: "alice", Password: "correct horse"})
+	if err != nil || !res.Valid || res.Rehashed {
+		t.Fatalf("VerifyPassword of right password should be valid, got %v %v", err, res)
+	}
+	if r, _ := s.Get(ctx, "alice"); r == nil || r.FailedCount != 0 || r.VerifiedAt.IsZero() {
+		t.Errorf("successful verification should reset counter, got %+v", r)
+	}
+
+	// Unknown subjects are never valid
+	res, err = client.VerifyPassword(ctx, &pb.CredentialReq{Subject: "bob", Password: "correct horse"})
+	if err != nil || res.Valid {
+		t.Errorf("VerifyPassword of unknown subject should be invalid, got %v %v", err, res)
+	}
+
+	stop()
+
+	// Outdated hashes are replaced on successful verification
+	client, stop = startFakeServer(t, []*fakeStrategy{strategy}, WithNormalization(hashing.NormalizationNFC), WithCredentialStore(s))
+	defer stop()
+
+	res, err = client.VerifyPassword(ctx, &pb.CredentialReq{Subject: "alice", Password: "correct horse"})
+	if err != nil || !res.Valid || !res.Rehashed {
+		t.Fatalf("VerifyPassword should rehash outdated hash, got %v %v", err, res)
+	}
+	if r, _ := s.Get(ctx, "alice"); r == nil || !strings.HasPrefix(r.Hash, "nfc:plain$") {
+		t.Errorf("stored hash should be replaced, got %+v", r)
+	}
+
+	del, err := client.DeletePassword(ctx, &pb.SubjectReq{Subject: "alice"})
+	if err != nil || !del.Deleted {
+		t.Fatalf("DeletePassword failed: %v %v", err, del)
+	}
+	del, err = client.DeletePassword(ctx, &pb.SubjectReq{Subject: "alice"})
+	if err != nil || del.Deleted {
+		t.Errorf("DeletePassword of unknown subject should not delete, got %v %v", err, del)
+	}
+}
+
+func TestCredentialStoreDisabled(t *testing.T) {
+	addr, stop := startTestServer(t)
+	defer stop()
+	client, closeClient := dialTestServer(t, addr)
+	defer closeClient()
+
+	_, err := client.VerifyPassword(context.Background(), &pb.CredentialReq{Subject: "alice", Password: "correct horse"})
+	if grpc.Code(err) != codes.Unimplemented {
+		t.Errorf("VerifyPassword without store should be unimplemented, got %v", err)
+	}
+}
+
+func TestCredentialStoreInputs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "credentials")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := store.BoltStore(filepath.Join(dir, "credentials.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	ctx := context.Background()
+	client, stop := startFakeServer(t, []*fakeStrategy{{name: "plain"}}, WithNormalization(hashing.NormalizationNFC), WithDummyValidation(true), WithCredentialStore(s))
+	defer stop()
+
+	if _, err := client.SetPassword(ctx, &pb.CredentialReq{Subject: "alice", Password: "correct horse"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Invalid passwords are rejected alike for known and unknown subjects
+	for _, subject := range []string{"alice", "bob"} {
+		_, err := client.VerifyPassword(ctx, &pb.CredentialReq{Subject: subject, Password: "\xffpassword"})
+		if grpc.Code(err) != codes.InvalidArgument {
+			t.Errorf("%s: invalid password should be rejected, got %v", subject, err)
+		}
+	}
+
+	// Subjects are bounded before reaching the store
+	long := strings.Repeat("a", store.MaxSubjectLength+1)
+	if _, err := client.SetPassword(ctx, &pb.CredentialReq{Subject: long[1:], Password: "correct horse"}); err != nil {
+		t.Errorf("subject at the limit should be accepted, got %v", err)
+	}
+	calls := map[string]func() error{
+		"set": func() error {
+			_, err := client.SetPassword(ctx, &pb.CredentialReq{Subject: long, Password: "correct horse"})
+			return err
+		},
+		"verify": func() error {
+			_, err := client.VerifyPassword(ctx, &pb.CredentialReq{Subject: long, Password: "correct horse"})
+			return err
+		},
+		"delete": func() error {
+			_, err := client.DeletePassword(ctx, &pb.SubjectReq{Subject: long})
+			return err
+		},
+	}
+	for name, call := range calls {
+		if err := call(); grpc.Code(err) != codes.InvalidArgument {
+			t.Errorf("%s: long subject should be rejected, got %v", name, err)
+		}
+	}
+}
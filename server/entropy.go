@@ -0,0 +1,68 @@
+package server
+
+import (
+	"sync"
+
+	"go.zenithar.org/password/hashing"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ReasonEntropy is the readiness condition raised when the salt source fails
+const ReasonEntropy = "entropy"
+
+// entropyMonitor reports salt source failures to metrics and readiness. The
+// readiness condition is cleared by the next valid salt.
+type entropyMonitor struct {
+	readiness *readiness
+	logger    *logrus.Entry
+
+	mu        sync.Mutex
+	failing   bool
+	failures  int
+	lastError string
+}
+
+func (m *entropyMonitor) observe(err error) {
+	if err == nil {
+		m.mu.Lock()
+		recovered := m.failing
+		m.failing = false
+		m.mu.Unlock()
+
+		if recovered {
+			m.readiness.set(ReasonEntropy, true)
+		}
+		return
+	}
+
+	test := "unknown"
+	if e, ok := err.(*hashing.EntropyError); ok {
+		test = e.Test
+	}
+	saltHealthFailures.WithLabelValues(test).Inc()
+	m.logger.WithError(err).Error("Salt source health test failed")
+
+	m.mu.Lock()
+	m.failing = true
+	m.failures++
+	m.lastError = err.Error()
+	m.mu.Unlock()
+
+	m.readiness.set(ReasonEntropy, false)
+}
+
+// details returns the salt source report published by health endpoints
+func (m *entropyMonitor) details() map[string]interface{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	res := map[string]interface{}{
+		"failing":  m.failing,
+		"failures": m.failures,
+	}
+	if len(m.lastError) > 0 {
+		res["last_error"] = m.lastError
+	}
+	return res
+}
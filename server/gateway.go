@@ -2,39 +2,41 @@ package server
 
 import (
 	"context"
-	"net"
+	"errors"
+	"io"
 	"net/http"
-	"time"
 
 	pb "go.zenithar.org/password/protocol/password"
 
 	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	opentracing "github.com/opentracing/opentracing-go"
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 )
 
-func prepareGateway(ctx context.Context, socketPath string) (http.Handler, error) {
-	// gRPC dialup options
+func prepareGateway(ctx context.Context, logger *logrus.Entry, tracer opentracing.Tracer, dialer gatewayDialer) (http.Handler, error) {
+	// gRPC dialup options, connection is established lazily
 	opts := []grpc.DialOption{
-		grpc.WithTimeout(10 * time.Second),
-		grpc.WithBlock(),
-		grpc.WithInsecure(), // Unix socket are insecure by default
-		grpc.WithDialer(func(addr string, timeout time.Duration) (net.Conn, error) {
-			return net.DialTimeout("unix", socketPath, timeout)
-		}),
+		grpc.WithInsecure(), // Internal transports are not exposed
+		grpc.WithDialer(dialer),
 	}
 
 	// gRPC dialup options
 	conn, err := grpc.DialContext(ctx, "", opts...)
 	if err != nil {
-		logrus.WithError(err).Error("fail to dial")
+		logger.WithError(err).Error("fail to dial")
 		return nil, err
 	}
 
 	// changes json serializer to include empty fields with default values
 	gwMux := runtime.NewServeMux(
-		runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.JSONPb{OrigName: true, EmitDefaults: true}),
-		runtime.WithProtoErrorHandler(runtime.DefaultHTTPProtoErrorHandler),
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, &redactingMarshaler{&runtime.JSONPb{OrigName: true, EmitDefaults: true}}),
+		runtime.WithProtoErrorHandler(gatewayErrorHandler),
+		runtime.WithOutgoingHeaderMatcher(gatewayHeaderMatcher),
+		runtime.WithMetadata(func(ctx context.Context, r *http.Request) metadata.MD {
+			return metadata.Join(traceMetadata(tracer)(ctx, r), requestIDMetadata(ctx, r))
+		}),
 	)
 
 	// Register Gateway endpoints
@@ -45,3 +47,29 @@ func prepareGateway(ctx context.Context, socketPath string) (http.Handler, error
 
 	return gwMux, nil
 }
+
+// errMalformedBody replaces request decoding errors, which may echo body
+// fragments such as passwords
+var errMalformedBody = errors.New("malformed request body")
+
+// redactingMarshaler hides request decoding error details
+type redactingMarshaler struct {
+	runtime.Marshaler
+}
+
+func (m *redactingMarshaler) Unmarshal(data []byte, v interface{}) error {
+	if err := m.Marshaler.Unmarshal(data, v); err != nil {
+		return errMalformedBody
+	}
+	return nil
+}
+
+func (m *redactingMarshaler) NewDecoder(r io.Reader) runtime.Decoder {
+	d := m.Marshaler.NewDecoder(r)
+	return runtime.DecoderFunc(func(v interface{}) error {
+		if err := d.Decode(v); err != nil {
+			return errMalformedBody
+		}
+		return nil
+	})
+}
@@ -3,6 +3,7 @@ package server
 import (
 	"context"
 
+	"go.zenithar.org/password/hashing"
 	pb "go.zenithar.org/password/protocol/password"
 
 	"github.com/grpc-ecosystem/go-grpc-middleware"
@@ -11,7 +12,6 @@ import (
 	"github.com/grpc-ecosystem/go-grpc-middleware/tags"
 	"github.com/grpc-ecosystem/go-grpc-middleware/tracing/opentracing"
 	"github.com/grpc-ecosystem/go-grpc-prometheus"
-	opentracing "github.com/opentracing/opentracing-go"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/health"
 	healthpb "google.golang.org/grpc/health/grpc_health_v1"
@@ -20,50 +20,89 @@ import (
 
 // -----------------------------------------------------------------------------
 
-func prepareGRPC(context context.Context) (*grpc.Server, error) {
+func prepareGRPC(context context.Context, ms *MicroServer, registry *hashing.Registry) (*grpc.Server, error) {
 	// gRPC Server settings
 	var sopts []grpc.ServerOption
 
 	// gRPC middlewares
-	sopts = append(sopts, grpc.StreamInterceptor(
-		grpc_middleware.ChainStreamServer(
-			grpc_ctxtags.StreamServerInterceptor(),
-			grpc_opentracing.StreamServerInterceptor(),
-			grpc_recovery.StreamServerInterceptor(
-				grpc_recovery.WithRecoveryHandler(recoveryFunc)),
-			grpc_logrus.StreamServerInterceptor(logrusEntry),
-		)),
-		grpc.UnaryInterceptor(
-			grpc_middleware.ChainUnaryServer(
-				grpc_ctxtags.UnaryServerInterceptor(),
-				grpc_opentracing.UnaryServerInterceptor(
-					grpc_opentracing.WithTracer(opentracing.GlobalTracer()),
-				),
-				grpc_prometheus.UnaryServerInterceptor,
-				grpc_recovery.UnaryServerInterceptor(
-					grpc_recovery.WithRecoveryHandler(recoveryFunc)),
-				grpc_logrus.UnaryServerInterceptor(logrusEntry),
-			),
-		))
+	streamInterceptors := []grpc.StreamServerInterceptor{
+		grpc_ctxtags.StreamServerInterceptor(),
+		peerStreamServerInterceptor(ms.trustedProxies),
+		requestIDStreamServerInterceptor(),
+		grpc_opentracing.StreamServerInterceptor(
+			grpc_opentracing.WithTracer(ms.tracer),
+		),
+	}
+	unaryInterceptors := []grpc.UnaryServerInterceptor{
+		grpc_ctxtags.UnaryServerInterceptor(),
+		peerUnaryServerInterceptor(ms.trustedProxies),
+		requestIDUnaryServerInterceptor(),
+		grpc_opentracing.UnaryServerInterceptor(
+			grpc_opentracing.WithTracer(ms.tracer),
+		),
+	}
+	if ms.metrics {
+		unaryInterceptors = append(unaryInterceptors, grpc_prometheus.UnaryServerInterceptor)
+	}
+
+	streamInterceptors = append(streamInterceptors,
+		grpc_recovery.StreamServerInterceptor(
+			grpc_recovery.WithRecoveryHandler(recoveryFunc(ms.logger))),
+		grpc_logrus.StreamServerInterceptor(ms.logger),
+		ms.guard.StreamServerInterceptor(),
+	)
+	unaryInterceptors = append(unaryInterceptors,
+		grpc_recovery.UnaryServerInterceptor(
+			grpc_recovery.WithRecoveryHandler(recoveryFunc(ms.logger))),
+		loggingUnaryServerInterceptor(ms.logger, ms.validateLogSampling),
+		ms.guard.UnaryServerInterceptor(),
+		auditUnaryServerInterceptor(ms.logger, ms.auditor, registry),
+		redactUnaryServerInterceptor(),
+	)
+
+	// Embedder middlewares
+	streamInterceptors = append(streamInterceptors, ms.streamInterceptors...)
+	unaryInterceptors = append(unaryInterceptors, ms.unaryInterceptors...)
+
+	sopts = append(sopts,
+		grpc.MaxRecvMsgSize(ms.maxMessageSize()),
+		grpc.StreamInterceptor(grpc_middleware.ChainStreamServer(streamInterceptors...)),
+		grpc.UnaryInterceptor(grpc_middleware.ChainUnaryServer(unaryInterceptors...)),
+	)
 	s := grpc.NewServer(sopts...)
 
 	// Password service
-	pb.RegisterPasswordServer(s, newServer())
+	svc, err := newServer(ms, registry)
+	if err != nil {
+		return nil, err
+	}
+	pb.RegisterPasswordServer(s, svc)
+
+	// Additional services
+	for _, register := range ms.services {
+		register(s)
+	}
 
 	// Prometheus
-	grpc_prometheus.Register(s)
+	if ms.metrics {
+		grpc_prometheus.Register(s)
+	}
 
 	// Health
 	healthServer := health.NewServer()
 	healthpb.RegisterHealthServer(s, healthServer)
-	healthServer.SetServingStatus("Password", healthpb.HealthCheckResponse_SERVING)
+	ms.readiness.attach(healthServer)
 
 	// Reflection
-	reflection.Register(s)
+	if ms.reflection {
+		reflection.Register(s)
+	}
 
 	return s, nil
 }
 
+// The gRPC library logger is global, it cannot follow the logger of each
+// server.
 func init() {
 	grpc_logrus.ReplaceGrpcLogger(logrusEntry)
 }
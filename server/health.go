@@ -0,0 +1,201 @@
+package server
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"go.zenithar.org/password/hashing"
+
+	"github.com/sirupsen/logrus"
+	"go.zenithar.org/common/web/utils"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+const (
+	// HealthLiveness is the gRPC health service reporting the process liveness
+	HealthLiveness = "liveness"
+	// HealthReadiness is the gRPC health service reporting the traffic readiness
+	HealthReadiness = "readiness"
+
+	// ReasonShutdown is the readiness condition raised while shutting down
+	ReasonShutdown = "shutdown"
+	// ReasonSelfTest is the readiness condition raised until self-tests pass
+	ReasonSelfTest = "selftest"
+)
+
+// readinessServices lists gRPC health services following the readiness, the
+// empty name is the overall server status.
+var readinessServices = []string{"", "Password", HealthReadiness}
+
+// readiness tracks conditions preventing the server from receiving traffic
+type readiness struct {
+	mu      sync.Mutex
+	reasons map[string]bool
+	health  *health.Server
+}
+
+func newReadiness() *readiness {
+	return &readiness{
+		reasons: map[string]bool{},
+	}
+}
+
+// attach publishes the readiness to the given gRPC health server
+func (r *readiness) attach(h *health.Server) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.health = h
+	if h != nil {
+		h.SetServingStatus(HealthLiveness, healthpb.HealthCheckResponse_SERVING)
+	}
+	r.publish()
+}
+
+// set raises or clears the given condition
+func (r *readiness) set(reason string, ready bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if ready {
+		delete(r.reasons, reason)
+	} else {
+		r.reasons[reason] = true
+	}
+	r.publish()
+}
+
+// status returns the readiness and the sorted raised conditions
+func (r *readiness) status() (bool, []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	reasons := make([]string, 0, len(r.reasons))
+	for reason := range r.reasons {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+
+	return len(reasons) == 0, reasons
+}
+
+func (r *readiness) publish() {
+	if r.health == nil {
+		return
+	}
+
+	status := healthpb.HealthCheckResponse_SERVING
+	if len(r.reasons) > 0 {
+		status = healthpb.HealthCheckResponse_NOT_SERVING
+	}
+	for _, service := range readinessServices {
+		r.health.SetServingStatus(service, status)
+	}
+}
+
+// SetReadiness raises (ready is false) or clears (ready is true) a condition
+// identified by reason. The server accepts traffic once all conditions are
+// cleared, liveness is not affected.
+func (ms *MicroServer) SetReadiness(reason string, ready bool) {
+	ms.readiness.set(reason, ready)
+}
+
+// Ready returns the server readiness and the raised conditions
+func (ms *MicroServer) Ready() (bool, []string) {
+	return ms.readiness.status()
+}
+
+// -----------------------------------------------------------------------------
+
+// selfTest runs hashing self-tests and holds their last results
+type selfTest struct {
+	readiness *readiness
+	logger    *logrus.Entry
+
+	mu      sync.Mutex
+	status  string
+	results []hashing.TestResult
+}
+
+// start withholds readiness and runs self-tests in background
+func (st *selfTest) start(registry *hashing.Registry) {
+	st.readiness.set(ReasonSelfTest, false)
+	st.report("running", nil)
+
+	go st.run(registry)
+}
+
+func (st *selfTest) run(registry *hashing.Registry) {
+	results, err := hashing.SelfTest(registry)
+	if err != nil {
+		for _, res := range results {
+			if len(res.Error) > 0 {
+				st.logger.WithField("selftest", res.Name).Error(res.Error)
+			}
+		}
+		st.logger.WithError(err).Error("Self-tests failed, server will not report ready")
+		st.report("failed", results)
+		return
+	}
+
+	st.report("passed", results)
+	st.readiness.set(ReasonSelfTest, true)
+}
+
+func (st *selfTest) report(status string, results []hashing.TestResult) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.status = status
+	st.results = results
+}
+
+// details returns the self-test report published by health endpoints
+func (st *selfTest) details() map[string]interface{} {
+	if st == nil {
+		return nil
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	return map[string]interface{}{
+		"status":  st.status,
+		"results": st.results,
+	}
+}
+
+func livenessHandler(ms *MicroServer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		res := map[string]interface{}{
+			"status":    "OK",
+			"timestamp": time.Now().UTC().Unix(),
+			"entropy":   ms.entropy.details(),
+		}
+		if details := ms.selfTest.details(); details != nil {
+			res["selftest"] = details
+		}
+		utils.JSONResponse(w, http.StatusOK, res)
+	})
+}
+
+func readinessHandler(rd *readiness) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ready, reasons := rd.status()
+		if !ready {
+			utils.JSONResponse(w, http.StatusServiceUnavailable, map[string]interface{}{
+				"status":    "NOT_SERVING",
+				"reasons":   reasons,
+				"timestamp": time.Now().UTC().Unix(),
+			})
+			return
+		}
+
+		utils.JSONResponse(w, http.StatusOK, map[string]interface{}{
+			"status":    "OK",
+			"timestamp": time.Now().UTC().Unix(),
+		})
+	})
+}
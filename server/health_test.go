@@ -0,0 +1,195 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"go.zenithar.org/password/hashing"
+	pb "go.zenithar.org/password/protocol/password"
+
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// serveShutdownTest serves a microserver whose shutdown is driven by the test
+func serveShutdownTest(t *testing.T, opts ...Option) (*MicroServer, string, chan error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts = append([]Option{WithSelfTest(false), WithMetrics(false)}, opts...)
+	ms := New(l.Addr().String(), l, opts...)
+	served := make(chan error, 1)
+	go func() { served <- ms.Serve(l) }()
+
+	return ms, l.Addr().String(), served
+}
+
+// shutdownClient does not keep idle connections, that would hold the HTTP
+// shutdown until they time out
+var shutdownClient = &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+
+func shutdownAsync(ms *MicroServer) chan error {
+	done := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		done <- ms.Shutdown(ctx)
+	}()
+	return done
+}
+
+func TestShutdownReadiness(t *testing.T) {
+	const drainDelay = 500 * time.Millisecond
+
+	ms, addr, served := serveShutdownTest(t, WithDrainDelay(drainDelay))
+	conn, err := grpc.Dial(addr, grpc.WithInsecure(), grpc.WithBlock(), grpc.WithTimeout(5*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	health := healthpb.NewHealthClient(conn)
+
+	status := func(service string) healthpb.HealthCheckResponse_ServingStatus {
+		res, err := health.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+		if err != nil {
+			t.Fatalf("%s health check failed: %v", service, err)
+		}
+		return res.Status
+	}
+	httpStatus := func(path string) int {
+		res, err := shutdownClient.Get("http://" + addr + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		return res.StatusCode
+	}
+
+	if s := status(HealthReadiness); s != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("server should be ready, got %s", s)
+	}
+
+	start := time.Now()
+	done := shutdownAsync(ms)
+
+	// Readiness turns not serving as soon as shutdown starts
+	for status(HealthReadiness) != healthpb.HealthCheckResponse_NOT_SERVING {
+		if time.Since(start) > drainDelay/2 {
+			t.Fatal("readiness should be not serving once shutdown starts")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// Traffic is still served during the drain delay, liveness is kept
+	if s := status(HealthLiveness); s != healthpb.HealthCheckResponse_SERVING {
+		t.Errorf("liveness should be serving while draining, got %s", s)
+	}
+	if code := httpStatus("/readyz"); code != http.StatusServiceUnavailable {
+		t.Errorf("/readyz should be unavailable while draining, got %d", code)
+	}
+	if code := httpStatus("/livez"); code != http.StatusOK {
+		t.Errorf("/livez should be ok while draining, got %d", code)
+	}
+	if time.Since(start) >= drainDelay {
+		t.Fatal("checks should run within the drain delay")
+	}
+
+	if err := <-done; err != nil {
+		t.Errorf("Shutdown() = %v", err)
+	}
+	if time.Since(start) < drainDelay {
+		t.Errorf("shutdown should wait for the drain delay, took %v", time.Since(start))
+	}
+	if err := <-served; err != nil {
+		t.Errorf("Serve() = %v", err)
+	}
+}
+
+func TestShutdownInFlight(t *testing.T) {
+	strategy := &fakeStrategy{name: "plain", delay: 300 * time.Millisecond}
+	registry, err := hashing.NewRegistry(strategy)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ms, addr, served := serveShutdownTest(t, WithRegistry(registry), WithHashing("plain", 16))
+	client, closeClient := dialTestServer(t, addr)
+	defer closeClient()
+
+	// One gRPC and one gateway call are verifying when shutdown starts
+	grpcDone := make(chan error, 1)
+	go func() {
+		res, err := client.Validate(context.Background(), &pb.PasswordReq{Password: "correct horse", Hash: "plain$correct horse"})
+		if err == nil && !res.Valid {
+			err = errors.New("password should be valid")
+		}
+		grpcDone <- err
+	}()
+	httpDone := make(chan int, 1)
+	go func() {
+		res, err := shutdownClient.Post("http://"+addr+"/v1/validate", "application/json", strings.NewReader(`{"password":"correct horse","hash":"plain$correct horse"}`))
+		if err != nil {
+			httpDone <- 0
+			return
+		}
+		res.Body.Close()
+		httpDone <- res.StatusCode
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for strategy.verifications() < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("calls should be in flight")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	done := shutdownAsync(ms)
+
+	if err := <-grpcDone; err != nil {
+		t.Errorf("in-flight gRPC call should complete, got %v", err)
+	}
+	if code := <-httpDone; code != http.StatusOK {
+		t.Errorf("in-flight gateway call should complete, got %d", code)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("Shutdown() = %v", err)
+	}
+	<-served
+
+	// Listeners are closed once calls are drained
+	if conn, err := net.DialTimeout("tcp", addr, time.Second); err == nil {
+		conn.Close()
+		t.Error("listener should be closed after shutdown")
+	}
+}
+
+func TestShutdownIdleConnection(t *testing.T) {
+	ms, addr, served := serveShutdownTest(t, WithHTTPTimeouts(200*time.Millisecond, time.Second, time.Second))
+
+	// Connected client sending nothing
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	time.Sleep(50 * time.Millisecond)
+
+	select {
+	case err := <-shutdownAsync(ms):
+		if err != nil {
+			t.Errorf("Shutdown() = %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("idle connections should not hold the shutdown")
+	}
+	if err := <-served; err != nil {
+		t.Errorf("Serve() = %v", err)
+	}
+}
@@ -0,0 +1,147 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"go.zenithar.org/password/hashing"
+	pb "go.zenithar.org/password/protocol/password"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+func (m *myService) CheckHistory(c context.Context, s *pb.HistoryReq) (*pb.HistoryRes, error) {
+	res := &pb.HistoryRes{Index: -1}
+
+	// Check mandatory fields
+	if len(strings.TrimSpace(s.Password)) == 0 {
+		policyRejections.WithLabelValues(rejectEmptyPassword).Inc()
+		res.Error = &pb.Error{
+			Code:    http.StatusPreconditionFailed,
+			Message: "Password value is mandatory !",
+		}
+		return res, nil
+	}
+	if err := m.checkHistory(s.Password, s.Hashes); err != nil {
+		return nil, err
+	}
+
+	index, perr, err := m.historyMatch(c, s.Password, s.Hashes)
+	if err != nil {
+		return nil, err
+	}
+	if perr != nil {
+		res.Error = perr
+		return res, nil
+	}
+
+	// Return result
+	res.Index = int32(index)
+	res.Matched = index >= 0
+	if res.Matched {
+		historyChecks.WithLabelValues("reused").Inc()
+	} else {
+		historyChecks.WithLabelValues("clear").Inc()
+	}
+
+	return res, nil
+}
+
+// checkHistory rejects histories exceeding the configured limits, before any
+// hashing work.
+func (m *myService) checkHistory(password string, hashes []string) error {
+	if len(hashes) > m.maxHistory {
+		policyRejections.WithLabelValues(rejectHistoryTooLong).Inc()
+		return grpc.Errorf(codes.InvalidArgument, "history exceeds %d hashes", m.maxHistory)
+	}
+	return m.checkLengths(password, hashes...)
+}
+
+// historyResult is the outcome of the verification of a previous hash
+type historyResult struct {
+	verification
+	werr  error
+	panic interface{}
+}
+
+// historyMatch verifies the password against previous hashes, with at most
+// historySlots verifications at once. The index of the first matching hash is
+// returned, -1 when none matched. Unsupported or malformed hashes are
+// reported as a response error.
+func (m *myService) historyMatch(ctx context.Context, password string, hashes []string) (int, *pb.Error, error) {
+	// Resolve hash strategies before any hashing work
+	strategies := make([]hashing.Strategy, len(hashes))
+	for i, hash := range hashes {
+		strategy, err := m.registry.Identify(hash)
+		if err != nil {
+			historyChecks.WithLabelValues("malformed").Inc()
+			return -1, &pb.Error{
+				Code:    http.StatusBadRequest,
+				Message: fmt.Sprintf("hash %d: %v", i, err),
+			}, nil
+		}
+		strategies[i] = strategy
+	}
+
+	results := make([]historyResult, len(hashes))
+	slots := make(chan struct{}, m.historySlots)
+	var wg sync.WaitGroup
+	for i := range hashes {
+		// Stop dispatching once the caller is gone, started verifications
+		// are still awaited
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			wg.Wait()
+			for _, r := range results {
+				if r.panic != nil {
+					panic(r.panic)
+				}
+			}
+			return -1, nil, grpc.Errorf(codes.Canceled, "history check cancelled, %v", ctx.Err())
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			// Panics are raised again on the handler goroutine, to be recovered
+			defer func() {
+				results[i].panic = recover()
+			}()
+			results[i].verification, results[i].werr = m.verify(ctx, strategies[i], hashes[i], password)
+		}(i)
+	}
+	wg.Wait()
+
+	for i, r := range results {
+		switch {
+		case r.panic != nil:
+			panic(r.panic)
+		case r.werr != nil:
+			m.rejected(r.werr)
+			return -1, nil, r.werr
+		case r.err == hashing.ErrInvalidPassword:
+			return -1, nil, m.invalidPassword()
+		case r.err != nil:
+			historyChecks.WithLabelValues("malformed").Inc()
+			return -1, &pb.Error{
+				Code:    http.StatusBadRequest,
+				Message: fmt.Sprintf("hash %d: %v", i, r.err),
+			}, nil
+		}
+	}
+	for i, r := range results {
+		if r.valid {
+			return i, nil, nil
+		}
+	}
+
+	return -1, nil, nil
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	pb "go.zenithar.org/password/protocol/password"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+func TestCheckHistory(t *testing.T) {
+	strategy := &fakeStrategy{name: "plain", delay: 10 * time.Millisecond}
+	client, stop := startFakeServer(t, []*fakeStrategy{strategy}, WithHistory(8, 2))
+	defer stop()
+
+	history := []string{"plain$winter2017", "plain$spring2018", "plain$summer2018", "nfc:plain$été2018", "plain$autumn2018", "plain$winter2018"}
+
+	tests := []struct {
+		password string
+		matched  bool
+		index    int32
+	}{
+		{"summer2018", true, 2},
+		{"été2018", true, 3},
+		{"winter2019", false, -1},
+	}
+	for _, tt := range tests {
+		res, err := client.CheckHistory(context.Background(), &pb.HistoryReq{Password: tt.password, Hashes: history})
+		if err != nil || res.Error != nil {
+			t.Fatalf("CheckHistory failed: %v %v", err, res.GetError())
+		}
+		if res.Matched != tt.matched || res.Index != tt.index {
+			t.Errorf("CheckHistory(%q) = %v %d, expected %v %d", tt.password, res.Matched, res.Index, tt.matched, tt.index)
+		}
+	}
+
+	strategy.mu.Lock()
+	if strategy.peak > 2 {
+		t.Errorf("at most 2 verifications should run at once, got %d", strategy.peak)
+	}
+	strategy.mu.Unlock()
+
+	// Unsupported hashes are reported
+	res, err := client.CheckHistory(context.Background(), &pb.HistoryReq{Password: "summer2018", Hashes: []string{"plain$winter2017", "unknown$hash"}})
+	if err != nil || res.GetError().GetCode() != http.StatusBadRequest {
+		t.Errorf("unsupported hash should be reported, got %v %v", err, res)
+	}
+
+	// History size is bounded
+	_, err = client.CheckHistory(context.Background(), &pb.HistoryReq{Password: "summer2018", Hashes: make([]string, 9)})
+	if grpc.Code(err) != codes.InvalidArgument {
+		t.Errorf("history exceeding the limit should be rejected, got %v", err)
+	}
+
+	// Encode rejects reused passwords
+	encoded, err := client.Encode(context.Background(), &pb.PasswordReq{Password: "spring2018", History: history})
+	if err != nil || encoded.GetError().GetCode() != http.StatusPreconditionFailed {
+		t.Errorf("reused password should be rejected, got %v %v", err, encoded)
+	}
+	encoded, err = client.Encode(context.Background(), &pb.PasswordReq{Password: "spring2019", History: history})
+	if err != nil || encoded.Error != nil || encoded.Hash != "plain$spring2019" {
+		t.Errorf("new password should be encoded, got %v %v", err, encoded)
+	}
+}
+
+func TestCheckHistoryCancel(t *testing.T) {
+	strategy := &fakeStrategy{name: "plain", delay: 50 * time.Millisecond}
+	client, stop := startFakeServer(t, []*fakeStrategy{strategy}, WithHistory(8, 1))
+	defer stop()
+
+	history := make([]string, 8)
+	for i := range history {
+		history[i] = "plain$winter2017"
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 75*time.Millisecond)
+	defer cancel()
+	if _, err := client.CheckHistory(ctx, &pb.HistoryReq{Password: "summer2018", Hashes: history}); grpc.Code(err) != codes.DeadlineExceeded {
+		t.Fatalf("CheckHistory should time out, got %v", err)
+	}
+
+	// Remaining verifications are not dispatched once the caller is gone,
+	// at most one more than the two started before the deadline
+	time.Sleep(time.Duration(len(history)) * strategy.delay)
+	if n := strategy.verifications(); n > 3 {
+		t.Errorf("verifications should stop with the caller, got %d", n)
+	}
+}
@@ -2,46 +2,47 @@ package server
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"strings"
-	"time"
 
+	"go.zenithar.org/password/hashing"
 	pb "go.zenithar.org/password/protocol/password"
 	"go.zenithar.org/password/version"
 
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/sirupsen/logrus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.zenithar.org/common/web/utils"
 )
 
-var (
-	httpServer *http.Server
-)
-
-func prepareHTTP(ctx context.Context, serverName string) (*http.Server, error) {
+func prepareHTTP(ctx context.Context, ms *MicroServer, guard *authGuard, dialer gatewayDialer) (*http.Server, error) {
 	// Assign a HTTP router
 	router := http.NewServeMux()
 
-	// Swagger
-	router.HandleFunc("/swagger.json", func(w http.ResponseWriter, req *http.Request) {
-		io.Copy(w, strings.NewReader(pb.Swagger))
-	})
+	// Swagger, reflecting configured input limits
+	swagger := swaggerWithLimits(ms.maxPasswordLength, ms.maxHashLength, ms.maxHistory)
+	router.Handle("/swagger.json", guard.Handler("/swagger.json", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		io.Copy(w, strings.NewReader(swagger))
+	})))
 
-	// Metrics endpoint
-	router.Handle("/metrics", prometheus.Handler())
+	// Metrics endpoint, exemplars are only exposed to scrapers negotiating
+	// the OpenMetrics format
+	if ms.metrics {
+		router.Handle("/metrics", guard.Handler("/metrics", promhttp.InstrumentMetricHandler(
+			prometheus.DefaultRegisterer,
+			promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{EnableOpenMetrics: true}),
+		)))
+	}
 
-	// Health monitoring endpoint
-	router.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
-		utils.JSONResponse(w, http.StatusOK, map[string]interface{}{
-			"status":    "OK",
-			"timestamp": time.Now().UTC().Unix(),
-		})
-	})
+	// Health monitoring endpoints, "/healthz" is kept as liveness alias
+	router.Handle("/healthz", guard.Handler("/healthz", livenessHandler(ms)))
+	router.Handle("/livez", guard.Handler("/livez", livenessHandler(ms)))
+	router.Handle("/readyz", guard.Handler("/readyz", readinessHandler(ms.readiness)))
 
 	// Service discovery
-	router.HandleFunc("/.well-known/finger", func(w http.ResponseWriter, r *http.Request) {
+	router.Handle("/.well-known/finger", guard.Handler("/.well-known/finger", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		utils.JSONResponse(w, http.StatusOK, map[string]interface{}{
 			"service-name":        "Password",
 			"service-description": "Remote password hasher",
@@ -52,25 +53,89 @@ func prepareHTTP(ctx context.Context, serverName string) (*http.Server, error) {
 			"build_date":          version.BuildDate,
 			"swagger_doc_url":     "/swagger.json",
 			"healthz_url":         "/healthz",
+			"livez_url":           "/livez",
+			"readyz_url":          "/readyz",
 			"metric_url":          "/metrics",
 			"endpoints":           []string{"grpc", "http"},
+			"compliance":          complianceName(ms.compliance),
 		})
-	})
+	})))
 
-	// initialize grpc-gateway
-	gw, err := prepareGateway(ctx, "service.sock")
-	if err != nil {
-		logrus.WithError(err).Error("Unable to initialize gRPC Gateway")
-		return nil, err
+	// Additional routes
+	for _, r := range ms.routes {
+		router.Handle(r.pattern, guard.Handler(r.pattern, r.handler))
+	}
+
+	// initialize grpc-gateway, "Authorization" header is forwarded as gRPC
+	// metadata and enforced by the gRPC authentication interceptor. Incoming
+	// trace context is continued by the gateway span and propagated to gRPC.
+	if ms.gateway {
+		gw, err := prepareGateway(ctx, ms.logger, ms.tracer, dialer)
+		if err != nil {
+			ms.logger.WithError(err).Error("Unable to initialize gRPC Gateway")
+			return nil, err
+		}
+		gw = limitBodyHandler(ms.maxBodySize(), gw)
+		if ms.metrics {
+			gw = instrumentHandler("gateway", gw)
+		}
+		gw = traceHandler(ms.tracer, gw)
+		router.Handle("/", gw)
 	}
-	router.Handle("/", gw)
 
 	// Return HTTP Server instance
 	return &http.Server{
-		Addr:         serverName,
-		Handler:      router,
-		ReadTimeout:  5 * time.Second,
-		WriteTimeout: 10 * time.Second,
-		IdleTimeout:  120 * time.Second,
+		Addr:         ms.serverName,
+		Handler:      requestIDHandler(recoveryHandler(ms.logger, router)),
+		ReadTimeout:  ms.readTimeout,
+		WriteTimeout: ms.writeTimeout,
+		IdleTimeout:  ms.idleTimeout,
 	}, nil
 }
+
+// complianceName returns the compliance mode reported by service discovery
+func complianceName(c *hashing.Compliance) string {
+	if c == nil {
+		return hashing.ComplianceNone
+	}
+	return c.Name
+}
+
+// swaggerWithLimits returns the OpenAPI document with password and hash
+// maximum lengths and history sizes, the embedded document is returned when
+// it can't be decoded.
+func swaggerWithLimits(maxPassword, maxHash, maxHistory int) string {
+	var doc map[string]interface{}
+	if err := json.Unmarshal([]byte(pb.Swagger), &doc); err != nil {
+		return pb.Swagger
+	}
+
+	definitions, _ := doc["definitions"].(map[string]interface{})
+	limits := map[string]map[string]int{
+		"passwordPasswordReq": {"password": maxPassword, "hash": maxHash, "history": maxHash},
+		"passwordHistoryReq":  {"password": maxPassword, "hashes": maxHash},
+		"passwordChangeReq":   {"old_password": maxPassword, "new_password": maxPassword, "hash": maxHash},
+	}
+	for definition, fields := range limits {
+		req, _ := definitions[definition].(map[string]interface{})
+		properties, _ := req["properties"].(map[string]interface{})
+		for name, max := range fields {
+			field, ok := properties[name].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			// Repeated fields bound their items
+			if items, ok := field["items"].(map[string]interface{}); ok {
+				field["maxItems"] = maxHistory
+				field = items
+			}
+			field["maxLength"] = max
+		}
+	}
+
+	out, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return pb.Swagger
+	}
+	return string(out)
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	"go.zenithar.org/common/web/utils"
+	"google.golang.org/grpc/codes"
+)
+
+// bodyLimit bounds a gateway request body, exceeded is set once the body
+// reader hit the limit
+type bodyLimit struct {
+	size     int64
+	exceeded bool
+}
+
+type bodyLimitKey struct{}
+
+// limitedBody fails reads past the limit
+type limitedBody struct {
+	io.ReadCloser
+	limit     *bodyLimit
+	remaining int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if b.remaining <= 0 {
+		// Probe for data past the limit
+		var probe [1]byte
+		n, err := b.ReadCloser.Read(probe[:])
+		if n > 0 {
+			b.limit.exceeded = true
+			return 0, fmt.Errorf("request body exceeds %d bytes", b.limit.size)
+		}
+		return 0, err
+	}
+
+	if int64(len(p)) > b.remaining {
+		p = p[:b.remaining]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+	return n, err
+}
+
+// limitBodyHandler rejects gateway requests whose body exceeds size with a
+// 413 status, bodies without content length are checked while decoded.
+func limitBodyHandler(size int64, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limit := &bodyLimit{size: size}
+		if r.ContentLength > size {
+			writeBodyTooLarge(w, limit)
+			return
+		}
+
+		r.Body = &limitedBody{ReadCloser: r.Body, limit: limit, remaining: size}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), bodyLimitKey{}, limit)))
+	})
+}
+
+// gatewayErrorHandler returns 413 for oversized request bodies, other errors
+// are rendered by the default handler.
+func gatewayErrorHandler(ctx context.Context, mux *runtime.ServeMux, marshaler runtime.Marshaler, w http.ResponseWriter, r *http.Request, err error) {
+	if limit, ok := r.Context().Value(bodyLimitKey{}).(*bodyLimit); ok && limit.exceeded {
+		writeBodyTooLarge(w, limit)
+		return
+	}
+	runtime.DefaultHTTPProtoErrorHandler(ctx, mux, marshaler, w, r, err)
+}
+
+func writeBodyTooLarge(w http.ResponseWriter, limit *bodyLimit) {
+	policyRejections.WithLabelValues(rejectBodyTooLarge).Inc()
+	utils.JSONResponse(w, http.StatusRequestEntityTooLarge, map[string]interface{}{
+		"code":    codes.InvalidArgument,
+		"message": fmt.Sprintf("request body exceeds %d bytes", limit.size),
+		"details": []interface{}{},
+	})
+}
@@ -0,0 +1,125 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	pb "go.zenithar.org/password/protocol/password"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+const (
+	testMaxPassword = 16
+	testMaxHash     = 32
+)
+
+func TestInputLimits(t *testing.T) {
+	addr, stop := startTestServer(t, WithInputLimits(testMaxPassword, testMaxHash))
+	defer stop()
+	client, closeConn := dialTestServer(t, addr)
+	defer closeConn()
+
+	tests := []struct {
+		name string
+		call func() error
+		code codes.Code
+	}{
+		{"password at limit", func() error {
+			_, err := client.Encode(context.Background(), &pb.PasswordReq{Password: strings.Repeat("a", testMaxPassword)})
+			return err
+		}, codes.OK},
+		{"password too long", func() error {
+			_, err := client.Encode(context.Background(), &pb.PasswordReq{Password: strings.Repeat("a", testMaxPassword+1)})
+			return err
+		}, codes.InvalidArgument},
+		{"validated password too long", func() error {
+			_, err := client.Validate(context.Background(), &pb.PasswordReq{Password: strings.Repeat("a", testMaxPassword+1), Hash: "hash"})
+			return err
+		}, codes.InvalidArgument},
+		{"hash too long", func() error {
+			_, err := client.Validate(context.Background(), &pb.PasswordReq{Password: "password", Hash: strings.Repeat("a", testMaxHash+1)})
+			return err
+		}, codes.InvalidArgument},
+		{"message too large", func() error {
+			_, err := client.Encode(context.Background(), &pb.PasswordReq{Password: strings.Repeat("a", 4096)})
+			return err
+		}, codes.ResourceExhausted},
+	}
+	for _, tt := range tests {
+		if code := grpc.Code(tt.call()); code != tt.code {
+			t.Errorf("%s: code = %s, expected %s", tt.name, code, tt.code)
+		}
+	}
+}
+
+// unsizedReader hides the body length, requests are sent chunked
+type unsizedReader struct {
+	io.Reader
+}
+
+func TestBodyLimit(t *testing.T) {
+	addr, stop := startTestServer(t, WithInputLimits(testMaxPassword, testMaxHash))
+	defer stop()
+
+	oversized := fmt.Sprintf(`{"password":"%s"}`, strings.Repeat("a", 16384))
+	tests := []struct {
+		name   string
+		body   io.Reader
+		status int
+	}{
+		{"small body", strings.NewReader(`{"password":"correct horse"}`), http.StatusOK},
+		{"password too long", strings.NewReader(fmt.Sprintf(`{"password":"%s"}`, strings.Repeat("a", testMaxPassword+1))), http.StatusBadRequest},
+		{"content length too large", strings.NewReader(oversized), http.StatusRequestEntityTooLarge},
+		{"chunked body too large", unsizedReader{strings.NewReader(oversized)}, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		res, err := http.Post("http://"+addr+"/v1/password", "application/json", tt.body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if res.StatusCode != tt.status {
+			t.Errorf("%s: status = %d, expected %d (%s)", tt.name, res.StatusCode, tt.status, body)
+			continue
+		}
+		if tt.status == http.StatusRequestEntityTooLarge && !strings.Contains(string(body), "request body exceeds") {
+			t.Errorf("%s: body should describe the limit, got %s", tt.name, body)
+		}
+	}
+}
+
+func TestSwaggerLimits(t *testing.T) {
+	addr, stop := startTestServer(t, WithInputLimits(testMaxPassword, testMaxHash))
+	defer stop()
+
+	res, err := http.Get("http://" + addr + "/swagger.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	var doc struct {
+		Definitions map[string]struct {
+			Properties map[string]struct {
+				MaxLength int `json:"maxLength"`
+			} `json:"properties"`
+		} `json:"definitions"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&doc); err != nil {
+		t.Fatal(err)
+	}
+
+	properties := doc.Definitions["passwordPasswordReq"].Properties
+	if properties["password"].MaxLength != testMaxPassword || properties["hash"].MaxLength != testMaxHash {
+		t.Errorf("swagger should document configured limits, got %+v", properties)
+	}
+}
@@ -0,0 +1,41 @@
+package server
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/logrus"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// validateMethod is the high volume method subject to log sampling
+const validateMethod = auditedService + "Validate"
+
+// loggingUnaryServerInterceptor logs finished calls. Successful Validate
+// calls are sampled: one out of sampling is logged at info level, the others
+// at debug level.
+func loggingUnaryServerInterceptor(logger *logrus.Entry, sampling int) grpc.UnaryServerInterceptor {
+	full := grpc_logrus.UnaryServerInterceptor(logger)
+	if sampling <= 1 {
+		return full
+	}
+
+	quiet := grpc_logrus.UnaryServerInterceptor(logger,
+		grpc_logrus.WithLevels(func(code codes.Code) logrus.Level {
+			if code == codes.OK {
+				return logrus.DebugLevel
+			}
+			return grpc_logrus.DefaultCodeToLevel(code)
+		}),
+	)
+
+	var calls uint64
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if info.FullMethod == validateMethod && (atomic.AddUint64(&calls, 1)-1)%uint64(sampling) != 0 {
+			return quiet(ctx, req, info, handler)
+		}
+		return full(ctx, req, info, handler)
+	}
+}
@@ -0,0 +1,60 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	pb "go.zenithar.org/password/protocol/password"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestValidateLogSampling(t *testing.T) {
+	logs := &syncBuffer{}
+	logger := logrus.New()
+	logger.Out = logs
+	logger.Formatter = &logrus.JSONFormatter{}
+	logger.Level = logrus.InfoLevel
+
+	client, stop := startFakeServer(t, []*fakeStrategy{{name: "plain"}},
+		WithLogger(logrus.NewEntry(logger)),
+		WithInputLimits(64, 64),
+		WithValidateLogSampling(3),
+	)
+	defer stop()
+
+	ctx := context.Background()
+	for i := 0; i < 6; i++ {
+		if _, err := client.Validate(ctx, &pb.PasswordReq{Password: "correct horse", Hash: "plain$correct horse"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := client.Validate(ctx, &pb.PasswordReq{Password: "correct horse", Hash: strings.Repeat("a", 65)}); err == nil {
+			t.Fatal("oversized hash should be rejected")
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := client.Encode(ctx, &pb.PasswordReq{Password: "correct horse"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// One out of 3 successful calls is logged, errors and other methods
+	// always are
+	var ok, failed int
+	for _, line := range logLines(t, logs, "Validate") {
+		if line["grpc.code"] == "OK" {
+			ok++
+		} else {
+			failed++
+		}
+	}
+	if ok != 2 || failed != 3 {
+		t.Errorf("expected 2 successful and 3 failed Validate logs, got %d and %d", ok, failed)
+	}
+	if n := len(logLines(t, logs, "Encode")); n != 2 {
+		t.Errorf("Encode calls should not be sampled, got %d logs", n)
+	}
+}
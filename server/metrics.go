@@ -0,0 +1,187 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/uber/jaeger-client-go"
+)
+
+// durationBuckets covers fast digests up to memory hard functions
+var durationBuckets = prometheus.ExponentialBuckets(0.001, 2, 14)
+
+var (
+	hashDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "password_hash_duration_seconds",
+		Help:    "Time spent hashing passwords, by algorithm.",
+		Buckets: durationBuckets,
+	}, []string{"algorithm"})
+
+	verifyDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "password_verify_duration_seconds",
+		Help:    "Time spent verifying passwords, by algorithm.",
+		Buckets: durationBuckets,
+	}, []string{"algorithm"})
+
+	validations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "password_validations_total",
+		Help: "Number of password validations, by outcome (valid, invalid, dummy, malformed, unsupported).",
+	}, []string{"outcome"})
+
+	historyChecks = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "password_history_checks_total",
+		Help: "Number of password history checks, by outcome (reused, clear, malformed).",
+	}, []string{"outcome"})
+
+	changeChecks = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "password_change_checks_total",
+		Help: "Number of password change checks, by outcome (accepted, similar, invalid, malformed).",
+	}, []string{"outcome"})
+
+	storeOperations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "password_store_operations_total",
+		Help: "Number of managed credential operations, by operation (set, verify, delete) and outcome.",
+	}, []string{"operation", "outcome"})
+
+	needsRehash = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "password_needs_rehash_total",
+		Help: "Number of valid passwords whose hash should be replaced, by algorithm.",
+	}, []string{"algorithm"})
+
+	policyRejections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "password_policy_rejections_total",
+		Help: "Number of requests rejected by a policy, by reason.",
+	}, []string{"reason"})
+
+	queueDepth = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "password_worker_queue_depth",
+		Help: "Number of hashing operations waiting for a worker.",
+	})
+
+	queueWait = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "password_worker_queue_wait_seconds",
+		Help:    "Time spent by hashing operations waiting for a worker.",
+		Buckets: durationBuckets,
+	})
+
+	memoryInUse = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "password_hashing_memory_in_use_bytes",
+		Help: "Memory used by hashing operations in progress, by memory hard algorithm (argon2).",
+	}, []string{"algorithm"})
+
+	saltHealthFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "password_salt_health_failures_total",
+		Help: "Number of salts rejected by the salt source health tests.",
+	}, []string{"test"})
+
+	panics = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "password_panics_total",
+		Help: "Number of recovered handler panics, by transport (grpc, http).",
+	}, []string{"transport"})
+
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "password_http_requests_total",
+		Help: "Number of HTTP requests, by handler, method and status code.",
+	}, []string{"handler", "method", "code"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "password_http_request_duration_seconds",
+		Help:    "HTTP request latencies, by handler and method.",
+		Buckets: durationBuckets,
+	}, []string{"handler", "method"})
+)
+
+func init() {
+	prometheus.MustRegister(
+		hashDuration,
+		verifyDuration,
+		validations,
+		historyChecks,
+		changeChecks,
+		storeOperations,
+		needsRehash,
+		policyRejections,
+		queueDepth,
+		queueWait,
+		memoryInUse,
+		saltHealthFailures,
+		panics,
+		httpRequests,
+		httpDuration,
+	)
+}
+
+// Policy rejection reasons
+const (
+	rejectUnauthenticated  = "unauthenticated"
+	rejectPermissionDenied = "permission_denied"
+	rejectEmptyPassword    = "empty_password"
+	rejectEmptyHash        = "empty_hash"
+	rejectQueueFull        = "queue_full"
+	rejectPasswordTooLong  = "password_too_long"
+	rejectHashTooLong      = "hash_too_long"
+	rejectBodyTooLarge     = "body_too_large"
+	rejectInvalidPassword  = "invalid_password"
+	rejectHistoryTooLong   = "history_too_long"
+	rejectPasswordReused   = "password_reused"
+	rejectSubjectTooLong   = "subject_too_long"
+)
+
+// traceIDLabel is the exemplar label linking an observation to its trace
+const traceIDLabel = "trace_id"
+
+// traceExemplar returns the exemplar labels of the sampled trace of the
+// context, nil is returned when the call is not traced.
+func traceExemplar(ctx context.Context) prometheus.Labels {
+	span := opentracing.SpanFromContext(ctx)
+	if span == nil {
+		return nil
+	}
+	sc, ok := span.Context().(jaeger.SpanContext)
+	if !ok || !sc.IsSampled() {
+		return nil
+	}
+	return prometheus.Labels{traceIDLabel: sc.TraceID().String()}
+}
+
+// observe records the value, with the trace exemplar of the context when the
+// call is traced. Exemplars are exposed in the OpenMetrics format only.
+func observe(ctx context.Context, o prometheus.Observer, value float64) {
+	if e := traceExemplar(ctx); e != nil {
+		if eo, ok := o.(prometheus.ExemplarObserver); ok {
+			eo.ObserveWithExemplar(value, e)
+			return
+		}
+	}
+	o.Observe(value)
+}
+
+// -----------------------------------------------------------------------------
+
+type statusRecorder struct {
+	http.ResponseWriter
+	code int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.code = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+// instrumentHandler records request count and latency of the given handler,
+// latencies are linked to the request span started by an outer traceHandler.
+func instrumentHandler(name string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, code: http.StatusOK}
+
+		next.ServeHTTP(rec, r)
+
+		httpRequests.WithLabelValues(name, r.Method, strconv.Itoa(rec.code)).Inc()
+		observe(r.Context(), httpDuration.WithLabelValues(name, r.Method), time.Since(start).Seconds())
+	})
+}
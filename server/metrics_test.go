@@ -0,0 +1,132 @@
+package server
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"go.zenithar.org/password/utils/tracer"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/uber/jaeger-client-go"
+)
+
+func TestObserveExemplar(t *testing.T) {
+	tr, _, closer := tracer.NewInMemory("password")
+	defer closer.Close()
+
+	h := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "test_seconds", Buckets: []float64{1}})
+	exemplar := func() *dto.Exemplar {
+		var m dto.Metric
+		if err := h.Write(&m); err != nil {
+			t.Fatal(err)
+		}
+		return m.GetHistogram().GetBucket()[0].GetExemplar()
+	}
+
+	// Calls without span are observed without exemplar
+	observe(context.Background(), h, 0.5)
+	if e := exemplar(); e != nil {
+		t.Errorf("untraced observation should not have an exemplar, got %v", e)
+	}
+
+	span := tr.StartSpan("test")
+	defer span.Finish()
+	observe(opentracing.ContextWithSpan(context.Background(), span), h, 0.25)
+
+	e := exemplar()
+	if e == nil || e.GetValue() != 0.25 {
+		t.Fatalf("traced observation should have an exemplar, got %v", e)
+	}
+	if label := e.GetLabel(); len(label) != 1 || label[0].GetName() != traceIDLabel || len(label[0].GetValue()) == 0 {
+		t.Errorf("exemplar should carry the trace id, got %v", label)
+	}
+
+	// Unsampled and foreign traces are not linked
+	unsampledTracer, unsampledCloser := jaeger.NewTracer("password", jaeger.NewConstSampler(false), jaeger.NewNullReporter())
+	defer unsampledCloser.Close()
+	for _, tr := range []opentracing.Tracer{unsampledTracer, opentracing.NoopTracer{}} {
+		span := tr.StartSpan("test")
+		if labels := traceExemplar(opentracing.ContextWithSpan(context.Background(), span)); labels != nil {
+			t.Errorf("traceExemplar() = %v, expected no exemplar", labels)
+		}
+		span.Finish()
+	}
+}
+
+func TestInstrumentHandler(t *testing.T) {
+	counter := func(code string) float64 {
+		var m dto.Metric
+		if err := httpRequests.WithLabelValues("test", http.MethodGet, code).Write(&m); err != nil {
+			t.Fatal(err)
+		}
+		return m.GetCounter().GetValue()
+	}
+
+	h := instrumentHandler("test", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+
+	ok, missing := counter("200"), counter("404")
+	for _, path := range []string{"/", "/", "/missing"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	if counter("200")-ok != 2 || counter("404")-missing != 1 {
+		t.Errorf("requests should be counted by status code, got 200: %v 404: %v", counter("200")-ok, counter("404")-missing)
+	}
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	tr, _, closer := tracer.NewInMemory("password")
+	defer closer.Close()
+
+	addr, stop := startTestServer(t, WithMetrics(true), WithTracer(tr))
+	defer stop()
+
+	res, err := http.Post("http://"+addr+"/v1/password", "application/json", strings.NewReader(`{"password":"correct horse"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("encode should succeed, got %d", res.StatusCode)
+	}
+
+	scrape := func(accept string) string {
+		req, _ := http.NewRequest(http.MethodGet, "http://"+addr+"/metrics", nil)
+		req.Header.Set("Accept", accept)
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		body, _ := ioutil.ReadAll(res.Body)
+		return string(body)
+	}
+
+	// Exemplars link observations to the call trace in OpenMetrics format
+	body := scrape("application/openmetrics-text; version=0.0.1")
+	for _, name := range []string{"password_hash_duration_seconds", "password_worker_queue_wait_seconds", "password_http_request_duration_seconds"} {
+		if !regexp.MustCompile(name + `_bucket\{[^}]*\} \d+ # \{trace_id="[0-9a-f]+"\}`).MatchString(body) {
+			t.Errorf("%s should expose a trace exemplar", name)
+		}
+	}
+	if !strings.Contains(body, `password_http_requests_total{code="200",handler="gateway",method="POST"}`) {
+		t.Error("gateway requests should be counted")
+	}
+
+	// Text format has no exemplar
+	if body := scrape("text/plain"); !strings.Contains(body, "password_hash_duration_seconds_bucket") || strings.Contains(body, "trace_id") {
+		t.Error("text format should expose histograms without exemplars")
+	}
+}
@@ -0,0 +1,106 @@
+package server
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pb "go.zenithar.org/password/protocol/password"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+)
+
+func TestEmbedOptions(t *testing.T) {
+	var unaryCalls, streamCalls int32
+	unary := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		atomic.AddInt32(&unaryCalls, 1)
+		return handler(ctx, req)
+	}
+	stream := func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		atomic.AddInt32(&streamCalls, 1)
+		return handler(srv, ss)
+	}
+	custom := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("custom"))
+	})
+
+	addr, stop := startTestServer(t,
+		WithUnaryInterceptors(unary),
+		WithStreamInterceptors(stream),
+		WithReflection(false),
+		WithServices(reflection.Register),
+		WithHandler("/custom", custom),
+		WithGateway(false),
+	)
+	defer stop()
+
+	conn, err := grpc.Dial(addr, grpc.WithInsecure(), grpc.WithBlock(), grpc.WithTimeout(5*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// Unary interceptors are chained
+	client := pb.NewPasswordClient(conn)
+	if _, err := client.Ping(context.Background(), &empty.Empty{}); err != nil {
+		t.Fatalf("Ping failed: %v", err)
+	}
+	if n := atomic.LoadInt32(&unaryCalls); n != 1 {
+		t.Errorf("unary interceptor should be called once, got %d", n)
+	}
+
+	// Additional services are registered, their streams are intercepted
+	refl, err := rpb.NewServerReflectionClient(conn).ServerReflectionInfo(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := refl.Send(&rpb.ServerReflectionRequest{MessageRequest: &rpb.ServerReflectionRequest_ListServices{}}); err != nil {
+		t.Fatal(err)
+	}
+	res, err := refl.Recv()
+	if err != nil {
+		t.Fatalf("reflection should be registered: %v", err)
+	}
+	refl.CloseSend()
+	var services []string
+	for _, s := range res.GetListServicesResponse().GetService() {
+		services = append(services, s.Name)
+	}
+	if !strings.Contains(strings.Join(services, ","), "password.Password") {
+		t.Errorf("reflection should list the password service, got %v", services)
+	}
+	if n := atomic.LoadInt32(&streamCalls); n != 1 {
+		t.Errorf("stream interceptor should be called once, got %d", n)
+	}
+
+	// HTTP routes are mounted, the gateway is not
+	cases := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/custom", http.StatusOK, "custom"},
+		{"/v1/password", http.StatusNotFound, ""},
+	}
+	for _, c := range cases {
+		res, err := http.Get("http://" + addr + c.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if res.StatusCode != c.code {
+			t.Errorf("%s: status should be %d, got %d", c.path, c.code, res.StatusCode)
+		}
+		if len(c.body) > 0 && string(body) != c.body {
+			t.Errorf("%s: body should be %q, got %q", c.path, c.body, body)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware"
+	"github.com/grpc-ecosystem/go-grpc-middleware/tags"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// forwardedForKey is the gRPC metadata key of forwarded caller addresses, the
+// gateway appends the HTTP remote address to the received value.
+const forwardedForKey = "x-forwarded-for"
+
+type clientAddressCtxKey struct{}
+
+// proxies lists the networks allowed to forward caller addresses
+type proxies []*net.IPNet
+
+func (p proxies) trusted(addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, n := range p {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientAddress returns the caller address. Forwarded addresses are only
+// considered when the connection comes from the gateway internal transport
+// or a trusted proxy, the last untrusted hop is returned.
+func (p proxies) clientAddress(ctx context.Context) string {
+	addr, internal := connAddress(ctx)
+	if !internal && !p.trusted(addr) {
+		return addr
+	}
+
+	var hops []string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for _, value := range md[forwardedForKey] {
+			for _, hop := range strings.Split(value, ",") {
+				if hop = strings.TrimSpace(hop); len(hop) > 0 {
+					hops = append(hops, hop)
+				}
+			}
+		}
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr = hops[i]
+		if !p.trusted(addr) {
+			break
+		}
+	}
+
+	return addr
+}
+
+// connAddress returns the host of the connection peer, and whether the
+// connection comes from the gateway internal transport.
+func connAddress(ctx context.Context) (string, bool) {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return "", false
+	}
+
+	switch p.Addr.Network() {
+	case "tcp", "tcp4", "tcp6":
+		if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+			return host, false
+		}
+		return p.Addr.String(), false
+	}
+	return p.Addr.String(), true
+}
+
+// peerAddress returns the caller address resolved by the peer interceptors,
+// or the connection address.
+func peerAddress(ctx context.Context) string {
+	if addr, ok := ctx.Value(clientAddressCtxKey{}).(string); ok {
+		return addr
+	}
+	addr, _ := connAddress(ctx)
+	return addr
+}
+
+func (p proxies) withClientAddress(ctx context.Context) context.Context {
+	addr := p.clientAddress(ctx)
+
+	// net.Addr peer tag is not serializable by the JSON formatter, the
+	// caller address is logged instead
+	grpc_ctxtags.Extract(ctx).Set("peer.address", addr)
+
+	return context.WithValue(ctx, clientAddressCtxKey{}, addr)
+}
+
+// peerUnaryServerInterceptor resolves the caller address once per call
+func peerUnaryServerInterceptor(p proxies) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(p.withClientAddress(ctx), req)
+	}
+}
+
+// peerStreamServerInterceptor is the streaming counterpart of
+// peerUnaryServerInterceptor.
+func peerStreamServerInterceptor(p proxies) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		wrapped := grpc_middleware.WrapServerStream(stream)
+		wrapped.WrappedContext = p.withClientAddress(stream.Context())
+		return handler(srv, wrapped)
+	}
+}
@@ -0,0 +1,47 @@
+package server
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestClientAddress(t *testing.T) {
+	_, lb, _ := net.ParseCIDR("10.0.0.0/8")
+	trusted := proxies{lb}
+
+	tcp := func(ip string) net.Addr { return &net.TCPAddr{IP: net.ParseIP(ip), Port: 4242} }
+	gateway := bufconn.Listen(1).Addr()
+
+	tests := []struct {
+		name      string
+		addr      net.Addr
+		forwarded []string
+		expected  string
+	}{
+		{"direct", tcp("198.51.100.7"), nil, "198.51.100.7"},
+		{"direct forged", tcp("198.51.100.7"), []string{"203.0.113.1"}, "198.51.100.7"},
+		{"gateway", gateway, []string{"198.51.100.7"}, "198.51.100.7"},
+		// grpc-gateway appends the remote address to the client supplied header
+		{"gateway forged", gateway, []string{"203.0.113.1, 198.51.100.7"}, "198.51.100.7"},
+		{"gateway forged metadata", gateway, []string{"203.0.113.1", "198.51.100.7"}, "198.51.100.7"},
+		{"gateway behind proxy", gateway, []string{"203.0.113.1, 198.51.100.7, 10.0.0.2"}, "198.51.100.7"},
+		{"trusted proxy", tcp("10.1.2.3"), []string{"203.0.113.1, 198.51.100.7"}, "198.51.100.7"},
+		{"trusted proxy only", tcp("10.1.2.3"), []string{"10.0.0.4"}, "10.0.0.4"},
+		{"trusted proxy without header", tcp("10.1.2.3"), nil, "10.1.2.3"},
+	}
+	for _, tt := range tests {
+		ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: tt.addr})
+		if tt.forwarded != nil {
+			md := metadata.MD{forwardedForKey: tt.forwarded}
+			ctx = metadata.NewIncomingContext(ctx, md)
+		}
+		if addr := trusted.clientAddress(ctx); addr != tt.expected {
+			t.Errorf("%s: clientAddress() = %s, expected %s", tt.name, addr, tt.expected)
+		}
+	}
+}
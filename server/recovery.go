@@ -1,9 +1,11 @@
 package server
 
 import (
+	"net/http"
 	"runtime"
 
 	"github.com/sirupsen/logrus"
+	"go.zenithar.org/common/web/utils"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 )
@@ -12,10 +14,87 @@ const (
 	MAXSTACKSIZE = 4096
 )
 
-func recoveryFunc(p interface{}) error {
-	// log stack
-	stack := make([]byte, MAXSTACKSIZE)
-	stack = stack[:runtime.Stack(stack, false)]
-	logrus.Errorf("panic grpc: err=%v, stack:\n%s", p, string(stack))
-	return grpc.Errorf(codes.Internal, "panic error: %v", p)
+// Recovered panic transports
+const (
+	transportGRPC = "grpc"
+	transportHTTP = "http"
+)
+
+// recoveryFunc logs the panic, the payload is never returned to the caller
+// since it may hold request values.
+func recoveryFunc(logger *logrus.Entry) func(p interface{}) error {
+	return func(p interface{}) error {
+		panics.WithLabelValues(transportGRPC).Inc()
+		logger.Errorf("panic grpc: err=%v, stack:\n%s", p, stack())
+		return grpc.Errorf(codes.Internal, "internal error")
+	}
+}
+
+// writeTracker records whether a response has been started
+type writeTracker struct {
+	http.ResponseWriter
+	written bool
+}
+
+func (w *writeTracker) WriteHeader(code int) {
+	w.written = true
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *writeTracker) Write(b []byte) (int, error) {
+	w.written = true
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *writeTracker) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		w.written = true
+		f.Flush()
+	}
+}
+
+// recoveryHandler recovers HTTP handler panics with a JSON internal error
+// response. When the handler already started its response, the connection is
+// aborted instead. Request secrets can't be scrubbed from the payload of
+// gateway panics, only its type is logged.
+func recoveryHandler(logger *logrus.Entry, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		w := &writeTracker{ResponseWriter: rw}
+		defer func() {
+			p := recover()
+			if p == nil {
+				return
+			}
+			// Deliberate connection abort
+			if p == http.ErrAbortHandler {
+				panic(p)
+			}
+
+			panics.WithLabelValues(transportHTTP).Inc()
+			id, _ := RequestIDFromContext(r.Context())
+			logger.WithFields(logrus.Fields{
+				requestIDTag:  id,
+				"http.method": r.Method,
+				"http.path":   r.URL.Path,
+			}).Errorf("panic http: type=%T, stack:\n%s", p, stack())
+
+			// A partial response can't be turned into an error
+			if w.written {
+				panic(http.ErrAbortHandler)
+			}
+
+			utils.JSONResponse(w, http.StatusInternalServerError, map[string]interface{}{
+				"code":       http.StatusInternalServerError,
+				"message":    "internal error",
+				"request_id": id,
+			})
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func stack() string {
+	buf := make([]byte, MAXSTACKSIZE)
+	return string(buf[:runtime.Stack(buf, false)])
 }
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/sirupsen/logrus"
+)
+
+func panicCount(t *testing.T) float64 {
+	var m dto.Metric
+	if err := panics.WithLabelValues(transportHTTP).Write(&m); err != nil {
+		t.Fatal(err)
+	}
+	return m.GetCounter().GetValue()
+}
+
+func TestRecoveryHandler(t *testing.T) {
+	var logs bytes.Buffer
+	logger := logrus.New()
+	logger.Out = &logs
+
+	h := requestIDHandler(recoveryHandler(logrus.NewEntry(logger), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/partial":
+			w.Write([]byte("partial"))
+		case "/abort":
+			panic(http.ErrAbortHandler)
+		}
+		panic("secret payload")
+	})))
+
+	// serve returns the response and the value of a panic escaping the handler
+	serve := func(path string) (rec *httptest.ResponseRecorder, escaped interface{}) {
+		rec = httptest.NewRecorder()
+		defer func() {
+			escaped = recover()
+		}()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec, nil
+	}
+
+	// Panics before any write are answered with a JSON error
+	before := panicCount(t)
+	rec, escaped := serve("/")
+	if escaped != nil {
+		t.Fatalf("panic should be recovered, got %v", escaped)
+	}
+	var body struct {
+		Code      int    `json:"code"`
+		Message   string `json:"message"`
+		RequestID string `json:"request_id"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("response should be JSON, got %q", rec.Body.String())
+	}
+	if rec.Code != http.StatusInternalServerError || body.Code != http.StatusInternalServerError || len(body.RequestID) == 0 {
+		t.Errorf("response = %d %+v, expected internal error with request id", rec.Code, body)
+	}
+	if panicCount(t)-before != 1 {
+		t.Error("recovered panic should be counted")
+	}
+	if strings.Contains(logs.String(), "secret payload") || !strings.Contains(logs.String(), body.RequestID) {
+		t.Errorf("panic should be logged by type with the request id, got %q", logs.String())
+	}
+
+	// Started responses are aborted, not completed with an error
+	rec, escaped = serve("/partial")
+	if escaped != http.ErrAbortHandler {
+		t.Errorf("partial response should be aborted, got %v", escaped)
+	}
+	if rec.Body.String() != "partial" || rec.Code != http.StatusOK {
+		t.Errorf("error should not be appended to a partial response, got %d %q", rec.Code, rec.Body.String())
+	}
+	if panicCount(t)-before != 2 {
+		t.Error("panic of a partial response should be counted")
+	}
+
+	// Deliberate aborts are left to net/http
+	if _, escaped = serve("/abort"); escaped != http.ErrAbortHandler {
+		t.Errorf("deliberate abort should be propagated, got %v", escaped)
+	}
+	if panicCount(t)-before != 2 {
+		t.Error("deliberate abort should not be counted")
+	}
+}
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"context"
+
+	pb "go.zenithar.org/password/protocol/password"
+	"go.zenithar.org/password/utils/redact"
+
+	"google.golang.org/grpc"
+)
+
+type secretHolder interface {
+	Secrets() []string
+}
+
+type errorHolder interface {
+	GetError() *pb.Error
+}
+
+// redactUnaryServerInterceptor scrubs request secrets from returned errors,
+// response error messages and panic payloads. The call scrubber is available
+// to handlers with redact.FromContext.
+func redactUnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (res interface{}, err error) {
+		holder, ok := req.(secretHolder)
+		if !ok {
+			return handler(ctx, req)
+		}
+		scrubber := redact.New(holder.Secrets()...)
+
+		// Recovery interceptor receives the scrubbed panic payload
+		defer func() {
+			if p := recover(); p != nil {
+				panic(scrubber.Value(p))
+			}
+		}()
+
+		res, err = handler(redact.NewContext(ctx, scrubber), req)
+		if r, ok := res.(errorHolder); ok && r.GetError() != nil {
+			r.GetError().Message = scrubber.String(r.GetError().Message)
+		}
+
+		return res, scrubError(scrubber, err)
+	}
+}
+
+// scrubError returns a scrubbed copy of a gRPC error, keeping its code
+func scrubError(s *redact.Scrubber, err error) error {
+	if err == nil {
+		return nil
+	}
+	desc := grpc.ErrorDesc(err)
+	if msg := s.String(desc); msg != desc {
+		return grpc.Errorf(grpc.Code(err), "%s", msg)
+	}
+	return err
+}
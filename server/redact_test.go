@@ -0,0 +1,211 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.zenithar.org/password/hashing"
+	pb "go.zenithar.org/password/protocol/password"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	canary = "canary-7f3e9b2d-hunter2"
+	// pinCanary is sent as a JSON number by careless clients
+	pinCanary = "73195286410"
+)
+
+// leakyStrategy echoes passwords in its errors and panics
+type leakyStrategy struct{}
+
+func (leakyStrategy) Name() string { return "leaky" }
+
+func (leakyStrategy) Hash(password []byte) (string, error) {
+	if strings.HasPrefix(string(password), "panic") {
+		panic(fmt.Sprintf("unable to hash '%s'", password))
+	}
+	return "", fmt.Errorf("unable to hash '%s'", password)
+}
+
+func (leakyStrategy) Verify(encoded string, password []byte) (bool, error) {
+	return false, fmt.Errorf("unable to verify '%s' against '%s'", password, encoded)
+}
+
+func (leakyStrategy) Parameters(encoded string) (map[string]string, error) {
+	return map[string]string{}, nil
+}
+
+func (leakyStrategy) NeedsUpgrade(encoded string) bool { return false }
+
+// syncBuffer serializes log writes of concurrent calls
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestPasswordReqFormat(t *testing.T) {
+	req := &pb.PasswordReq{Password: canary, Hash: "leaky$" + canary, Subject: "alice"}
+
+	for _, format := range []string{"%v", "%+v", "%s", "%#v", "%q", "%x"} {
+		out := fmt.Sprintf(format, req)
+		if strings.Contains(out, canary) {
+			t.Errorf("%s formatting leaks the password: %s", format, out)
+		}
+	}
+	if out := fmt.Sprintf("%v", req); !strings.Contains(out, "alice") {
+		t.Errorf("subject should be kept, got %s", out)
+	}
+}
+
+func TestSecretRedaction(t *testing.T) {
+	// Generated String() and text format helpers
+	requests := []proto.Message{
+		&pb.PasswordReq{Password: canary, Hash: "leaky$" + canary, History: []string{"leaky$" + canary}},
+		&pb.HistoryReq{Password: canary, Hashes: []string{"leaky$" + canary}},
+		&pb.ChangeReq{OldPassword: canary, Hash: "leaky$" + canary, NewPassword: canary},
+		&pb.CredentialReq{Subject: "alice", Password: canary},
+	}
+	for _, req := range requests {
+		for name, out := range map[string]string{
+			"String":            req.String(),
+			"CompactTextString": proto.CompactTextString(req),
+			"MarshalTextString": proto.MarshalTextString(req),
+		} {
+			if strings.Contains(out, canary) {
+				t.Errorf("%T %s leaks the password: %s", req, name, out)
+			}
+		}
+	}
+
+	registry, err := hashing.NewRegistry(leakyStrategy{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logs := &syncBuffer{}
+	logger := logrus.New()
+	logger.Out = logs
+	logger.Level = logrus.DebugLevel
+
+	tracer := mocktracer.New()
+
+	addr, stop := startTestServer(t,
+		WithRegistry(registry),
+		WithHashing("leaky", 16),
+		WithLogger(logrus.NewEntry(logger)),
+		WithTracer(tracer),
+	)
+	defer stop()
+
+	var responses []string
+
+	// gRPC calls
+	client, closeClient := dialTestServer(t, addr)
+	defer closeClient()
+
+	grpcCalls := []struct {
+		name string
+		call func() (fmt.Stringer, error)
+	}{
+		{"encode error", func() (fmt.Stringer, error) {
+			return client.Encode(context.Background(), &pb.PasswordReq{Password: canary})
+		}},
+		{"encode panic", func() (fmt.Stringer, error) {
+			return client.Encode(context.Background(), &pb.PasswordReq{Password: "panic" + canary})
+		}},
+		{"validate error", func() (fmt.Stringer, error) {
+			return client.Validate(context.Background(), &pb.PasswordReq{Password: canary, Hash: "leaky$" + canary})
+		}},
+	}
+	for _, c := range grpcCalls {
+		res, err := c.call()
+		if err != nil {
+			responses = append(responses, c.name+": "+err.Error())
+			continue
+		}
+		responses = append(responses, c.name+": "+res.String())
+	}
+
+	// Gateway calls, decoding errors may echo body fragments
+	httpCalls := []struct {
+		path      string
+		body      string
+		malformed bool
+	}{
+		{"/v1/password", `{"password":"` + canary + `"}`, false},
+		{"/v1/password", `{"password":"panic` + canary + `"}`, false},
+		{"/v1/password", `{"` + canary + `":"value"}`, false},
+		{"/v1/password", `{"password":` + canary + `}`, true},
+		{"/v1/password", `{"password":` + pinCanary + `}`, true},
+		{"/v1/validate", `{"password":"` + canary + `","hash":"leaky$` + canary + `"}`, false},
+		{"/v1/validate", `{"password":"` + canary + `","hash":"leaky$` + canary, true},
+	}
+	for _, c := range httpCalls {
+		res, err := http.Post("http://"+addr+c.path, "application/json", strings.NewReader(c.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if c.malformed && !strings.Contains(string(body), errMalformedBody.Error()) {
+			t.Errorf("decoding error details should be hidden, got %s", body)
+		}
+		responses = append(responses, fmt.Sprintf("%s %d: %s", c.path, res.StatusCode, body))
+	}
+
+	for _, r := range responses {
+		if strings.Contains(r, canary) || strings.Contains(r, pinCanary) {
+			t.Errorf("response leaks the password: %s", r)
+		}
+	}
+
+	if !strings.Contains(logs.String(), "panic grpc") {
+		t.Errorf("panic should be logged, got:\n%s", logs.String())
+	}
+	if strings.Contains(logs.String(), canary) {
+		t.Errorf("logs leak the password:\n%s", logs.String())
+	}
+
+	spans := tracer.FinishedSpans()
+	if len(spans) == 0 {
+		t.Fatal("spans should be recorded")
+	}
+	for _, span := range spans {
+		if strings.Contains(span.OperationName, canary) {
+			t.Errorf("span %s name leaks the password", span.OperationName)
+		}
+		for k, v := range span.Tags() {
+			if strings.Contains(fmt.Sprint(v), canary) {
+				t.Errorf("span %s tag %s leaks the password: %v", span.OperationName, k, v)
+			}
+		}
+		for _, record := range span.Logs() {
+			for _, field := range record.Fields {
+				if strings.Contains(field.ValueString, canary) {
+					t.Errorf("span %s log %s leaks the password: %s", span.OperationName, field.Key, field.ValueString)
+				}
+			}
+		}
+	}
+}
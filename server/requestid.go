@@ -0,0 +1,125 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware"
+	"github.com/grpc-ecosystem/go-grpc-middleware/tags"
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// RequestIDHeader is the HTTP header carrying the request correlation ID
+	RequestIDHeader = "X-Request-ID"
+
+	// requestIDKey is the gRPC metadata key carrying the request ID
+	requestIDKey = "x-request-id"
+	// requestIDTag is the log field of the request ID
+	requestIDTag = "request.id"
+	// maxRequestIDLength bounds caller supplied request IDs
+	maxRequestIDLength = 128
+)
+
+type requestIDCtxKey struct{}
+
+// RequestIDFromContext returns the request ID of the current HTTP or gRPC call
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDCtxKey{}).(string)
+	return id, ok
+}
+
+// requestIDHandler accepts the caller request ID, or generates one, and
+// echoes it in the response.
+func requestIDHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDCtxKey{}, id)))
+	})
+}
+
+// requestIDMetadata forwards the gateway request ID to the gRPC server
+func requestIDMetadata(ctx context.Context, r *http.Request) metadata.MD {
+	id, ok := RequestIDFromContext(r.Context())
+	if !ok {
+		return nil
+	}
+	return metadata.Pairs(requestIDKey, id)
+}
+
+// gatewayHeaderMatcher forwards gRPC response metadata as HTTP headers, the
+// request ID is already echoed by requestIDHandler.
+func gatewayHeaderMatcher(key string) (string, bool) {
+	if key == requestIDKey {
+		return "", false
+	}
+	return runtime.MetadataHeaderPrefix + key, true
+}
+
+// requestIDUnaryServerInterceptor tags the call with the request ID received
+// as metadata, or a new one, and returns it as response header.
+func requestIDUnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, id := withRequestID(ctx)
+		grpc.SetHeader(ctx, metadata.Pairs(requestIDKey, id))
+		return handler(ctx, req)
+	}
+}
+
+// requestIDStreamServerInterceptor is the streaming counterpart of
+// requestIDUnaryServerInterceptor.
+func requestIDStreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, id := withRequestID(stream.Context())
+		stream.SetHeader(metadata.Pairs(requestIDKey, id))
+
+		wrapped := grpc_middleware.WrapServerStream(stream)
+		wrapped.WrappedContext = ctx
+		return handler(srv, wrapped)
+	}
+}
+
+func withRequestID(ctx context.Context) (context.Context, string) {
+	var id string
+	if md, ok := metadata.FromIncomingContext(ctx); ok && len(md[requestIDKey]) > 0 {
+		id = md[requestIDKey][0]
+	}
+	if !validRequestID(id) {
+		id = newRequestID()
+	}
+
+	grpc_ctxtags.Extract(ctx).Set(requestIDTag, id)
+
+	return context.WithValue(ctx, requestIDCtxKey{}, id), id
+}
+
+// validRequestID accepts bounded printable ASCII identifiers, to keep caller
+// input from forging log lines.
+func validRequestID(id string) bool {
+	if len(id) == 0 || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
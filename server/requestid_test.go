@@ -0,0 +1,121 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestValidRequestID(t *testing.T) {
+	tests := []struct {
+		id    string
+		valid bool
+	}{
+		{"4bf92f3577b34da6", true},
+		{"req-42/login_service:7", true},
+		{strings.Repeat("a", maxRequestIDLength), true},
+		{"", false},
+		{strings.Repeat("a", maxRequestIDLength+1), false},
+		{"req 42", false},
+		{"req\n42", false},
+		{"req\x7f42", false},
+		{"reqé42", false},
+	}
+	for _, tt := range tests {
+		if validRequestID(tt.id) != tt.valid {
+			t.Errorf("validRequestID(%q) should be %v", tt.id, tt.valid)
+		}
+	}
+}
+
+// logLines decodes the JSON log lines of the given method
+func logLines(t *testing.T, logs *syncBuffer, method string) []map[string]interface{} {
+	var lines []map[string]interface{}
+	for _, raw := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+		var line map[string]interface{}
+		if err := json.Unmarshal([]byte(raw), &line); err != nil {
+			t.Fatalf("log line %q is not JSON, %v", raw, err)
+		}
+		if line["grpc.method"] == method {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func TestRequestID(t *testing.T) {
+	logs := &syncBuffer{}
+	logger := logrus.New()
+	logger.Out = logs
+	logger.Formatter = &logrus.JSONFormatter{}
+
+	addr, stop := startTestServer(t, WithLogger(logrus.NewEntry(logger)))
+	defer stop()
+	client, closeClient := dialTestServer(t, addr)
+	defer closeClient()
+
+	tests := []struct {
+		name string
+		id   string
+		kept bool
+	}{
+		{"valid", "req-42", true},
+		{"forged log fields", "req-42 level=error", false},
+		{"oversized", strings.Repeat("a", maxRequestIDLength+1), false},
+		{"missing", "", false},
+	}
+	for _, tt := range tests {
+		// gRPC calls receive the ID as response header
+		ctx := context.Background()
+		if len(tt.id) > 0 {
+			ctx = metadata.NewOutgoingContext(ctx, metadata.Pairs(requestIDKey, tt.id))
+		}
+		var header metadata.MD
+		if _, err := client.Ping(ctx, &empty.Empty{}, grpc.Header(&header)); err != nil {
+			t.Fatal(err)
+		}
+		if ids := header[requestIDKey]; len(ids) != 1 || (ids[0] == tt.id) != tt.kept || !validRequestID(ids[0]) {
+			t.Errorf("%s: gRPC request ID = %v, expected kept %v", tt.name, ids, tt.kept)
+		}
+
+		// HTTP calls receive it as response header
+		req, _ := http.NewRequest(http.MethodPost, "http://"+addr+"/v1/password", strings.NewReader(`{"password":"correct horse"}`))
+		if len(tt.id) > 0 {
+			req.Header.Set(RequestIDHeader, tt.id)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if id := res.Header.Get(RequestIDHeader); (id == tt.id) != tt.kept || !validRequestID(id) {
+			t.Errorf("%s: HTTP request ID = %q, expected kept %v", tt.name, id, tt.kept)
+		}
+	}
+
+	// The gateway forwards the HTTP request ID to the gRPC server
+	req, _ := http.NewRequest(http.MethodPost, "http://"+addr+"/v1/password", strings.NewReader(`{"password":"correct horse"}`))
+	req.Header.Set(RequestIDHeader, "gateway-42")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	found := false
+	for _, line := range logLines(t, logs, "Encode") {
+		if line[requestIDTag] == "gateway-42" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("gRPC call log should carry the gateway request ID")
+	}
+}
@@ -2,93 +2,791 @@ package server
 
 import (
 	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
 	"net"
 	"net/http"
+	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"go.zenithar.org/password/audit"
+	"go.zenithar.org/password/auth"
+	"go.zenithar.org/password/hashing"
+	"go.zenithar.org/password/similarity"
+	"go.zenithar.org/password/store"
+	"go.zenithar.org/password/utils/secret"
+
+	opentracing "github.com/opentracing/opentracing-go"
 	"github.com/sirupsen/logrus"
 	"github.com/soheilhy/cmux"
+	"go.zenithar.org/butcher"
+	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc"
 )
 
+// logrusEntry is the default server logger, it is also the gRPC library logger
+// which is shared by the whole process.
 var logrusEntry = logrus.NewEntry(logrus.New())
 
+// ErrServerStarted is raised when starting a server which is already running
+var ErrServerStarted = errors.New("server: already started")
+
 // MicroServer represents a microservice server instance
 type MicroServer struct {
+	mu         sync.Mutex
 	serverName string
 	lis        net.Listener
-	httpServer *http.Server
-	grpcServer *grpc.Server
+	running    *run
+
+	gatewayTransport string
+	socketPath       string
+	socketMode       os.FileMode
+	readTimeout      time.Duration
+	writeTimeout     time.Duration
+	idleTimeout      time.Duration
+	trustedProxies   proxies
+	algorithm        string
+	saltLength       int
+	normalization    string
+	dummyValidation  bool
+	validateFloor    time.Duration
+
+	authenticator auth.Authenticator
+	policy        *auth.Policy
+	auditor       *audit.Logger
+	publicMethods []string
+	publicPaths   []string
+	guard         *authGuard
+
+	logger              *logrus.Entry
+	butcher             *butcher.Butcher
+	registry            *hashing.Registry
+	saltSource          *hashing.SaltSource
+	compliance          *hashing.Compliance
+	workerCount         int
+	queueSize           int
+	workers             *workerPool
+	entropy             *entropyMonitor
+	unaryInterceptors   []grpc.UnaryServerInterceptor
+	streamInterceptors  []grpc.StreamServerInterceptor
+	services            []ServiceRegistrar
+	routes              []route
+	readiness           *readiness
+	selfTest            *selfTest
+	drainDelay          time.Duration
+	validateLogSampling int
+	lockMemory          bool
+	maxPasswordLength   int
+	maxHashLength       int
+	maxHistory          int
+	historyConcurrency  int
+	changePolicy        similarity.Policy
+	store               store.Store
+	tracer              opentracing.Tracer
+	gateway             bool
+	metrics             bool
+	reflection          bool
+}
+
+// ServiceRegistrar registers an additional service on the gRPC server
+type ServiceRegistrar func(s *grpc.Server)
+
+// route defines an additional HTTP handler
+type route struct {
+	pattern string
+	handler http.Handler
+}
+
+// Option defines microserver option setting function signature
+type Option func(*MicroServer)
+
+// WithGatewayTransport defines how the gRPC gateway reaches the gRPC server
+// (GatewayInProcess or GatewayUnix)
+func WithGatewayTransport(transport string) Option {
+	return func(ms *MicroServer) {
+		ms.gatewayTransport = transport
+	}
+}
+
+// WithSocketPath defines the unix socket used by the gRPC gateway
+func WithSocketPath(path string) Option {
+	return func(ms *MicroServer) {
+		ms.socketPath = path
+	}
+}
+
+// WithSocketMode defines the unix socket file permissions
+func WithSocketMode(mode os.FileMode) Option {
+	return func(ms *MicroServer) {
+		ms.socketMode = mode
+	}
+}
+
+// WithHTTPTimeouts defines the HTTP server timeouts
+func WithHTTPTimeouts(read, write, idle time.Duration) Option {
+	return func(ms *MicroServer) {
+		ms.readTimeout = read
+		ms.writeTimeout = write
+		ms.idleTimeout = idle
+	}
+}
+
+// WithTrustedProxies defines the networks of proxies allowed to forward the
+// caller address in X-Forwarded-For, connections from the gateway internal
+// transport are always trusted.
+func WithTrustedProxies(networks ...*net.IPNet) Option {
+	return func(ms *MicroServer) {
+		ms.trustedProxies = networks
+	}
+}
+
+// WithHashing defines the password hashing algorithm and salt length
+func WithHashing(algorithm string, saltLength int) Option {
+	return func(ms *MicroServer) {
+		ms.algorithm = algorithm
+		ms.saltLength = saltLength
+	}
+}
+
+// WithAuthenticator enables bearer token authentication of callers
+func WithAuthenticator(a auth.Authenticator) Option {
+	return func(ms *MicroServer) {
+		ms.authenticator = a
+	}
+}
+
+// WithPolicy enables role based authorization of authenticated callers
+func WithPolicy(p *auth.Policy) Option {
+	return func(ms *MicroServer) {
+		ms.policy = p
+	}
+}
+
+// WithAuditLogger enables audit trail of password operations
+func WithAuditLogger(l *audit.Logger) Option {
+	return func(ms *MicroServer) {
+		ms.auditor = l
+	}
+}
+
+// WithPublicMethods defines gRPC full method names reachable without authentication
+func WithPublicMethods(methods ...string) Option {
+	return func(ms *MicroServer) {
+		ms.publicMethods = methods
+	}
+}
+
+// WithPublicPaths defines HTTP paths reachable without authentication
+func WithPublicPaths(paths ...string) Option {
+	return func(ms *MicroServer) {
+		ms.publicPaths = paths
+	}
+}
+
+// WithLogger defines the logger used for requests and server events
+func WithLogger(logger *logrus.Entry) Option {
+	return func(ms *MicroServer) {
+		ms.logger = logger
+	}
+}
+
+// WithButcher defines the password hasher instance, it overrides algorithm and
+// salt length given by WithHashing.
+func WithButcher(b *butcher.Butcher) Option {
+	return func(ms *MicroServer) {
+		ms.butcher = b
+	}
+}
+
+// WithRegistry defines the hashing strategies available to the server, the
+// algorithm given by WithHashing must be registered. Vendored strategies are
+// used by default.
+func WithRegistry(r *hashing.Registry) Option {
+	return func(ms *MicroServer) {
+		ms.registry = r
+	}
+}
+
+// WithWorkers bounds concurrent hashing operations, at most queueSize
+// operations wait for one of the workers before being rejected.
+func WithWorkers(workers, queueSize int) Option {
+	return func(ms *MicroServer) {
+		ms.workerCount = workers
+		ms.queueSize = queueSize
+	}
+}
+
+// WithNormalization defines the Unicode normalization profile applied to
+// passwords before hashing. Hashes record their profile, which is used to
+// verify them whatever the current one.
+func WithNormalization(profile string) Option {
+	return func(ms *MicroServer) {
+		ms.normalization = profile
+	}
+}
+
+// WithDummyValidation makes Validate verify the password against a server held
+// hash of the current algorithm when the hash is empty, and report it as
+// invalid. Callers use it for unknown subjects, to not leak their existence
+// through response time.
+func WithDummyValidation(enabled bool) Option {
+	return func(ms *MicroServer) {
+		ms.dummyValidation = enabled
+	}
+}
+
+// WithValidateFloor defines the minimum Validate response time, it should
+// exceed the slowest verification to hide the algorithm of the hash.
+func WithValidateFloor(floor time.Duration) Option {
+	return func(ms *MicroServer) {
+		ms.validateFloor = floor
+	}
+}
+
+// WithInputLimits bounds password and hash lengths in bytes, longer values
+// are rejected before hashing. gRPC message and gateway body sizes are
+// bounded accordingly.
+func WithInputLimits(maxPassword, maxHash int) Option {
+	return func(ms *MicroServer) {
+		ms.maxPasswordLength = maxPassword
+		ms.maxHashLength = maxHash
+	}
+}
+
+// WithHistory bounds the number of previous hashes checked by CheckHistory
+// and Encode, and the number of them verified at once by a call.
+func WithHistory(maxHashes, concurrency int) Option {
+	return func(ms *MicroServer) {
+		ms.maxHistory = maxHashes
+		ms.historyConcurrency = concurrency
+	}
+}
+
+// WithChangePolicy defines the similarity policy applied by CheckChange
+func WithChangePolicy(p similarity.Policy) Option {
+	return func(ms *MicroServer) {
+		ms.changePolicy = p
+	}
+}
+
+// WithCredentialStore enables the managed credential store mode, where
+// SetPassword, VerifyPassword and DeletePassword keep hashes in the given
+// store. The store is not closed by the server.
+func WithCredentialStore(s store.Store) Option {
+	return func(ms *MicroServer) {
+		ms.store = s
+	}
+}
+
+// WithCompliance restricts hashing algorithms usable by Encode, hashes of
+// other algorithms are flagged as needing a rehash on validation.
+func WithCompliance(c *hashing.Compliance) Option {
+	return func(ms *MicroServer) {
+		ms.compliance = c
+	}
+}
+
+// WithMemoryLocking locks cleartext password buffers in RAM, to keep them
+// out of swap (best effort, requires CAP_IPC_LOCK or a sufficient
+// RLIMIT_MEMLOCK)
+func WithMemoryLocking(enabled bool) Option {
+	return func(ms *MicroServer) {
+		ms.lockMemory = enabled
+	}
+}
+
+// WithSaltSource defines the salt source used by vendored strategies, its
+// failures are reported in metrics and health. Custom registries should share
+// it to be monitored.
+func WithSaltSource(source *hashing.SaltSource) Option {
+	return func(ms *MicroServer) {
+		ms.saltSource = source
+	}
+}
+
+// WithUnaryInterceptors appends unary interceptors to the gRPC chain, they are
+// invoked after authentication, authorization and audit interceptors.
+func WithUnaryInterceptors(interceptors ...grpc.UnaryServerInterceptor) Option {
+	return func(ms *MicroServer) {
+		ms.unaryInterceptors = append(ms.unaryInterceptors, interceptors...)
+	}
+}
+
+// WithStreamInterceptors appends stream interceptors to the gRPC chain, they
+// are invoked after the authentication interceptor.
+func WithStreamInterceptors(interceptors ...grpc.StreamServerInterceptor) Option {
+	return func(ms *MicroServer) {
+		ms.streamInterceptors = append(ms.streamInterceptors, interceptors...)
+	}
+}
+
+// WithServices registers additional services on the gRPC server
+func WithServices(registrars ...ServiceRegistrar) Option {
+	return func(ms *MicroServer) {
+		ms.services = append(ms.services, registrars...)
+	}
+}
+
+// WithHandler mounts an additional HTTP handler, it is protected by
+// authentication unless its pattern is declared as a public path. The "/"
+// pattern is reserved to the gateway when it is enabled.
+func WithHandler(pattern string, handler http.Handler) Option {
+	return func(ms *MicroServer) {
+		ms.routes = append(ms.routes, route{pattern: pattern, handler: handler})
+	}
+}
+
+// WithDrainDelay defines how long the server keeps accepting traffic while
+// reporting not ready, before draining calls on shutdown. It gives load
+// balancers time to stop routing new requests.
+func WithDrainDelay(delay time.Duration) Option {
+	return func(ms *MicroServer) {
+		ms.drainDelay = delay
+	}
+}
+
+// WithSelfTest enables hashing self-tests at start, readiness is withheld until
+// they pass (enabled by default)
+func WithSelfTest(enabled bool) Option {
+	return func(ms *MicroServer) {
+		ms.selfTest = nil
+		if enabled {
+			ms.selfTest = &selfTest{}
+		}
+	}
+}
+
+// WithValidateLogSampling logs only one out of n successful Validate calls at
+// info level, the others are logged at debug level (1 logs every call)
+func WithValidateLogSampling(n int) Option {
+	return func(ms *MicroServer) {
+		ms.validateLogSampling = n
+	}
+}
+
+// WithTracer defines the tracer used by gRPC and HTTP spans (opentracing
+// global tracer by default)
+func WithTracer(t opentracing.Tracer) Option {
+	return func(ms *MicroServer) {
+		ms.tracer = t
+	}
+}
+
+// WithGateway enables the gRPC to JSON gateway (enabled by default)
+func WithGateway(enabled bool) Option {
+	return func(ms *MicroServer) {
+		ms.gateway = enabled
+	}
+}
+
+// WithMetrics enables the prometheus interceptors and endpoint (enabled by default)
+func WithMetrics(enabled bool) Option {
+	return func(ms *MicroServer) {
+		ms.metrics = enabled
+	}
+}
+
+// WithReflection enables the gRPC reflection service (enabled by default)
+func WithReflection(enabled bool) Option {
+	return func(ms *MicroServer) {
+		ms.reflection = enabled
+	}
 }
 
 // New returns a microserver instance
-func New(serverName string, l net.Listener) *MicroServer {
-	return &MicroServer{
-		serverName: serverName,
-		lis:        l,
+func New(serverName string, l net.Listener, opts ...Option) *MicroServer {
+	ms := &MicroServer{
+		serverName:          serverName,
+		lis:                 l,
+		gatewayTransport:    GatewayInProcess,
+		socketPath:          "service.sock",
+		socketMode:          0600,
+		readTimeout:         5 * time.Second,
+		writeTimeout:        10 * time.Second,
+		idleTimeout:         120 * time.Second,
+		algorithm:           butcher.DefaultAlgorithm,
+		saltLength:          64,
+		normalization:       hashing.NormalizationNone,
+		maxPasswordLength:   1024,
+		maxHashLength:       1024,
+		maxHistory:          24,
+		historyConcurrency:  4,
+		changePolicy:        similarity.DefaultPolicy,
+		publicMethods:       auth.DefaultPublicMethods,
+		publicPaths:         auth.DefaultPublicPaths,
+		logger:              logrusEntry,
+		readiness:           newReadiness(),
+		workerCount:         runtime.NumCPU(),
+		queueSize:           256,
+		validateLogSampling: 1,
+		selfTest:            &selfTest{},
+		gateway:             true,
+		metrics:             true,
+		reflection:          true,
+	}
+
+	for _, opt := range opts {
+		opt(ms)
+	}
+	if ms.tracer == nil {
+		ms.tracer = opentracing.GlobalTracer()
 	}
+	if ms.selfTest != nil {
+		ms.selfTest.readiness = ms.readiness
+		ms.selfTest.logger = ms.logger
+	}
+
+	// Hashing workers
+	if ms.workerCount <= 0 {
+		ms.workerCount = runtime.NumCPU()
+	}
+	ms.workers = newWorkerPool(ms.workerCount, ms.queueSize, ms.readiness)
+
+	// Password buffers locking
+	if ms.lockMemory {
+		if err := secret.CheckLocking(); err != nil {
+			ms.logger.WithError(err).Warn("Password buffers can't be locked in memory, they may be swapped")
+		}
+	}
+
+	// Salt source monitoring
+	if ms.saltSource == nil {
+		ms.saltSource = hashing.NewSaltSource(rand.Reader, ms.saltLength)
+	}
+	ms.entropy = &entropyMonitor{
+		readiness: ms.readiness,
+		logger:    ms.logger,
+	}
+	ms.saltSource.Observe(ms.entropy.observe)
+
+	// Authentication
+	ms.guard = newAuthGuard(ms.logger, ms.authenticator, ms.policy, ms.auditor, ms.publicMethods, ms.publicPaths)
+	if ms.policy != nil && ms.authenticator == nil {
+		ms.logger.Warn("Authorization policy is defined without authentication, all non public calls will be denied")
+	}
+
+	return ms
+}
+
+// Reload applies the given options at runtime. Only authentication,
+// authorization policy and public endpoints settings are refreshed, calls in
+// progress complete with previous settings. Other settings require a restart.
+func (ms *MicroServer) Reload(opts ...Option) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	for _, opt := range opts {
+		opt(ms)
+	}
+
+	ms.guard.update(ms.authenticator, ms.policy, ms.publicMethods, ms.publicPaths)
+}
+
+// maxMessageSize returns the largest accepted gRPC request, old and new
+// passwords, hash and history plus room for the subject and protobuf framing
+func (ms *MicroServer) maxMessageSize() int {
+	hashes := 1 + ms.maxHistory
+	return 2*ms.maxPasswordLength + hashes*(ms.maxHashLength+8) + 1024
+}
+
+// maxBodySize returns the largest accepted gateway request body, JSON string
+// escaping may expand a byte up to 6 characters
+func (ms *MicroServer) maxBodySize() int64 {
+	hashes := 1 + ms.maxHistory
+	return int64(6*(2*ms.maxPasswordLength+hashes*ms.maxHashLength) + 8*hashes + 1024)
+}
+
+// SetLogLevel changes the default server logger verbosity. It applies to the
+// whole process as the gRPC library logs through the default logger, even when
+// servers use WithLogger.
+func SetLogLevel(level logrus.Level) {
+	logrusEntry.Logger.SetLevel(level)
+}
+
+// SetLogFormatter changes the default server logger output format. As
+// SetLogLevel, it applies to the whole process.
+func SetLogFormatter(f logrus.Formatter) {
+	logrusEntry.Logger.Formatter = f
 }
 
 // -----------------------------------------------------------------------------
 
-// Start the microserver
+// Start the microserver on the listener given at construction, it blocks until
+// the server is shut down or one of its listeners fails.
 func (ms *MicroServer) Start() error {
-	var err error
+	return ms.Serve(ms.lis)
+}
 
-	ctx := context.Background()
-	ctx, cancel := context.WithCancel(ctx)
-	defer cancel()
+// Serve runs the microserver on the given listener, it blocks until the server
+// is shut down or one of its listeners fails. The first failure stops the other
+// listeners and is returned, a requested shutdown returns nil. Serve can be
+// called again with a new listener once it has returned.
+func (ms *MicroServer) Serve(l net.Listener) error {
+	ms.mu.Lock()
+	if ms.running != nil {
+		ms.mu.Unlock()
+		return ErrServerStarted
+	}
 
-	// Initialize unix socket
-	unixL, err := net.Listen("unix", "service.sock")
+	ms.readiness.set(ReasonShutdown, true)
+	r, err := ms.prepareRun(l)
 	if err != nil {
-		logrus.WithError(err).Error("Unable to create unix socket")
+		ms.mu.Unlock()
 		return err
 	}
+	ms.lis = l
+	ms.running = r
+	ms.mu.Unlock()
 
-	// tcpMuxer
-	tcpMux := cmux.New(ms.lis)
+	// Known answer tests gate readiness
+	if ms.selfTest != nil {
+		ms.selfTest.start(r.registry)
+	}
 
-	// Connection dispatcher rules
-	grpcL := tcpMux.MatchWithWriters(cmux.HTTP2MatchHeaderFieldPrefixSendSettings("content-type", "application/grpc"))
-	httpL := tcpMux.Match(cmux.HTTP1Fast())
+	defer func() {
+		ms.mu.Lock()
+		ms.running = nil
+		ms.mu.Unlock()
+		close(r.done)
+	}()
+
+	g, gctx := errgroup.WithContext(r.ctx)
+	g.Go(func() error {
+		return r.served("external gRPC server", r.grpcServer.Serve(r.grpcL))
+	})
+	if r.internalL != nil {
+		g.Go(func() error {
+			return r.served("internal gRPC server", r.grpcServer.Serve(r.internalL))
+		})
+	}
+	g.Go(func() error {
+		return r.served("HTTP server", r.httpServer.Serve(r.httpL))
+	})
+	g.Go(func() error {
+		return r.served("connection multiplexer", r.mux.Serve())
+	})
+
+	// First failure or shutdown request stops everything
+	g.Go(func() error {
+		<-gctx.Done()
+		r.stop()
+		return nil
+	})
+
+	return g.Wait()
+}
+
+// prepareRun initializes listeners and servers of a new run
+func (ms *MicroServer) prepareRun(l net.Listener) (*run, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// Initialize gateway internal listener
+	var (
+		internalL net.Listener
+		dialer    gatewayDialer
+		err       error
+	)
+	if ms.gateway {
+		internalL, dialer, err = ms.gatewayListener()
+		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("server: unable to create gateway listener, %v", err)
+		}
+	}
+	release := func() {
+		cancel()
+		if internalL != nil {
+			internalL.Close()
+		}
+	}
+
+	// Hashing strategies
+	registry := ms.registry
+	if registry == nil {
+		registry, err = hashing.DefaultRegistry(ms.saltSource)
+		if err != nil {
+			release()
+			return nil, fmt.Errorf("server: unable to initialize hashing strategies, %v", err)
+		}
+	}
 
 	// initialize gRPC server instance
-	ms.grpcServer, err = prepareGRPC(ctx)
+	grpcServer, err := prepareGRPC(ctx, ms, registry)
 	if err != nil {
-		logrus.WithError(err).Error("Unable to initialize gRPC server instance")
-		return err
+		release()
+		return nil, fmt.Errorf("server: unable to initialize gRPC server instance, %v", err)
 	}
 
 	// initialize HTTP server
-	ms.httpServer, err = prepareHTTP(ctx, ms.serverName)
+	httpServer, err := prepareHTTP(ctx, ms, ms.guard, dialer)
 	if err != nil {
-		logrus.WithError(err).Error("Unable to initialize HTTP server instance")
-		return err
+		release()
+		return nil, fmt.Errorf("server: unable to initialize HTTP server instance, %v", err)
 	}
 
-	// Start servers
-	go func() {
-		if err := ms.grpcServer.Serve(grpcL); err != nil {
-			logrus.WithError(err).Fatalln("Unable to start external gRPC server")
+	// tcpMuxer, connections sending nothing are dropped as they would hold
+	// the multiplexer, and the HTTP server shutdown with it
+	tcpMux := cmux.New(l)
+	if ms.readTimeout > 0 {
+		tcpMux.SetReadTimeout(ms.readTimeout)
+	}
+
+	r := &run{
+		ctx:        ctx,
+		cancel:     cancel,
+		lis:        l,
+		internalL:  internalL,
+		mux:        tcpMux,
+		grpcServer: grpcServer,
+		httpServer: httpServer,
+		logger:     ms.logger,
+		registry:   registry,
+		done:       make(chan struct{}),
+	}
+	if ms.gateway && ms.gatewayTransport == GatewayUnix {
+		r.socketPath = ms.socketPath
+	}
+
+	// Connection dispatcher rules
+	r.grpcL = tcpMux.MatchWithWriters(cmux.HTTP2MatchHeaderFieldPrefixSendSettings("content-type", "application/grpc"))
+	r.httpL = tcpMux.Match(cmux.HTTP1Fast())
+
+	return r, nil
+}
+
+// Shutdown gracefully stops the microserver. Readiness turns not serving and
+// traffic is still accepted during the drain delay, then new connections are
+// refused, in-flight HTTP and gateway requests are drained before gRPC calls,
+// and listeners are closed last. When the context expires, remaining calls are
+// aborted and the context error is returned.
+func (ms *MicroServer) Shutdown(ctx context.Context) error {
+	ms.mu.Lock()
+	r := ms.running
+	ms.mu.Unlock()
+	if r == nil {
+		return nil
+	}
+
+	// Let load balancers observe the readiness change
+	ms.readiness.set(ReasonShutdown, false)
+	if ms.drainDelay > 0 {
+		ms.logger.Infof("Not ready, draining for %s", ms.drainDelay)
+		select {
+		case <-time.After(ms.drainDelay):
+		case <-r.done:
+		case <-ctx.Done():
 		}
-	}()
-	go func() {
-		if err := ms.grpcServer.Serve(unixL); err != nil {
-			logrus.WithError(err).Fatalln("Unable to start internal gRPC server")
+	}
+
+	err := r.shutdown(ctx)
+
+	// Wait for Serve to return
+	select {
+	case <-r.done:
+	case <-ctx.Done():
+		if err == nil {
+			err = ctx.Err()
 		}
-	}()
-	go func() {
-		if err := ms.httpServer.Serve(httpL); err != nil {
-			logrus.WithError(err).Fatalln("Unable to start HTTP server")
+	}
+
+	return err
+}
+
+// -----------------------------------------------------------------------------
+
+// run holds the state of a single server execution
+type run struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	lis        net.Listener
+	internalL  net.Listener
+	grpcL      net.Listener
+	httpL      net.Listener
+	mux        cmux.CMux
+	grpcServer *grpc.Server
+	httpServer *http.Server
+	socketPath string
+	logger     *logrus.Entry
+	registry   *hashing.Registry
+
+	stopping int32
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// served converts the result of a Serve call, listeners closed on purpose are
+// not failures while any other return is.
+func (r *run) served(name string, err error) error {
+	if atomic.LoadInt32(&r.stopping) == 1 {
+		return nil
+	}
+	if err == nil {
+		err = errors.New("stopped unexpectedly")
+	}
+	r.logger.WithError(err).Errorf("%s failed", name)
+	return fmt.Errorf("server: %s failed, %v", name, err)
+}
+
+func (r *run) shutdown(ctx context.Context) error {
+	atomic.StoreInt32(&r.stopping, 1)
+
+	var errs []error
+
+	// Unlink gateway socket, established connections are not affected
+	if len(r.socketPath) > 0 {
+		if err := removeSocket(r.socketPath); err != nil {
+			errs = append(errs, err)
 		}
+	}
+
+	// Stop accepting connections, in-flight HTTP and gateway requests still
+	// reach the gRPC server while draining.
+	if err := r.httpServer.Shutdown(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("server: HTTP shutdown, %v", err))
+	}
+
+	// Drain gRPC calls, abort them when time limit is reached
+	stopped := make(chan struct{})
+	go func() {
+		r.grpcServer.GracefulStop()
+		close(stopped)
 	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		r.grpcServer.Stop()
+		errs = append(errs, fmt.Errorf("server: gRPC shutdown, %v", ctx.Err()))
+	}
+
+	// Close remaining listeners
+	r.stop()
 
-	return tcpMux.Serve()
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
 }
 
-// Shutdown the microserver
-func (ms *MicroServer) Shutdown(ctx context.Context, stopped chan struct{}) {
-	ms.grpcServer.GracefulStop()
-	ms.httpServer.Shutdown(ctx)
-	stopped <- struct{}{}
+// stop closes every listener and server of the run, it is safe to call it
+// several times.
+func (r *run) stop() {
+	r.stopOnce.Do(func() {
+		atomic.StoreInt32(&r.stopping, 1)
+		r.cancel()
+
+		r.httpServer.Close()
+		r.grpcServer.Stop()
+		r.lis.Close()
+		if r.internalL != nil {
+			r.internalL.Close()
+		}
+		if len(r.socketPath) > 0 {
+			removeSocket(r.socketPath)
+		}
+	})
 }
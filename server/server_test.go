@@ -0,0 +1,172 @@
+package server
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"go.zenithar.org/password/hashing"
+	pb "go.zenithar.org/password/protocol/password"
+
+	"google.golang.org/grpc"
+)
+
+// startTestServer serves a microserver on a loopback listener, self-tests
+// and metrics are disabled. The returned function stops the server.
+func startTestServer(t *testing.T, opts ...Option) (string, func()) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts = append([]Option{WithSelfTest(false), WithMetrics(false)}, opts...)
+	ms := New(l.Addr().String(), l, opts...)
+	go ms.Serve(l)
+
+	return l.Addr().String(), func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		ms.Shutdown(ctx)
+	}
+}
+
+// dialTestServer returns a password service client of the given server
+func dialTestServer(t *testing.T, addr string) (pb.PasswordClient, func()) {
+	conn, err := grpc.Dial(addr, grpc.WithInsecure(), grpc.WithBlock(), grpc.WithTimeout(5*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pb.NewPasswordClient(conn), func() { conn.Close() }
+}
+
+// fakeStrategy stores passwords in clear as "<name>$<password>". It keeps the
+// password buffers it receives, counts verifications and records the peak of
+// concurrent ones.
+type fakeStrategy struct {
+	name string
+	// delay is spent in every verification
+	delay time.Duration
+
+	mu       sync.Mutex
+	buffers  [][]byte
+	contents []string
+	verified int
+	running  int
+	peak     int
+}
+
+func (s *fakeStrategy) record(password []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.buffers = append(s.buffers, password)
+	s.contents = append(s.contents, string(password))
+}
+
+// verifications returns the number of Verify calls
+func (s *fakeStrategy) verifications() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.verified
+}
+
+func (s *fakeStrategy) Name() string { return s.name }
+
+func (s *fakeStrategy) Hash(password []byte) (string, error) {
+	s.record(password)
+	return s.name + "$" + string(password), nil
+}
+
+func (s *fakeStrategy) Verify(encoded string, password []byte) (bool, error) {
+	s.record(password)
+
+	s.mu.Lock()
+	s.verified++
+	s.running++
+	if s.running > s.peak {
+		s.peak = s.running
+	}
+	s.mu.Unlock()
+
+	time.Sleep(s.delay)
+
+	s.mu.Lock()
+	s.running--
+	s.mu.Unlock()
+
+	return encoded == s.name+"$"+string(password), nil
+}
+
+func (s *fakeStrategy) Parameters(encoded string) (map[string]string, error) {
+	return map[string]string{}, nil
+}
+
+func (s *fakeStrategy) NeedsUpgrade(encoded string) bool { return false }
+
+// startFakeServer serves a microserver hashing with the given fake strategies,
+// the first one is the current algorithm. The returned function closes the
+// client and stops the server.
+func startFakeServer(t *testing.T, strategies []*fakeStrategy, opts ...Option) (pb.PasswordClient, func()) {
+	registered := make([]hashing.Strategy, len(strategies))
+	for i, s := range strategies {
+		registered[i] = s
+	}
+	registry, err := hashing.NewRegistry(registered...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts = append([]Option{WithRegistry(registry), WithHashing(strategies[0].name, 16)}, opts...)
+	addr, stop := startTestServer(t, opts...)
+	client, closeClient := dialTestServer(t, addr)
+	return client, func() {
+		closeClient()
+		stop()
+	}
+}
+
+func TestServeRestart(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	ms := New(addr, l, WithSelfTest(false), WithMetrics(false))
+
+	shutdown := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := ms.Shutdown(ctx); err != nil {
+			t.Fatalf("Shutdown() = %v", err)
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		served := make(chan error, 1)
+		go func() { served <- ms.Serve(l) }()
+
+		client, closeConn := dialTestServer(t, addr)
+		if _, err := client.Encode(context.Background(), &pb.PasswordReq{Password: "correct horse"}); err != nil {
+			t.Errorf("run %d: Encode() = %v", i, err)
+		}
+		if err := ms.Serve(l); err != ErrServerStarted {
+			t.Errorf("run %d: concurrent Serve() = %v, expected ErrServerStarted", i, err)
+		}
+		closeConn()
+
+		shutdown()
+		if err := <-served; err != nil {
+			t.Errorf("run %d: Serve() should return nil on shutdown, got %v", i, err)
+		}
+
+		// Listener is closed on shutdown, the same address is bound again
+		if l, err = net.Listen("tcp", addr); err != nil {
+			t.Fatalf("run %d: address should be released on shutdown, got %v", i, err)
+		}
+	}
+	l.Close()
+
+	// Stopped server shutdown is a no-op
+	shutdown()
+}
@@ -0,0 +1,111 @@
+package server
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const (
+	// GatewayInProcess connects the gateway to the gRPC server through memory
+	GatewayInProcess = "inprocess"
+	// GatewayUnix connects the gateway to the gRPC server through a unix socket
+	GatewayUnix = "unix"
+
+	// inProcessBufferSize is the buffer size of each direction of in-process
+	// gateway connections, both peers write their HTTP/2 preface and settings
+	// before reading.
+	inProcessBufferSize = 256 * 1024
+)
+
+// gatewayDialer defines the connection factory used by the gRPC gateway
+type gatewayDialer func(addr string, timeout time.Duration) (net.Conn, error)
+
+// gatewayListener returns the internal gRPC listener and the matching dialer
+// according to the configured transport.
+func (ms *MicroServer) gatewayListener() (net.Listener, gatewayDialer, error) {
+	switch ms.gatewayTransport {
+	case GatewayInProcess:
+		l := bufconn.Listen(inProcessBufferSize)
+		return l, func(_ string, _ time.Duration) (net.Conn, error) {
+			return l.Dial()
+		}, nil
+	case GatewayUnix:
+		l, err := listenUnix(ms.socketPath, ms.socketMode)
+		if err != nil {
+			return nil, nil, err
+		}
+		path := ms.socketPath
+		return l, func(_ string, timeout time.Duration) (net.Conn, error) {
+			return net.DialTimeout("unix", path, timeout)
+		}, nil
+	}
+
+	return nil, nil, fmt.Errorf("server: unsupported gateway transport '%s'", ms.gatewayTransport)
+}
+
+// listenUnix creates a unix socket with the given permissions. The socket is
+// bound in a private directory and moved into place once its permissions are
+// set, it is never reachable with the default ones. A stale socket left by a
+// crashed instance is removed, a socket still in use is an error.
+func listenUnix(path string, mode os.FileMode) (net.Listener, error) {
+	if err := removeStaleSocket(path); err != nil {
+		return nil, err
+	}
+
+	dir, err := ioutil.TempDir(filepath.Dir(path), ".socket")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	private := filepath.Join(dir, filepath.Base(path))
+	l, err := net.ListenUnix("unix", &net.UnixAddr{Name: private, Net: "unix"})
+	if err != nil {
+		return nil, err
+	}
+	// Socket is unlinked by the server shutdown, the private path is gone
+	l.SetUnlinkOnClose(false)
+
+	if err = os.Chmod(private, mode); err == nil {
+		err = os.Rename(private, path)
+	}
+	if err != nil {
+		l.Close()
+		return nil, err
+	}
+
+	return l, nil
+}
+
+func removeStaleSocket(path string) error {
+	fi, err := os.Lstat(path)
+	switch {
+	case os.IsNotExist(err):
+		return nil
+	case err != nil:
+		return err
+	case fi.Mode()&os.ModeSocket == 0:
+		return fmt.Errorf("server: '%s' exists and is not a socket", path)
+	}
+
+	// Check if another instance is still listening
+	if conn, err := net.DialTimeout("unix", path, 100*time.Millisecond); err == nil {
+		conn.Close()
+		return fmt.Errorf("server: socket '%s' is in use by another instance", path)
+	}
+
+	return removeSocket(path)
+}
+
+func removeSocket(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
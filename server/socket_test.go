@@ -0,0 +1,114 @@
+package server
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestListenUnix(t *testing.T) {
+	dir, err := ioutil.TempDir("", "socket")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "service.sock")
+
+	for _, mode := range []os.FileMode{0600, 0660} {
+		l, err := listenUnix(path, mode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fi, err := os.Lstat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi.Mode()&os.ModeSocket == 0 || fi.Mode().Perm() != mode {
+			t.Errorf("socket mode = %v, expected %v", fi.Mode(), mode)
+		}
+
+		// Only the socket is left in the directory
+		if entries, _ := ioutil.ReadDir(dir); len(entries) != 1 {
+			t.Errorf("private directory should be removed, found %d entries", len(entries))
+		}
+
+		// Socket is reachable through its final path
+		go func() {
+			if c, err := l.Accept(); err == nil {
+				c.Close()
+			}
+		}()
+		conn, err := net.Dial("unix", path)
+		if err != nil {
+			t.Fatalf("socket should accept connections, got %v", err)
+		}
+		conn.Close()
+
+		// A socket in use is not replaced
+		if _, err := listenUnix(path, mode); err == nil || !strings.Contains(err.Error(), "in use") {
+			t.Errorf("listenUnix() = %v, expected socket in use", err)
+		}
+
+		// Closing the listener leaves the socket to the server shutdown
+		l.Close()
+		if _, err := os.Lstat(path); err != nil {
+			t.Errorf("socket should be left in place, got %v", err)
+		}
+	}
+
+	// Stale socket is replaced
+	l, err := listenUnix(path, 0600)
+	if err != nil {
+		t.Fatalf("stale socket should be replaced, got %v", err)
+	}
+	l.Close()
+	removeSocket(path)
+
+	// Other files are not
+	if err := ioutil.WriteFile(path, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := listenUnix(path, 0600); err == nil {
+		t.Error("listenUnix should not replace a regular file")
+	}
+}
+
+func TestGatewayTransport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "socket")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "service.sock")
+
+	for _, transport := range []string{GatewayInProcess, GatewayUnix} {
+		addr, stop := startTestServer(t, WithGatewayTransport(transport), WithSocketPath(path))
+
+		res, err := http.Post("http://"+addr+"/v1/password", "application/json", strings.NewReader(`{"password":"correct horse"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			t.Errorf("%s: gateway request should succeed, got %d", transport, res.StatusCode)
+		}
+
+		_, statErr := os.Lstat(path)
+		if (statErr == nil) != (transport == GatewayUnix) {
+			t.Errorf("%s: socket exists = %v", transport, statErr == nil)
+		}
+
+		stop()
+		if _, err := os.Lstat(path); !os.IsNotExist(err) {
+			t.Errorf("%s: socket should be removed on shutdown, got %v", transport, err)
+		}
+	}
+
+	if _, _, err := New("", nil, WithGatewayTransport("tcp")).gatewayListener(); err == nil {
+		t.Error("unsupported gateway transport should be rejected")
+	}
+}
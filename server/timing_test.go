@@ -0,0 +1,65 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	pb "go.zenithar.org/password/protocol/password"
+)
+
+func TestValidateTiming(t *testing.T) {
+	const floor = 50 * time.Millisecond
+
+	slow, fast := &fakeStrategy{name: "slow"}, &fakeStrategy{name: "fast"}
+	strategies := []*fakeStrategy{slow, fast}
+
+	hashes := map[string]string{
+		"fast":        "fast$$$c2FsdA$aGFzaA",
+		"slow":        "slow$$$c2FsdA$aGFzaA",
+		"dummy":       "",
+		"unsupported": "other$$$c2FsdA$aGFzaA",
+	}
+
+	// Unknown subjects cost a full verification of the current algorithm
+	client, stop := startFakeServer(t, strategies, WithDummyValidation(true))
+	res, err := client.Validate(context.Background(), &pb.PasswordReq{Password: "correct horse battery staple"})
+	stop()
+	if err != nil || res.Error != nil || res.Valid {
+		t.Fatalf("dummy validation should be invalid, got %v %v", err, res)
+	}
+	if slow.verifications() != 1 || fast.verifications() != 0 {
+		t.Errorf("dummy validation should verify the current algorithm, got slow %d fast %d", slow.verifications(), fast.verifications())
+	}
+
+	// The floor bounds every response time, whatever the algorithm or
+	// outcome. Only the lower bound is asserted, upper bounds depend on
+	// the scheduler.
+	client, stop = startFakeServer(t, strategies, WithDummyValidation(true), WithValidateFloor(floor))
+	defer stop()
+	for name, hash := range hashes {
+		begin := time.Now()
+		if _, err := client.Validate(context.Background(), &pb.PasswordReq{Password: "correct horse battery staple", Hash: hash}); err != nil {
+			t.Fatalf("%s: Validate() = %v", name, err)
+		}
+		if elapsed := time.Since(begin); elapsed < floor {
+			t.Errorf("%s validation took %v, less than the %v floor", name, elapsed, floor)
+		}
+	}
+}
+
+func TestValidateEmptyHash(t *testing.T) {
+	strategy := &fakeStrategy{name: "plain"}
+	client, stop := startFakeServer(t, []*fakeStrategy{strategy})
+	defer stop()
+
+	// Empty hashes are still rejected without dummy validation
+	res, err := client.Validate(context.Background(), &pb.PasswordReq{Password: "password"})
+	if err != nil || res.GetError().GetCode() != http.StatusPreconditionFailed {
+		t.Errorf("empty hash should be rejected, got %v %v", err, res)
+	}
+	if strategy.verifications() != 0 {
+		t.Error("empty hash should not be verified")
+	}
+}
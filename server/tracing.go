@@ -0,0 +1,93 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"go.zenithar.org/password/utils/tracer"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	otlog "github.com/opentracing/opentracing-go/log"
+	"google.golang.org/grpc/metadata"
+)
+
+// traceHandler starts a server span for each gateway request, continuing the
+// trace of the caller when trace context headers are present.
+func traceHandler(t opentracing.Tracer, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parent, _ := tracer.ExtractHTTP(t, r.Header)
+
+		span := t.StartSpan("HTTP "+r.Method+" "+r.URL.Path, ext.RPCServerOption(parent))
+		defer span.Finish()
+		ext.Component.Set(span, "grpc-gateway")
+		ext.HTTPMethod.Set(span, r.Method)
+		ext.HTTPUrl.Set(span, r.URL.Path)
+		if id, ok := RequestIDFromContext(r.Context()); ok {
+			span.SetTag(requestIDTag, id)
+		}
+
+		rec := &statusRecorder{ResponseWriter: w, code: http.StatusOK}
+		next.ServeHTTP(rec, r.WithContext(opentracing.ContextWithSpan(r.Context(), span)))
+
+		ext.HTTPStatusCode.Set(span, uint16(rec.code))
+		if rec.code >= http.StatusInternalServerError {
+			ext.Error.Set(span, true)
+		}
+	})
+}
+
+// traceMetadata propagates the gateway span to the gRPC server as metadata
+func traceMetadata(t opentracing.Tracer) func(context.Context, *http.Request) metadata.MD {
+	return func(ctx context.Context, r *http.Request) metadata.MD {
+		span := opentracing.SpanFromContext(r.Context())
+		if span == nil {
+			return nil
+		}
+
+		md := metadata.MD{}
+		if err := t.Inject(span.Context(), opentracing.HTTPHeaders, metadataCarrier(md)); err != nil {
+			return nil
+		}
+		return md
+	}
+}
+
+// metadataCarrier adapts gRPC metadata, which requires lowercase keys, to
+// opentracing text map propagation.
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Set(key, val string) {
+	key = strings.ToLower(key)
+	c[key] = append(c[key], val)
+}
+
+func (c metadataCarrier) ForeachKey(handler func(key, val string) error) error {
+	for k, vals := range c {
+		for _, v := range vals {
+			if err := handler(k, v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// startSpan starts a child span of the current call span, if any
+func startSpan(ctx context.Context, t opentracing.Tracer, operation string) opentracing.Span {
+	var opts []opentracing.StartSpanOption
+	if parent := opentracing.SpanFromContext(ctx); parent != nil {
+		opts = append(opts, opentracing.ChildOf(parent.Context()))
+	}
+	return t.StartSpan(operation, opts...)
+}
+
+// finishSpan marks the span as failed when err is not nil, and finishes it
+func finishSpan(span opentracing.Span, err error) {
+	if err != nil {
+		ext.Error.Set(span, true)
+		span.LogFields(otlog.Error(err))
+	}
+	span.Finish()
+}
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"go.zenithar.org/password/utils/tracer"
+
+	"github.com/uber/jaeger-client-go"
+)
+
+func TestGatewayTracePropagation(t *testing.T) {
+	const (
+		traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+		spanID  = "00f067aa0ba902b7"
+	)
+
+	tr, reporter, closer := tracer.NewInMemory("password")
+	defer closer.Close()
+
+	addr, stop := startTestServer(t, WithTracer(tr))
+	req, _ := http.NewRequest(http.MethodPost, "http://"+addr+"/v1/password", strings.NewReader(`{"password":"correct horse"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(tracer.TraceparentHeader, "00-"+traceID+"-"+spanID+"-01")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	stop()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("encode should succeed, got %d", res.StatusCode)
+	}
+
+	parents := map[jaeger.SpanID]jaeger.SpanID{}
+	byName := map[string]jaeger.SpanContext{}
+	for _, s := range reporter.GetSpans() {
+		span := s.(*jaeger.Span)
+		sc := span.Context().(jaeger.SpanContext)
+		parents[sc.SpanID()] = sc.ParentID()
+		byName[span.OperationName()] = sc
+	}
+
+	caller, _ := tracer.ParseTraceparent("00-" + traceID + "-" + spanID + "-01")
+	for _, name := range []string{"/password.Password/Encode", "password.hash"} {
+		sc, ok := byName[name]
+		if !ok {
+			t.Errorf("%s span should be reported, got %v", name, byName)
+			continue
+		}
+		if sc.TraceID() != caller.TraceID() {
+			t.Errorf("%s span should continue the caller trace, got %s", name, sc.TraceID())
+		}
+
+		// The caller span is an ancestor
+		id, found := sc.ParentID(), false
+		for i := 0; i < len(parents) && id != 0; i++ {
+			if id == caller.SpanID() {
+				found = true
+				break
+			}
+			id = parents[id]
+		}
+		if !found {
+			t.Errorf("%s span should descend from the caller span", name)
+		}
+	}
+}
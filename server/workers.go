@@ -0,0 +1,76 @@
+package server
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// ReasonSaturated is the readiness condition raised while the hashing queue is full
+const ReasonSaturated = "saturated"
+
+var errQueueFull = grpc.Errorf(codes.ResourceExhausted, "hashing queue is full")
+
+// workerPool bounds concurrent hashing operations, callers wait for a worker
+// in a bounded queue. Readiness is withheld from the time the queue is full until it
+// is half empty.
+type workerPool struct {
+	slots     chan struct{}
+	queueSize int32
+	waiting   int32
+	saturated int32
+	readiness *readiness
+}
+
+func newWorkerPool(workers, queueSize int, rd *readiness) *workerPool {
+	return &workerPool{
+		slots:     make(chan struct{}, workers),
+		queueSize: int32(queueSize),
+		readiness: rd,
+	}
+}
+
+// do runs fn on a worker, errQueueFull is returned when the queue is full
+func (p *workerPool) do(ctx context.Context, fn func()) error {
+	depth := atomic.AddInt32(&p.waiting, 1)
+	queueDepth.Inc()
+	if depth >= p.queueSize {
+		p.saturate()
+	}
+	if depth > p.queueSize {
+		p.leave()
+		return errQueueFull
+	}
+
+	start := time.Now()
+	select {
+	case p.slots <- struct{}{}:
+	case <-ctx.Done():
+		p.leave()
+		return grpc.Errorf(codes.Canceled, "hashing request cancelled while queued, %v", ctx.Err())
+	}
+	p.leave()
+	observe(ctx, queueWait, time.Since(start).Seconds())
+
+	defer func() { <-p.slots }()
+	fn()
+
+	return nil
+}
+
+func (p *workerPool) saturate() {
+	if atomic.CompareAndSwapInt32(&p.saturated, 0, 1) {
+		p.readiness.set(ReasonSaturated, false)
+	}
+}
+
+func (p *workerPool) leave() {
+	depth := atomic.AddInt32(&p.waiting, -1)
+	queueDepth.Dec()
+	if depth <= p.queueSize/2 && atomic.CompareAndSwapInt32(&p.saturated, 1, 0) {
+		p.readiness.set(ReasonSaturated, true)
+	}
+}
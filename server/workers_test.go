@@ -0,0 +1,90 @@
+package server
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// saturated returns whether the saturated readiness condition is raised
+func saturated(rd *readiness) bool {
+	_, reasons := rd.status()
+	for _, reason := range reasons {
+		if reason == ReasonSaturated {
+			return true
+		}
+	}
+	return false
+}
+
+func TestWorkerPool(t *testing.T) {
+	rd := newReadiness()
+	p := newWorkerPool(1, 2, rd)
+
+	// Occupy the single worker
+	release := make(chan struct{})
+	running := make(chan struct{})
+	done := make(chan error, 3)
+	go func() {
+		done <- p.do(context.Background(), func() {
+			close(running)
+			<-release
+		})
+	}()
+	<-running
+
+	// Fill the queue
+	for i := 0; i < 2; i++ {
+		go func() {
+			done <- p.do(context.Background(), func() {})
+		}()
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for !saturated(rd) {
+		if time.Now().After(deadline) {
+			t.Fatal("full queue should raise the saturated condition")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := p.do(context.Background(), func() { t.Error("rejected operation should not run") }); err != errQueueFull {
+		t.Errorf("do() = %v, expected errQueueFull", err)
+	}
+
+	close(release)
+	for i := 0; i < 3; i++ {
+		if err := <-done; err != nil {
+			t.Errorf("queued operation should run, got %v", err)
+		}
+	}
+	if saturated(rd) {
+		t.Error("saturated condition should be cleared once the queue is drained")
+	}
+}
+
+func TestWorkerPoolCancel(t *testing.T) {
+	p := newWorkerPool(1, 4, newReadiness())
+
+	release := make(chan struct{})
+	running := make(chan struct{})
+	go p.do(context.Background(), func() {
+		close(running)
+		<-release
+	})
+	<-running
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := p.do(ctx, func() { t.Error("cancelled operation should not run") })
+	if grpc.Code(err) != codes.Canceled {
+		t.Errorf("do() = %v, expected Canceled", err)
+	}
+	if waiting := atomic.LoadInt32(&p.waiting); waiting != 0 {
+		t.Errorf("cancelled operation should leave the queue, %d waiting", waiting)
+	}
+}
@@ -0,0 +1,183 @@
+package similarity
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// TransformationCase is detected when passwords only differ by case
+	TransformationCase = "case"
+	// TransformationIncrement is detected when the trailing number changes
+	TransformationIncrement = "increment"
+	// TransformationAppend is detected when characters are appended
+	TransformationAppend = "append"
+	// TransformationPrepend is detected when characters are prepended
+	TransformationPrepend = "prepend"
+)
+
+// Policy rejects new passwords too similar to the old one
+type Policy struct {
+	// MinDistance is the minimum normalized edit distance between passwords,
+	// from 0 (same) to 1 (nothing in common)
+	MinDistance float64
+	// RejectTransformations rejects passwords derived from the old one by a
+	// common transformation, whatever their distance
+	RejectTransformations bool
+}
+
+// DefaultPolicy rejects passwords a quarter similar to the old one, or
+// derived from it by a common transformation
+var DefaultPolicy = Policy{
+	MinDistance:           0.25,
+	RejectTransformations: true,
+}
+
+// Result describes the similarity between two passwords
+type Result struct {
+	// Distance is the normalized edit distance
+	Distance float64
+	// Transformation is the detected transformation, empty when none
+	Transformation string
+	// Rejected is true when the policy rejects the new password
+	Rejected bool
+}
+
+// Check compares the old and new passwords case insensitively. Both rune
+// slices are lowered in place, callers own and wipe them.
+func (p Policy) Check(old, next []rune) Result {
+	foldCase := equalFold(old, next)
+	lower(old)
+	lower(next)
+
+	r := Result{
+		Distance:       Distance(old, next),
+		Transformation: Transformation(old, next),
+	}
+	if r.Transformation == "" && foldCase {
+		r.Transformation = TransformationCase
+	}
+	r.Rejected = r.Distance < p.MinDistance || (p.RejectTransformations && r.Transformation != "")
+
+	return r
+}
+
+// Distance returns the Levenshtein distance between a and b divided by the
+// length of the longest one.
+func Distance(a, b []rune) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 0
+	}
+
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = minimum(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+
+	longest := len(a)
+	if len(b) > longest {
+		longest = len(b)
+	}
+	return float64(prev[len(b)]) / float64(longest)
+}
+
+// Transformation returns the common transformation deriving the new password from old,
+// empty when none is detected.
+func Transformation(old, next []rune) string {
+	if len(old) == 0 || len(next) == 0 || equal(old, next) {
+		return ""
+	}
+
+	// Trailing number changed, "summer2017" to "summer2018"
+	oldStem, oldDigits := splitDigits(old)
+	nextStem, nextDigits := splitDigits(next)
+	if len(oldDigits) > 0 && len(nextDigits) > 0 && equal(oldStem, nextStem) {
+		return TransformationIncrement
+	}
+
+	switch {
+	case len(next) > len(old) && equal(next[:len(old)], old):
+		return TransformationAppend
+	case len(next) > len(old) && equal(next[len(next)-len(old):], old):
+		return TransformationPrepend
+	}
+	return ""
+}
+
+// Runes decodes s in a new rune slice, to be wiped after use
+func Runes(s string) []rune {
+	r := make([]rune, 0, utf8.RuneCountInString(s))
+	for _, c := range s {
+		r = append(r, c)
+	}
+	return r
+}
+
+// Wipe zeroes the runes
+func Wipe(r []rune) {
+	for i := range r {
+		r[i] = 0
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+func splitDigits(r []rune) ([]rune, []rune) {
+	i := len(r)
+	for i > 0 && unicode.IsDigit(r[i-1]) {
+		i--
+	}
+	return r[:i], r[i:]
+}
+
+func lower(r []rune) {
+	for i := range r {
+		r[i] = unicode.ToLower(r[i])
+	}
+}
+
+func equal(a, b []rune) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func equalFold(a, b []rune) bool {
+	if len(a) != len(b) || equal(a, b) {
+		return false
+	}
+	for i := range a {
+		if unicode.ToLower(a[i]) != unicode.ToLower(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func minimum(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
@@ -0,0 +1,65 @@
+package similarity
+
+import (
+	"testing"
+)
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		distance float64
+	}{
+		{"", "", 0},
+		{"password", "password", 0},
+		{"kitten", "sitting", 3.0 / 7},
+		{"abcd", "", 1},
+		{"été", "ete", 2.0 / 3},
+	}
+	for _, tt := range tests {
+		if got := Distance([]rune(tt.a), []rune(tt.b)); got != tt.distance {
+			t.Errorf("Distance(%q, %q) = %v, expected %v", tt.a, tt.b, got, tt.distance)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		old, next      string
+		transformation string
+		rejected       bool
+	}{
+		{"summer2017", "summer2018", TransformationIncrement, true},
+		{"Winter9", "winter10", TransformationIncrement, true},
+		{"correcthorse", "correcthorse1", TransformationAppend, true},
+		{"correcthorse", "!correcthorse", TransformationPrepend, true},
+		{"CorrectHorse", "correcthorse", TransformationCase, true},
+		{"correcthorse", "correcthorse", "", true},
+		{"correcthorse", "batterystaple", "", false},
+		{"2017", "2018", TransformationIncrement, true},
+	}
+	for _, tt := range tests {
+		r := DefaultPolicy.Check(Runes(tt.old), Runes(tt.next))
+		if r.Transformation != tt.transformation || r.Rejected != tt.rejected {
+			t.Errorf("Check(%q, %q) = %+v, expected %q rejected %v", tt.old, tt.next, r, tt.transformation, tt.rejected)
+		}
+	}
+
+	// Transformations only
+	p := Policy{RejectTransformations: true}
+	if r := p.Check(Runes("correcthorse"), Runes("correcthorse1")); !r.Rejected {
+		t.Error("append should be rejected")
+	}
+	if r := p.Check(Runes("correcthorse"), Runes("correctmouse")); r.Rejected {
+		t.Errorf("similar password should be accepted without distance, got %+v", r)
+	}
+}
+
+func TestWipe(t *testing.T) {
+	r := Runes("secret")
+	Wipe(r)
+	for _, c := range r {
+		if c != 0 {
+			t.Fatalf("runes should be wiped, got %q", string(r))
+		}
+	}
+}
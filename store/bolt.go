@@ -0,0 +1,98 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// credentialsBucket holds JSON records by subject
+var credentialsBucket = []byte("credentials")
+
+type boltStore struct {
+	db *bolt.DB
+}
+
+// BoltStore returns a store embedded in the given file, created if missing.
+// The file is locked while the store is open.
+func BoltStore(path string) (Store, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("store: unable to open database file, %v", err)
+	}
+
+	if err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(credentialsBucket)
+		return err
+	}); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("store: unable to initialize database, %v", err)
+	}
+
+	return &boltStore{db: db}, nil
+}
+
+func (s *boltStore) Get(ctx context.Context, subject string) (*Record, error) {
+	var r *Record
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		r, err = getRecord(tx, subject)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if r.CreatedAt.IsZero() {
+		return nil, ErrNotFound
+	}
+	return r, nil
+}
+
+func (s *boltStore) Update(ctx context.Context, subject string, fn func(r *Record) error) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		r, err := getRecord(tx, subject)
+		if err != nil {
+			return err
+		}
+		if err := fn(r); err != nil {
+			return err
+		}
+
+		value, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(credentialsBucket).Put([]byte(subject), value)
+	})
+}
+
+func (s *boltStore) Delete(ctx context.Context, subject string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(credentialsBucket)
+		if b.Get([]byte(subject)) == nil {
+			return ErrNotFound
+		}
+		return b.Delete([]byte(subject))
+	})
+}
+
+func (s *boltStore) Close() error {
+	return s.db.Close()
+}
+
+// getRecord returns the stored record of the subject, or a new one
+func getRecord(tx *bolt.Tx, subject string) (*Record, error) {
+	r := &Record{Subject: subject}
+
+	value := tx.Bucket(credentialsBucket).Get([]byte(subject))
+	if value == nil {
+		return r, nil
+	}
+	if err := json.Unmarshal(value, r); err != nil {
+		return nil, fmt.Errorf("store: unable to decode credential, %v", err)
+	}
+	return r, nil
+}
@@ -0,0 +1,160 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// schema creates the credentials table, timestamps are stored as unix
+// nanoseconds, 0 meaning never.
+const schema = `CREATE TABLE IF NOT EXISTS credentials (
+	subject      VARCHAR(255) NOT NULL PRIMARY KEY,
+	hash         TEXT NOT NULL,
+	created_at   BIGINT NOT NULL,
+	changed_at   BIGINT NOT NULL,
+	verified_at  BIGINT NOT NULL,
+	failed_count INTEGER NOT NULL
+)`
+
+type sqlStore struct {
+	db *sql.DB
+	// lock is appended to selects of records to update
+	lock string
+	// dollar uses $n placeholders instead of ?
+	dollar bool
+}
+
+// SQLStore returns a store using the given database/sql driver, which must be
+// registered. The credentials table is created if missing.
+func SQLStore(driver, dsn string) (Store, error) {
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("store: unable to open database, %v", err)
+	}
+
+	s := &sqlStore{db: db, lock: " FOR UPDATE"}
+	switch driver {
+	case "sqlite3":
+		// SQLite serializes writers, and gives each connection its own
+		// in-memory database
+		db.SetMaxOpenConns(1)
+		s.lock = ""
+	case "postgres", "pgx":
+		s.dollar = true
+	}
+
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("store: unable to initialize database, %v", err)
+	}
+
+	return s, nil
+}
+
+func (s *sqlStore) Get(ctx context.Context, subject string) (*Record, error) {
+	r, err := s.get(ctx, s.db, subject, "")
+	if err != nil {
+		return nil, err
+	}
+	if r.CreatedAt.IsZero() {
+		return nil, ErrNotFound
+	}
+	return r, nil
+}
+
+func (s *sqlStore) Update(ctx context.Context, subject string, fn func(r *Record) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("store: unable to begin transaction, %v", err)
+	}
+	defer tx.Rollback()
+
+	r, err := s.get(ctx, tx, subject, s.lock)
+	if err != nil {
+		return err
+	}
+	exists := !r.CreatedAt.IsZero()
+	if err := fn(r); err != nil {
+		return err
+	}
+
+	query := "INSERT INTO credentials (hash, created_at, changed_at, verified_at, failed_count, subject) VALUES (?, ?, ?, ?, ?, ?)"
+	if exists {
+		query = "UPDATE credentials SET hash = ?, created_at = ?, changed_at = ?, verified_at = ?, failed_count = ? WHERE subject = ?"
+	}
+	if _, err := tx.ExecContext(ctx, s.rebind(query), r.Hash, unixNano(r.CreatedAt), unixNano(r.ChangedAt), unixNano(r.VerifiedAt), r.FailedCount, subject); err != nil {
+		return fmt.Errorf("store: unable to save credential, %v", err)
+	}
+
+	return tx.Commit()
+}
+
+func (s *sqlStore) Delete(ctx context.Context, subject string) error {
+	res, err := s.db.ExecContext(ctx, s.rebind("DELETE FROM credentials WHERE subject = ?"), subject)
+	if err != nil {
+		return fmt.Errorf("store: unable to delete credential, %v", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *sqlStore) Close() error {
+	return s.db.Close()
+}
+
+// queryer is implemented by sql.DB and sql.Tx
+type queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// get returns the stored record of the subject, or a new one
+func (s *sqlStore) get(ctx context.Context, q queryer, subject, lock string) (*Record, error) {
+	r := &Record{Subject: subject}
+
+	var created, changed, verified int64
+	err := q.QueryRowContext(ctx, s.rebind("SELECT hash, created_at, changed_at, verified_at, failed_count FROM credentials WHERE subject = ?"+lock), subject).
+		Scan(&r.Hash, &created, &changed, &verified, &r.FailedCount)
+	switch {
+	case err == sql.ErrNoRows:
+		return r, nil
+	case err != nil:
+		return nil, fmt.Errorf("store: unable to read credential, %v", err)
+	}
+
+	r.CreatedAt, r.ChangedAt, r.VerifiedAt = fromUnixNano(created), fromUnixNano(changed), fromUnixNano(verified)
+	return r, nil
+}
+
+// rebind replaces ? placeholders by $n ones when required by the driver
+func (s *sqlStore) rebind(query string) string {
+	if !s.dollar {
+		return query
+	}
+
+	parts := strings.Split(query, "?")
+	out := parts[0]
+	for i, part := range parts[1:] {
+		out += "$" + strconv.Itoa(i+1) + part
+	}
+	return out
+}
+
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func fromUnixNano(n int64) time.Time {
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n).UTC()
+}
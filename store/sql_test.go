@@ -0,0 +1,18 @@
+// +build cgo
+
+package store
+
+import (
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestSQLStore(t *testing.T) {
+	s, err := SQLStore("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	testStore(t, s)
+}
@@ -0,0 +1,47 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// MaxSubjectLength is the longest subject in bytes accepted by every backend
+const MaxSubjectLength = 255
+
+var (
+	// ErrNotFound is raised when no credential is stored for the subject
+	ErrNotFound = errors.New("store: credential not found")
+)
+
+// Record defines a managed credential
+type Record struct {
+	// Subject is the opaque account identifier owning the credential
+	Subject string `json:"sub"`
+	// Hash is the encoded password hash
+	Hash string `json:"hash"`
+	// CreatedAt is the time of the first password definition, zero for
+	// records not stored yet
+	CreatedAt time.Time `json:"created_at"`
+	// ChangedAt is the time of the last password change
+	ChangedAt time.Time `json:"changed_at"`
+	// VerifiedAt is the time of the last successful verification
+	VerifiedAt time.Time `json:"verified_at"`
+	// FailedCount is the number of failed verifications since the last
+	// successful one
+	FailedCount int `json:"failed_count"`
+}
+
+// Store defines managed credential storage contract
+type Store interface {
+	// Get returns the credential of the subject, ErrNotFound when none
+	Get(ctx context.Context, subject string) (*Record, error)
+	// Update applies fn to the credential of the subject within a
+	// transaction, a record with a zero CreatedAt is given when none is
+	// stored. The record is saved unless fn returns an error, which is
+	// returned.
+	Update(ctx context.Context, subject string, fn func(r *Record) error) error
+	// Delete removes the credential of the subject, ErrNotFound when none
+	Delete(ctx context.Context, subject string) error
+	Close() error
+}
@@ -0,0 +1,101 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBoltStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "credentials.db")
+
+	s, err := BoltStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStore(t, s)
+	s.Close()
+
+	// Records survive a reopening
+	s, err = BoltStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if _, err := s.Get(context.Background(), "alice"); err != nil {
+		t.Errorf("record should be persisted, got %v", err)
+	}
+}
+
+// testStore checks the store contract, an "alice" record is left
+func testStore(t *testing.T, s Store) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	if _, err := s.Get(ctx, "alice"); err != ErrNotFound {
+		t.Fatalf("Get of unknown subject should fail with ErrNotFound, got %v", err)
+	}
+	if err := s.Delete(ctx, "alice"); err != ErrNotFound {
+		t.Fatalf("Delete of unknown subject should fail with ErrNotFound, got %v", err)
+	}
+
+	// Create
+	if err := s.Update(ctx, "alice", func(r *Record) error {
+		if !r.CreatedAt.IsZero() || r.Subject != "alice" {
+			t.Errorf("a new record should be given, got %+v", r)
+		}
+		r.Hash, r.CreatedAt, r.ChangedAt = "plain$one", now, now
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Update
+	if err := s.Update(ctx, "alice", func(r *Record) error {
+		r.FailedCount++
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Aborted update
+	errAbort := errors.New("abort")
+	if err := s.Update(ctx, "alice", func(r *Record) error {
+		r.Hash = "plain$two"
+		return errAbort
+	}); err != errAbort {
+		t.Errorf("Update should return fn error, got %v", err)
+	}
+
+	r, err := s.Get(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Record{Subject: "alice", Hash: "plain$one", CreatedAt: now, ChangedAt: now, FailedCount: 1}
+	if !r.CreatedAt.Equal(now) || !r.ChangedAt.Equal(now) || !r.VerifiedAt.IsZero() || r.Hash != expected.Hash || r.FailedCount != expected.FailedCount || r.Subject != expected.Subject {
+		t.Errorf("Get = %+v, expected %+v", r, expected)
+	}
+
+	// Delete
+	if err := s.Update(ctx, "bob", func(r *Record) error {
+		r.Hash, r.CreatedAt, r.ChangedAt = "plain$bob", now, now
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete(ctx, "bob"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get(ctx, "bob"); err != ErrNotFound {
+		t.Errorf("deleted record should not be found, got %v", err)
+	}
+}
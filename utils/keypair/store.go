@@ -0,0 +1,98 @@
+package keypair
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	// ErrNoCertificate is raised when no certificate is loaded
+	ErrNoCertificate = errors.New("keypair: no certificate available")
+)
+
+// Pair defines a X509 certificate and private key file couple
+type Pair struct {
+	CertFile string
+	KeyFile  string
+}
+
+// Store holds X509 key pairs that can be reloaded at runtime, handshakes in
+// progress keep using the previous certificates.
+type Store struct {
+	mu    sync.Mutex
+	pairs []Pair
+	certs atomic.Value
+}
+
+// New returns a key pair store loaded with given files
+func New(pairs ...Pair) (*Store, error) {
+	s := &Store{}
+	if err := s.Reload(pairs...); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Reload loads all given key pairs, the store is left unchanged when any of
+// them fails to load.
+func (s *Store) Reload(pairs ...Pair) error {
+	var certs []tls.Certificate
+	for _, p := range pairs {
+		cert, err := tls.LoadX509KeyPair(p.CertFile, p.KeyFile)
+		if err != nil {
+			return fmt.Errorf("keypair: unable to load '%s', %v", p.CertFile, err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return ErrNoCertificate
+	}
+
+	s.mu.Lock()
+	s.pairs = pairs
+	s.certs.Store(certs)
+	s.mu.Unlock()
+
+	return nil
+}
+
+// Files returns all loaded certificate and key file paths
+func (s *Store) Files() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var files []string
+	for _, p := range s.pairs {
+		files = append(files, p.CertFile, p.KeyFile)
+	}
+	return files
+}
+
+// Pairs returns loaded key pair file couples
+func (s *Store) Pairs() []Pair {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Pair(nil), s.pairs...)
+}
+
+// GetCertificate implements tls.Config GetCertificate hook, the first
+// certificate supported by the client is returned.
+func (s *Store) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	certs, _ := s.certs.Load().([]tls.Certificate)
+	if len(certs) == 0 {
+		return nil, ErrNoCertificate
+	}
+
+	for i := range certs {
+		if err := hello.SupportsCertificate(&certs[i]); err == nil {
+			return &certs[i], nil
+		}
+	}
+
+	// Let the handshake fail with the default certificate
+	return &certs[0], nil
+}
@@ -0,0 +1,154 @@
+package keypair
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writePair writes a self-signed certificate for the given name and key
+func writePair(t *testing.T, dir, name string, key interface{}) Pair {
+	var pub interface{}
+	switch k := key.(type) {
+	case *ecdsa.PrivateKey:
+		pub = &k.PublicKey
+	case *rsa.PrivateKey:
+		pub = &k.PublicKey
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, pub, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := Pair{CertFile: filepath.Join(dir, name+".crt"), KeyFile: filepath.Join(dir, name+".key")}
+	if err := ioutil.WriteFile(p.CertFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(p.KeyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func commonName(t *testing.T, cert *tls.Certificate) string {
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return leaf.Subject.CommonName
+}
+
+func TestStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "keypair")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ec := writePair(t, dir, "ec", ecKey)
+	rsaPair := writePair(t, dir, "rsa", rsaKey)
+
+	if _, err := New(); err != ErrNoCertificate {
+		t.Errorf("New() error = %v, expected ErrNoCertificate", err)
+	}
+	if _, err := New(Pair{CertFile: ec.CertFile, KeyFile: rsaPair.KeyFile}); err == nil {
+		t.Error("New should reject mismatching certificate and key")
+	}
+
+	s, err := New(ec, rsaPair)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if files := s.Files(); len(files) != 4 || files[0] != ec.CertFile || files[3] != rsaPair.KeyFile {
+		t.Errorf("Files() = %v", files)
+	}
+
+	// The first certificate supported by the client is selected
+	tests := []struct {
+		name     string
+		hello    *tls.ClientHelloInfo
+		expected string
+	}{
+		{"ecdsa client", &tls.ClientHelloInfo{
+			CipherSuites:      []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256},
+			SupportedCurves:   []tls.CurveID{tls.CurveP256},
+			SupportedPoints:   []uint8{0},
+			SignatureSchemes:  []tls.SignatureScheme{tls.ECDSAWithP256AndSHA256},
+			SupportedVersions: []uint16{tls.VersionTLS12},
+		}, "ec"},
+		{"rsa client", &tls.ClientHelloInfo{
+			CipherSuites:      []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256},
+			SupportedCurves:   []tls.CurveID{tls.CurveP256},
+			SupportedPoints:   []uint8{0},
+			SignatureSchemes:  []tls.SignatureScheme{tls.PSSWithSHA256, tls.PKCS1WithSHA256},
+			SupportedVersions: []uint16{tls.VersionTLS12},
+		}, "rsa"},
+		{"unsupported client", &tls.ClientHelloInfo{
+			CipherSuites:      []uint16{tls.TLS_RSA_WITH_AES_128_CBC_SHA},
+			SignatureSchemes:  []tls.SignatureScheme{tls.Ed25519},
+			SupportedVersions: []uint16{tls.VersionTLS12},
+		}, "ec"},
+	}
+	for _, tt := range tests {
+		cert, err := s.GetCertificate(tt.hello)
+		if err != nil {
+			t.Fatalf("%s: GetCertificate() error = %v", tt.name, err)
+		}
+		if name := commonName(t, cert); name != tt.expected {
+			t.Errorf("%s: GetCertificate() = %s, expected %s", tt.name, name, tt.expected)
+		}
+	}
+
+	// Failed reloads keep the loaded certificates
+	if err := s.Reload(rsaPair, Pair{CertFile: filepath.Join(dir, "missing.crt"), KeyFile: rsaPair.KeyFile}); err == nil {
+		t.Error("Reload should fail when a key pair can't be loaded")
+	}
+	if err := s.Reload(); err != ErrNoCertificate {
+		t.Errorf("Reload() error = %v, expected ErrNoCertificate", err)
+	}
+	if pairs := s.Pairs(); len(pairs) != 2 || pairs[0] != ec {
+		t.Errorf("Pairs() = %v, failed reloads should keep previous pairs", pairs)
+	}
+
+	// Certificates are swapped on success
+	renewed := writePair(t, dir, "renewed", ecKey)
+	if err := s.Reload(renewed); err != nil {
+		t.Fatal(err)
+	}
+	cert, err := s.GetCertificate(tests[0].hello)
+	if err != nil || commonName(t, cert) != "renewed" {
+		t.Errorf("GetCertificate() = %v, expected the reloaded certificate", err)
+	}
+
+	var empty Store
+	if _, err := empty.GetCertificate(tests[0].hello); err != ErrNoCertificate {
+		t.Errorf("GetCertificate() error = %v, expected ErrNoCertificate", err)
+	}
+}
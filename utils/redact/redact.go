@@ -0,0 +1,81 @@
+package redact
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// Mask replaces secret values
+	Mask = "[REDACTED]"
+)
+
+// Scrubber replaces known secret values in diagnostic messages. A nil
+// Scrubber returns values unchanged.
+type Scrubber struct {
+	secrets []string
+}
+
+// New returns a scrubber of the given secrets, empty values are ignored
+func New(secrets ...string) *Scrubber {
+	s := &Scrubber{}
+	for _, secret := range secrets {
+		if len(secret) > 0 {
+			s.secrets = append(s.secrets, secret)
+		}
+	}
+
+	// Longest secrets first, to never leave a suffix of a secret containing
+	// another one
+	sort.Slice(s.secrets, func(i, j int) bool {
+		return len(s.secrets[i]) > len(s.secrets[j])
+	})
+
+	return s
+}
+
+// String returns v with every secret replaced by Mask
+func (s *Scrubber) String(v string) string {
+	if s == nil {
+		return v
+	}
+	for _, secret := range s.secrets {
+		v = strings.Replace(v, secret, Mask, -1)
+	}
+	return v
+}
+
+// Value returns the scrubbed default format of v
+func (s *Scrubber) Value(v interface{}) string {
+	return s.String(fmt.Sprintf("%v", v))
+}
+
+// Error returns err, or a scrubbed copy when its message contains a secret
+func (s *Scrubber) Error(err error) error {
+	if err == nil {
+		return nil
+	}
+	msg := s.String(err.Error())
+	if msg == err.Error() {
+		return err
+	}
+	return errors.New(msg)
+}
+
+// -----------------------------------------------------------------------------
+
+type scrubberKey struct{}
+
+// NewContext returns a context holding the scrubber of the current call
+func NewContext(ctx context.Context, s *Scrubber) context.Context {
+	return context.WithValue(ctx, scrubberKey{}, s)
+}
+
+// FromContext returns the scrubber of the current call, nil if none
+func FromContext(ctx context.Context) *Scrubber {
+	s, _ := ctx.Value(scrubberKey{}).(*Scrubber)
+	return s
+}
@@ -0,0 +1,55 @@
+package redact
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestScrubber(t *testing.T) {
+	s := New("secret", "", "secret-longer")
+
+	tests := []struct {
+		in, out string
+	}{
+		{"nothing to hide", "nothing to hide"},
+		{"value is secret", "value is " + Mask},
+		{"value is secret-longer", "value is " + Mask},
+		{"secret, secret", Mask + ", " + Mask},
+	}
+	for _, tt := range tests {
+		if got := s.String(tt.in); got != tt.out {
+			t.Errorf("String(%q) = %q, expected %q", tt.in, got, tt.out)
+		}
+	}
+
+	if got := s.Value(struct{ V string }{"secret"}); got != "{"+Mask+"}" {
+		t.Errorf("Value should be scrubbed, got %q", got)
+	}
+
+	err := errors.New("unrelated")
+	if s.Error(err) != err {
+		t.Error("Error should keep errors without secret")
+	}
+	if got := s.Error(errors.New("bad secret")); got.Error() != "bad "+Mask {
+		t.Errorf("Error should be scrubbed, got %q", got)
+	}
+	if s.Error(nil) != nil {
+		t.Error("Error(nil) should be nil")
+	}
+}
+
+func TestNilScrubber(t *testing.T) {
+	s := FromContext(context.Background())
+	if s != nil {
+		t.Fatal("FromContext should be nil without scrubber")
+	}
+	if got := s.String("secret"); got != "secret" {
+		t.Errorf("nil scrubber should keep values, got %q", got)
+	}
+
+	ctx := NewContext(context.Background(), New("secret"))
+	if got := FromContext(ctx).String("secret"); got != Mask {
+		t.Errorf("context scrubber should be used, got %q", got)
+	}
+}
@@ -0,0 +1,81 @@
+package secret
+
+import (
+	"errors"
+	"runtime"
+)
+
+var (
+	// ErrNotSupported is raised when memory locking or core dump settings
+	// are not available on the current platform
+	ErrNotSupported = errors.New("secret: not supported on this platform")
+)
+
+// Buffer holds a cleartext secret, its memory is wiped on Destroy. Locked
+// buffers are allocated out of the Go heap and pinned in RAM to never be
+// written to swap.
+type Buffer struct {
+	data   []byte
+	mapped []byte
+}
+
+// FromString copies the secret to a new buffer. Locking is best effort, the
+// buffer is allocated on the heap when memory can't be locked.
+func FromString(s string, lock bool) *Buffer {
+	b := &Buffer{}
+	if lock {
+		if mapped, err := allocLocked(len(s)); err == nil {
+			b.mapped = mapped
+			b.data = mapped[:len(s)]
+		}
+	}
+	if b.data == nil {
+		b.data = make([]byte, len(s))
+	}
+	copy(b.data, s)
+
+	return b
+}
+
+// Bytes returns the secret value, it must not be used after Destroy
+func (b *Buffer) Bytes() []byte {
+	return b.data
+}
+
+// Locked returns true when the buffer memory is locked
+func (b *Buffer) Locked() bool {
+	return b.mapped != nil
+}
+
+// Destroy wipes and releases the buffer
+func (b *Buffer) Destroy() {
+	if b == nil || b.data == nil {
+		return
+	}
+
+	Wipe(b.data)
+	if b.mapped != nil {
+		Wipe(b.mapped)
+		freeLocked(b.mapped)
+	}
+	b.data, b.mapped = nil, nil
+}
+
+// Wipe overwrites the given slice with zeroes
+func Wipe(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+	runtime.KeepAlive(b)
+}
+
+// CheckLocking returns an error when memory can't be locked, usually because
+// of the RLIMIT_MEMLOCK limit or missing CAP_IPC_LOCK capability.
+func CheckLocking() error {
+	mapped, err := allocLocked(1)
+	if err != nil {
+		return err
+	}
+	freeLocked(mapped)
+	return nil
+}
@@ -0,0 +1,14 @@
+// +build !linux,!darwin,!freebsd
+
+package secret
+
+func allocLocked(size int) ([]byte, error) {
+	return nil, ErrNotSupported
+}
+
+func freeLocked(mapped []byte) {}
+
+// DisableCoreDumps is not supported on this platform
+func DisableCoreDumps() error {
+	return ErrNotSupported
+}
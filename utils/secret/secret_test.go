@@ -0,0 +1,63 @@
+package secret
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestBufferWiped(t *testing.T) {
+	b := FromString("secret", false)
+	data := b.Bytes()
+	if string(data) != "secret" {
+		t.Fatalf("buffer should hold the secret, got %q", data)
+	}
+	if b.Locked() {
+		t.Error("buffer should not be locked")
+	}
+
+	b.Destroy()
+	if !bytes.Equal(data, make([]byte, len(data))) {
+		t.Errorf("buffer should be wiped, got %q", data)
+	}
+	if b.Bytes() != nil {
+		t.Error("destroyed buffer should be empty")
+	}
+
+	// Destroy is idempotent
+	b.Destroy()
+}
+
+func TestLockedBuffer(t *testing.T) {
+	if err := CheckLocking(); err != nil {
+		t.Skipf("memory locking is not available: %v", err)
+	}
+
+	b := FromString("secret", true)
+	if !b.Locked() {
+		t.Fatal("buffer should be locked")
+	}
+	if string(b.Bytes()) != "secret" {
+		t.Fatalf("buffer should hold the secret, got %q", b.Bytes())
+	}
+
+	// Wipe the mapped pages before release, they can't be read afterwards
+	mapped := b.mapped
+	Wipe(b.data)
+	if !bytes.Equal(mapped, make([]byte, len(mapped))) {
+		t.Error("mapped pages should be wiped")
+	}
+
+	b.Destroy()
+	if b.Bytes() != nil || b.Locked() {
+		t.Error("destroyed buffer should be released")
+	}
+}
+
+func TestWipe(t *testing.T) {
+	b := []byte("secret")
+	Wipe(b)
+	if !bytes.Equal(b, make([]byte, 6)) {
+		t.Errorf("slice should be wiped, got %q", b)
+	}
+	Wipe(nil)
+}
@@ -0,0 +1,43 @@
+// +build linux darwin freebsd
+
+package secret
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// allocLocked maps anonymous pages holding at least size bytes, locked in RAM
+func allocLocked(size int) ([]byte, error) {
+	pageSize := os.Getpagesize()
+	length := ((size + pageSize - 1) / pageSize) * pageSize
+	if length == 0 {
+		length = pageSize
+	}
+
+	mapped, err := syscall.Mmap(-1, 0, length, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_ANON|syscall.MAP_PRIVATE)
+	if err != nil {
+		return nil, fmt.Errorf("secret: unable to map memory, %v", err)
+	}
+	if err := syscall.Mlock(mapped); err != nil {
+		syscall.Munmap(mapped)
+		return nil, fmt.Errorf("secret: unable to lock memory, %v", err)
+	}
+
+	return mapped, nil
+}
+
+func freeLocked(mapped []byte) {
+	syscall.Munlock(mapped)
+	syscall.Munmap(mapped)
+}
+
+// DisableCoreDumps sets the process core file size limit to zero, so that
+// secrets in memory are never written to disk on crash.
+func DisableCoreDumps() error {
+	if err := syscall.Setrlimit(syscall.RLIMIT_CORE, &syscall.Rlimit{Cur: 0, Max: 0}); err != nil {
+		return fmt.Errorf("secret: unable to disable core dumps, %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+package tracer
+
+import (
+	"io"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/uber/jaeger-client-go"
+)
+
+// NewInMemory returns a tracer sampling every trace and keeping finished
+// spans in memory, to be inspected by tests with reporter.GetSpans().
+func NewInMemory(serviceName string) (opentracing.Tracer, *jaeger.InMemoryReporter, io.Closer) {
+	reporter := jaeger.NewInMemoryReporter()
+	t, closer := jaeger.NewTracer(
+		serviceName,
+		jaeger.NewConstSampler(true),
+		reporter,
+		jaeger.TracerOptions.Gen128Bit(true),
+	)
+	return t, reporter, closer
+}
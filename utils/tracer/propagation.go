@@ -0,0 +1,84 @@
+package tracer
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/uber/jaeger-client-go"
+)
+
+const (
+	// TraceparentHeader is the W3C trace context header
+	TraceparentHeader = "traceparent"
+)
+
+var (
+	// ErrInvalidTraceparent is raised when the traceparent header is malformed
+	ErrInvalidTraceparent = errors.New("tracer: invalid traceparent header")
+)
+
+// ExtractHTTP returns the span context propagated by incoming HTTP headers.
+// The tracer native format is preferred, the W3C traceparent header is used
+// as a fallback when the tracer is a jaeger tracer.
+func ExtractHTTP(t opentracing.Tracer, h http.Header) (opentracing.SpanContext, error) {
+	sc, err := t.Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(h))
+	if err == nil {
+		return sc, nil
+	}
+
+	value := h.Get(TraceparentHeader)
+	if len(value) == 0 {
+		return nil, opentracing.ErrSpanContextNotFound
+	}
+	if _, ok := t.(*jaeger.Tracer); !ok {
+		return nil, opentracing.ErrSpanContextNotFound
+	}
+
+	return ParseTraceparent(value)
+}
+
+// ParseTraceparent decodes a W3C traceparent header value
+// ("version-traceid-parentid-flags") as a jaeger span context.
+func ParseTraceparent(value string) (jaeger.SpanContext, error) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return jaeger.SpanContext{}, ErrInvalidTraceparent
+	}
+	for _, part := range parts[:4] {
+		if !isLowerHex(part) {
+			return jaeger.SpanContext{}, ErrInvalidTraceparent
+		}
+	}
+	if parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return jaeger.SpanContext{}, ErrInvalidTraceparent
+	}
+
+	traceID, err := jaeger.TraceIDFromString(parts[1])
+	if err != nil || !traceID.IsValid() {
+		return jaeger.SpanContext{}, ErrInvalidTraceparent
+	}
+	spanID, err := strconv.ParseUint(parts[2], 16, 64)
+	if err != nil || spanID == 0 {
+		return jaeger.SpanContext{}, ErrInvalidTraceparent
+	}
+	flags, err := strconv.ParseUint(parts[3], 16, 8)
+	if err != nil {
+		return jaeger.SpanContext{}, ErrInvalidTraceparent
+	}
+
+	return jaeger.NewSpanContext(traceID, jaeger.SpanID(spanID), 0, flags&0x01 == 0x01, nil), nil
+}
+
+// isLowerHex returns true when s only contains lowercase hexadecimal digits,
+// as required by the trace context specification
+func isLowerHex(s string) bool {
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,89 @@
+package tracer
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/uber/jaeger-client-go"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	const (
+		traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+		spanID  = "00f067aa0ba902b7"
+	)
+
+	tests := []struct {
+		name    string
+		value   string
+		valid   bool
+		sampled bool
+	}{
+		{"sampled", "00-" + traceID + "-" + spanID + "-01", true, true},
+		{"not sampled", "00-" + traceID + "-" + spanID + "-00", true, false},
+		{"surrounding spaces", " 00-" + traceID + "-" + spanID + "-01 ", true, true},
+		{"future version with extra fields", "01-" + traceID + "-" + spanID + "-01-extra", true, true},
+		{"version 00 with extra fields", "00-" + traceID + "-" + spanID + "-01-extra", false, false},
+		{"forbidden version", "ff-" + traceID + "-" + spanID + "-01", false, false},
+		{"non hex version", "zz-" + traceID + "-" + spanID + "-01", false, false},
+		{"short version", "0-" + traceID + "-" + spanID + "-01", false, false},
+		{"short trace id", "00-" + traceID[2:] + "-" + spanID + "-01", false, false},
+		{"long span id", "00-" + traceID + "-" + spanID + "00-01", false, false},
+		{"missing flags", "00-" + traceID + "-" + spanID, false, false},
+		{"zero trace id", "00-00000000000000000000000000000000-" + spanID + "-01", false, false},
+		{"zero span id", "00-" + traceID + "-0000000000000000-01", false, false},
+		{"uppercase trace id", "00-4BF92F3577B34DA6A3CE929D0E0E4736-" + spanID + "-01", false, false},
+		{"uppercase span id", "00-" + traceID + "-00F067AA0BA902B7-01", false, false},
+		{"non hex flags", "00-" + traceID + "-" + spanID + "-0x", false, false},
+		{"empty", "", false, false},
+	}
+	for _, tt := range tests {
+		sc, err := ParseTraceparent(tt.value)
+		if (err == nil) != tt.valid {
+			t.Errorf("%s: ParseTraceparent() error = %v, expected valid %v", tt.name, err, tt.valid)
+			continue
+		}
+		if !tt.valid {
+			continue
+		}
+		if sc.TraceID().String() != traceID || sc.SpanID().String() != spanID[2:] {
+			t.Errorf("%s: ParseTraceparent() = %s, expected trace %s span %s", tt.name, sc, traceID, spanID)
+		}
+		if sc.IsSampled() != tt.sampled {
+			t.Errorf("%s: sampled = %v, expected %v", tt.name, sc.IsSampled(), tt.sampled)
+		}
+	}
+}
+
+func TestExtractHTTP(t *testing.T) {
+	tr, _, closer := NewInMemory("password")
+	defer closer.Close()
+
+	h := http.Header{}
+	if _, err := ExtractHTTP(tr, h); err != opentracing.ErrSpanContextNotFound {
+		t.Errorf("missing context should not be found, got %v", err)
+	}
+
+	h.Set(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if _, err := ExtractHTTP(tr, h); err != nil {
+		t.Errorf("traceparent should be extracted, got %v", err)
+	}
+	if _, err := ExtractHTTP(opentracing.NoopTracer{}, h); err != opentracing.ErrSpanContextNotFound {
+		t.Errorf("traceparent is only supported by jaeger tracers, got %v", err)
+	}
+
+	// Native format is preferred
+	span := tr.StartSpan("caller")
+	defer span.Finish()
+	if err := tr.Inject(span.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(h)); err != nil {
+		t.Fatal(err)
+	}
+	sc, err := ExtractHTTP(tr, h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sc.(jaeger.SpanContext).TraceID() != span.Context().(jaeger.SpanContext).TraceID() {
+		t.Errorf("native context should be preferred to traceparent, got %v", sc)
+	}
+}
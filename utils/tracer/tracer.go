@@ -3,35 +3,119 @@ package tracer
 import (
 	"fmt"
 	"io"
+	"strings"
 
+	"github.com/opentracing/opentracing-go"
+	"github.com/sirupsen/logrus"
 	"github.com/uber/jaeger-client-go"
 	jaegercfg "github.com/uber/jaeger-client-go/config"
-	jaegerlog "github.com/uber/jaeger-client-go/log"
 )
 
-func SetupTracer() io.Closer {
+// Samplers lists supported sampler types
+var Samplers = []string{
+	jaeger.SamplerTypeConst,
+	jaeger.SamplerTypeProbabilistic,
+	jaeger.SamplerTypeRateLimiting,
+	jaeger.SamplerTypeRemote,
+}
+
+// Settings defines distributed tracing settings
+type Settings struct {
+	Enabled     bool   `mapstructure:"enabled"`
+	ServiceName string `mapstructure:"service_name"`
+	// Endpoint is the jaeger agent address (host:port) receiving spans
+	Endpoint string `mapstructure:"endpoint"`
+	// Sampler is one of const, probabilistic, ratelimiting or remote
+	Sampler string `mapstructure:"sampler"`
+	// SampleRate is the sampler parameter: 0 or 1 for const, a probability
+	// for probabilistic, traces per second for ratelimiting, and the initial
+	// probability for remote
+	SampleRate float64 `mapstructure:"sample_rate"`
+	// LogSpans logs every reported span
+	LogSpans bool `mapstructure:"log_spans"`
+}
+
+// Validate checks tracing settings consistency
+func (s Settings) Validate() error {
+	if !s.Enabled {
+		return nil
+	}
+	if len(s.ServiceName) == 0 {
+		return fmt.Errorf("tracing.service_name is mandatory")
+	}
+	if len(s.Endpoint) == 0 {
+		return fmt.Errorf("tracing.endpoint is mandatory")
+	}
+	if !isSampler(s.Sampler) {
+		return fmt.Errorf("tracing.sampler '%s' is not supported", s.Sampler)
+	}
+	if s.SampleRate < 0 {
+		return fmt.Errorf("tracing.sample_rate must not be negative")
+	}
+	if strings.ToLower(s.Sampler) == jaeger.SamplerTypeProbabilistic && s.SampleRate > 1 {
+		return fmt.Errorf("tracing.sample_rate must be lower than 1 for probabilistic sampler")
+	}
+	return nil
+}
+
+// Setup initializes the global tracer according to settings. A no-op tracer
+// is kept when tracing is disabled.
+func Setup(s Settings, logger *logrus.Entry) (io.Closer, error) {
+	if !s.Enabled {
+		return nopCloser{}, nil
+	}
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+
 	cfg := jaegercfg.Configuration{
 		Sampler: &jaegercfg.SamplerConfig{
-			Type:  jaeger.SamplerTypeConst,
-			Param: 1,
+			Type:  strings.ToLower(s.Sampler),
+			Param: s.SampleRate,
 		},
 		Reporter: &jaegercfg.ReporterConfig{
-			LogSpans: true,
+			LogSpans:           s.LogSpans,
+			LocalAgentHostPort: s.Endpoint,
 		},
 	}
 
-	// Example logger and metrics factory. Use github.com/uber/jaeger-client-go/log
-	// and github.com/uber/jaeger-lib/metrics respectively to bind to real logging and metrics
-	// frameworks.
-	jLogger := jaegerlog.StdLogger
-
-	// Initialize tracer with a logger and a metrics factory
-	closer, err := cfg.InitGlobalTracer(
-		"password-api-server",
-		jaegercfg.Logger(jLogger),
+	t, closer, err := cfg.New(
+		s.ServiceName,
+		jaegercfg.Logger(&jaegerLogger{logger: logger}),
+		jaegercfg.Gen128Bit(true),
 	)
 	if err != nil {
-		panic(fmt.Sprintf("Could not initialize jaeger tracer: %s", err.Error()))
+		return nil, fmt.Errorf("tracer: unable to initialize jaeger tracer, %v", err)
 	}
-	return closer
+	opentracing.SetGlobalTracer(t)
+
+	return closer, nil
+}
+
+// -----------------------------------------------------------------------------
+
+func isSampler(name string) bool {
+	for _, s := range Samplers {
+		if strings.ToLower(name) == s {
+			return true
+		}
+	}
+	return false
+}
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
+
+// jaegerLogger routes jaeger reporter messages to logrus
+type jaegerLogger struct {
+	logger *logrus.Entry
+}
+
+func (l *jaegerLogger) Error(msg string) {
+	l.logger.Error(msg)
+}
+
+func (l *jaegerLogger) Infof(msg string, args ...interface{}) {
+	l.logger.Infof(msg, args...)
 }